@@ -0,0 +1,330 @@
+package jobscheduler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrApplicationNotFound is returned when no application is registered
+	// with a name
+	ErrApplicationNotFound = errors.New("application not found")
+
+	// ErrApplicationExists is returned when registering an application under
+	// a name that is already taken
+	ErrApplicationExists = errors.New("application already exists")
+
+	// ErrApplicationNotAllowed is returned when a job asks to run something
+	// its registered application does not permit
+	ErrApplicationNotAllowed = errors.New("application not allowed")
+)
+
+// checksumPrefix introduces the hex digest in Application.Checksum
+const checksumPrefix = "sha256:"
+
+// Application registers an executable that jobs can run by name. A job
+// naming it runs Path with Args followed by the job's own arguments, each
+// of which must fully match one of AllowedArgs. The job may only set the
+// environment variables named in AllowedEnv, so that it cannot change what
+// actually runs with the likes of LD_PRELOAD or PATH. Env is set on top of
+// the job's environment, and Limits cap those the job asks for.
+type Application struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	Args        []string          `json:"args,omitempty"`         // Always passed, before the job's arguments
+	AllowedArgs []string          `json:"allowed_args,omitempty"` // Regular expressions for the job's arguments
+	AllowedEnv  []string          `json:"allowed_env,omitempty"`  // Environment variables the job may set
+	Env         map[string]string `json:"env,omitempty"`
+	Limits      ResourceLimits    `json:"limits,omitempty"`
+	Checksum    string            `json:"checksum,omitempty"` // "sha256:" and the hex digest Path must have
+}
+
+// Validate checks if the application is valid
+func (a Application) Validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("application name cannot be empty")
+	}
+	if a.Path == "" {
+		return fmt.Errorf("application path cannot be empty")
+	}
+	if _, err := compileArgPatterns(a.AllowedArgs); err != nil {
+		return err
+	}
+	if err := a.Limits.Validate(); err != nil {
+		return err
+	}
+	if a.Checksum != "" {
+		digest, ok := strings.CutPrefix(a.Checksum, checksumPrefix)
+		if decoded, err := hex.DecodeString(digest); !ok || err != nil || len(decoded) != sha256.Size {
+			return fmt.Errorf("checksum must be %q followed by a hex SHA-256 digest", checksumPrefix)
+		}
+	}
+	return nil
+}
+
+// compileArgPatterns compiles allowed argument patterns, anchored so that
+// they must match a whole argument
+func compileArgPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed argument pattern %q: %v", pattern, err)
+		}
+		compiled[i] = re
+	}
+	return compiled, nil
+}
+
+// registeredApplication is an application with its patterns compiled
+type registeredApplication struct {
+	Application
+	allowed []*regexp.Regexp
+}
+
+// applicationRegistry holds the applications jobs can run by name. When
+// restricted, jobs can run nothing else.
+type applicationRegistry struct {
+	mu         sync.RWMutex
+	apps       map[string]*registeredApplication
+	restricted bool
+}
+
+func newApplicationRegistry(restricted bool) *applicationRegistry {
+	return &applicationRegistry{
+		apps:       make(map[string]*registeredApplication),
+		restricted: restricted,
+	}
+}
+
+// put registers or replaces an application
+func (r *applicationRegistry) put(app Application, replace bool) error {
+	if err := app.Validate(); err != nil {
+		return fmt.Errorf("invalid application: %v", err)
+	}
+	allowed, _ := compileArgPatterns(app.AllowedArgs)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exists := r.apps[app.Name]
+	switch {
+	case exists && !replace:
+		return fmt.Errorf("application %s: %w", app.Name, ErrApplicationExists)
+	case !exists && replace:
+		return fmt.Errorf("application %s: %w", app.Name, ErrApplicationNotFound)
+	}
+	r.apps[app.Name] = &registeredApplication{Application: app, allowed: allowed}
+	return nil
+}
+
+// remove unregisters an application
+func (r *applicationRegistry) remove(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.apps[name]; !exists {
+		return fmt.Errorf("application %s: %w", name, ErrApplicationNotFound)
+	}
+	delete(r.apps, name)
+	return nil
+}
+
+// get returns a registered application
+func (r *applicationRegistry) get(name string) (Application, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	app, exists := r.apps[name]
+	if !exists {
+		return Application{}, false
+	}
+	return app.Application, true
+}
+
+// list returns every registered application, ordered by name
+func (r *applicationRegistry) list() []Application {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	apps := make([]Application, 0, len(r.apps))
+	for _, app := range r.apps {
+		apps = append(apps, app.Application)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name < apps[j].Name
+	})
+	return apps
+}
+
+// check reports whether a job may run its application, without resolving
+// its checksum
+func (r *applicationRegistry) check(job JobPayload) error {
+	if job.Application == nil {
+		return nil
+	}
+	_, _, err := r.apply(*job.Application)
+	return err
+}
+
+// resolve returns the application a job actually runs. A job naming a
+// registered application runs it with the job's arguments, environment and
+// limits applied on top, after its checksum has been verified. Other jobs
+// run their own application, unless the registry is restricted.
+func (r *applicationRegistry) resolve(app ApplicationConfig) (ApplicationConfig, error) {
+	resolved, checksum, err := r.apply(app)
+	if err != nil {
+		return app, err
+	}
+	if checksum != "" {
+		if err := verifyChecksum(resolved.Path, checksum); err != nil {
+			return app, fmt.Errorf("application %s: %w: %v", app.Name, ErrApplicationNotAllowed, err)
+		}
+	}
+	return resolved, nil
+}
+
+// apply merges a job's application with the registered one it names,
+// returning the checksum the result must have
+func (r *applicationRegistry) apply(app ApplicationConfig) (ApplicationConfig, string, error) {
+	r.mu.RLock()
+	registered, exists := r.apps[app.Name]
+	restricted := r.restricted
+	r.mu.RUnlock()
+
+	if !exists {
+		if restricted || app.Path == "" {
+			return app, "", fmt.Errorf("application %q: %w", app.Name, ErrApplicationNotFound)
+		}
+		return app, "", nil
+	}
+
+	if app.Path != "" && app.Path != registered.Path {
+		return app, "", fmt.Errorf("application %s: %w: path %s is not the registered one", app.Name, ErrApplicationNotAllowed, app.Path)
+	}
+	for _, arg := range app.Args {
+		if !matchesAny(registered.allowed, arg) {
+			return app, "", fmt.Errorf("application %s: %w: argument %q", app.Name, ErrApplicationNotAllowed, arg)
+		}
+	}
+	for name := range app.Env {
+		if !slices.Contains(registered.AllowedEnv, name) {
+			return app, "", fmt.Errorf("application %s: %w: environment variable %s", app.Name, ErrApplicationNotAllowed, name)
+		}
+	}
+
+	resolved := app
+	resolved.Path = registered.Path
+	resolved.Args = append(append([]string(nil), registered.Args...), app.Args...)
+	if len(registered.Env) > 0 {
+		resolved.Env = make(map[string]string, len(app.Env)+len(registered.Env))
+		for k, v := range app.Env {
+			resolved.Env[k] = v
+		}
+		for k, v := range registered.Env {
+			resolved.Env[k] = v
+		}
+	}
+	resolved.Limits = capLimits(app.Limits, registered.Limits)
+	return resolved, registered.Checksum, nil
+}
+
+// matchesAny reports whether arg fully matches one of the patterns
+func matchesAny(patterns []*regexp.Regexp, arg string) bool {
+	for _, re := range patterns {
+		if re.MatchString(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// capLimits applies each of the registered limits to the requested ones,
+// letting a request only tighten them
+func capLimits(requested, registered ResourceLimits) ResourceLimits {
+	return ResourceLimits{
+		MemoryMax:    capLimit(requested.MemoryMax, registered.MemoryMax),
+		CPUQuota:     capLimit(requested.CPUQuota, registered.CPUQuota),
+		MaxProcesses: capLimit(requested.MaxProcesses, registered.MaxProcesses),
+		MaxOpenFiles: capLimit(requested.MaxOpenFiles, registered.MaxOpenFiles),
+		MaxFileSize:  capLimit(requested.MaxFileSize, registered.MaxFileSize),
+	}
+}
+
+// capLimit returns the tighter of two limits, where zero is unlimited
+func capLimit[T int | int64 | float64](requested, registered T) T {
+	if registered > 0 && (requested <= 0 || requested > registered) {
+		return registered
+	}
+	return requested
+}
+
+// verifyChecksum checks the SHA-256 digest of the executable at path,
+// looking it up in PATH if it has no directory
+func verifyChecksum(path, checksum string) error {
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if got := checksumPrefix + hex.EncodeToString(h.Sum(nil)); got != strings.ToLower(checksum) {
+		return fmt.Errorf("%s has checksum %s", path, got)
+	}
+	return nil
+}
+
+// CreateApplication registers an application that jobs can run by name
+func (s *Scheduler) CreateApplication(app Application) (*Application, error) {
+	if err := s.applications.put(app, false); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// UpdateApplication replaces a registered application. Jobs already
+// submitted run it with the new settings.
+func (s *Scheduler) UpdateApplication(app Application) (*Application, error) {
+	if err := s.applications.put(app, true); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// DeleteApplication unregisters an application. Jobs already submitted
+// that name it fail when they start if the registry is restricted.
+func (s *Scheduler) DeleteApplication(name string) error {
+	return s.applications.remove(name)
+}
+
+// GetApplication returns a registered application
+func (s *Scheduler) GetApplication(name string) (*Application, error) {
+	app, exists := s.applications.get(name)
+	if !exists {
+		return nil, fmt.Errorf("application %s: %w", name, ErrApplicationNotFound)
+	}
+	return &app, nil
+}
+
+// ListApplications returns every registered application, ordered by name
+func (s *Scheduler) ListApplications() []Application {
+	return s.applications.list()
+}
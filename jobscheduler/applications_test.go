@@ -0,0 +1,166 @@
+package jobscheduler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplications(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	greet := Application{
+		Name:        "greet",
+		Path:        "sh",
+		Args:        []string{"-c", `echo "$GREETING" "$@" $PUNCTUATION; ulimit -n`, "greet"},
+		AllowedArgs: []string{"[a-z]+"},
+		AllowedEnv:  []string{"GREETING", "PUNCTUATION"},
+		Env:         map[string]string{"GREETING": "hello"},
+		Limits:      ResourceLimits{MaxOpenFiles: 64},
+	}
+
+	namedJob := func(id, name string, args ...string) JobPayload {
+		return JobPayload{
+			ID:          id,
+			Channel:     "applications-channel",
+			Application: &ApplicationConfig{Name: name, Args: args},
+		}
+	}
+
+	run := func(t *testing.T, job JobPayload, status JobStatus) *JobResult {
+		t.Helper()
+		require.NoError(t, scheduler.SubmitJob(job))
+		waitForStatus(t, scheduler, job.ID, status)
+		result, err := scheduler.GetJobResult(job.ID)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("Management", func(t *testing.T) {
+		_, err := scheduler.CreateApplication(greet)
+		require.NoError(t, err)
+
+		_, err = scheduler.CreateApplication(greet)
+		assert.ErrorIs(t, err, ErrApplicationExists)
+
+		_, err = scheduler.UpdateApplication(Application{Name: "missing", Path: "true"})
+		assert.ErrorIs(t, err, ErrApplicationNotFound)
+
+		_, err = scheduler.CreateApplication(Application{Name: "bad", Path: "true", AllowedArgs: []string{"("}})
+		assert.Error(t, err)
+
+		_, err = scheduler.CreateApplication(Application{Name: "bad", Path: "true", Checksum: "md5:abc"})
+		assert.Error(t, err)
+
+		app, err := scheduler.GetApplication("greet")
+		require.NoError(t, err)
+		assert.Equal(t, "sh", app.Path)
+		assert.Len(t, scheduler.ListApplications(), 1)
+	})
+
+	t.Run("ByName", func(t *testing.T) {
+		job := namedJob("by-name", "greet", "world")
+		job.Application.Env = map[string]string{"GREETING": "goodbye", "PUNCTUATION": "!"}
+		job.Application.Limits = ResourceLimits{MaxOpenFiles: 1024}
+		result := run(t, job, JobStatusComplete)
+
+		// The registered environment and limits win over the job's
+		assert.Equal(t, "hello world !\n64\n", result.Output)
+	})
+
+	t.Run("DisallowedEnv", func(t *testing.T) {
+		for _, name := range []string{"LD_PRELOAD", "LD_LIBRARY_PATH", "PATH"} {
+			job := namedJob("bad-env", "greet")
+			job.Application.Env = map[string]string{name: "/tmp"}
+			assert.ErrorIs(t, scheduler.SubmitJob(job), ErrApplicationNotAllowed, name)
+		}
+	})
+
+	t.Run("DisallowedArgument", func(t *testing.T) {
+		err := scheduler.SubmitJob(namedJob("bad-arg", "greet", "world; rm -rf /"))
+		assert.ErrorIs(t, err, ErrApplicationNotAllowed)
+	})
+
+	t.Run("OtherPath", func(t *testing.T) {
+		job := namedJob("other-path", "greet")
+		job.Application.Path = "bash"
+		assert.ErrorIs(t, scheduler.SubmitJob(job), ErrApplicationNotAllowed)
+	})
+
+	t.Run("UnknownName", func(t *testing.T) {
+		assert.ErrorIs(t, scheduler.SubmitJob(namedJob("unknown", "missing")), ErrApplicationNotFound)
+	})
+
+	t.Run("Checksum", func(t *testing.T) {
+		path, err := exec.LookPath("sh")
+		require.NoError(t, err)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		sum := sha256.Sum256(data)
+
+		verified := greet
+		verified.Checksum = checksumPrefix + hex.EncodeToString(sum[:])
+		_, err = scheduler.UpdateApplication(verified)
+		require.NoError(t, err)
+		run(t, namedJob("checksum-match", "greet"), JobStatusComplete)
+
+		sum[0] ^= 0xff
+		verified.Checksum = checksumPrefix + hex.EncodeToString(sum[:])
+		_, err = scheduler.UpdateApplication(verified)
+		require.NoError(t, err)
+		result := run(t, namedJob("checksum-mismatch", "greet"), JobStatusFailed)
+		assert.Contains(t, result.Error, ErrApplicationNotAllowed.Error())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, scheduler.DeleteApplication("greet"))
+		assert.ErrorIs(t, scheduler.DeleteApplication("greet"), ErrApplicationNotFound)
+		assert.ErrorIs(t, scheduler.SubmitJob(namedJob("deleted", "greet")), ErrApplicationNotFound)
+	})
+}
+
+func TestRestrictedApplications(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+	cfg.WorkDir = tmpDir
+	cfg.RestrictApplications = true
+	cfg.Applications = []Application{{Name: "echo", Path: "echo", AllowedArgs: []string{".*"}}}
+
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	defer scheduler.Shutdown()
+
+	err = scheduler.SubmitJob(JobPayload{
+		ID:          "arbitrary",
+		Channel:     "restricted",
+		Application: &ApplicationConfig{Name: "sh", Path: "sh", Args: []string{"-c", "true"}},
+	})
+	assert.ErrorIs(t, err, ErrApplicationNotFound)
+
+	_, err = scheduler.CreateSchedule(Schedule{
+		ID:         "arbitrary-schedule",
+		Expression: "@hourly",
+		Template:   JobPayload{Channel: "restricted", Application: &ApplicationConfig{Path: "sh"}},
+	})
+	assert.ErrorIs(t, err, ErrApplicationNotFound)
+
+	require.NoError(t, scheduler.SubmitJob(JobPayload{
+		ID:          "registered",
+		Channel:     "restricted",
+		Application: &ApplicationConfig{Name: "echo", Args: []string{"hi"}},
+	}))
+	waitForStatus(t, scheduler, "registered", JobStatusComplete)
+	result, err := scheduler.GetJobResult("registered")
+	require.NoError(t, err)
+	assert.Equal(t, "hi\n", result.Output)
+
+	cfg.Applications = append(cfg.Applications, cfg.Applications[0])
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,189 @@
+package jobscheduler
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrArtifactNotFound is returned when a job has no artifact with a name,
+// or it has expired
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// Artifact is a file kept from an application's working directory after it
+// exited
+type Artifact struct {
+	Name string `json:"name"` // Path relative to the working directory, with forward slashes
+	Size int64  `json:"size"`
+}
+
+// WorkDirCleanup decides when an application's scratch directory is removed
+type WorkDirCleanup string
+
+const (
+	// WorkDirCleanupAlways removes it as soon as the application exits. It
+	// is the default.
+	WorkDirCleanupAlways WorkDirCleanup = "always"
+
+	// WorkDirCleanupOnSuccess keeps it if the application failed, so that
+	// it can be inspected
+	WorkDirCleanupOnSuccess WorkDirCleanup = "on_success"
+
+	// WorkDirCleanupNever always keeps it
+	WorkDirCleanupNever WorkDirCleanup = "never"
+)
+
+// Valid reports whether the policy is known; empty means the default
+func (c WorkDirCleanup) Valid() bool {
+	switch c {
+	case "", WorkDirCleanupAlways, WorkDirCleanupOnSuccess, WorkDirCleanupNever:
+		return true
+	}
+	return false
+}
+
+// validateArtifactPattern checks that an artifact glob pattern is well
+// formed and cannot match outside the working directory
+func validateArtifactPattern(pattern string) error {
+	if !filepath.IsLocal(pattern) {
+		return fmt.Errorf("artifact pattern %q must be a relative path within the working directory", pattern)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid artifact pattern %q: %v", pattern, err)
+	}
+	return nil
+}
+
+// artifactPath returns the directory a job's artifacts are kept in. The
+// job ID is escaped so that it cannot name a path outside dir.
+func artifactPath(dir, jobID string) string {
+	name := url.PathEscape(jobID)
+	if strings.HasPrefix(name, ".") {
+		name = "%2E" + name[1:]
+	}
+	return filepath.Join(dir, name)
+}
+
+// artifactDir returns the directory artifacts are kept in
+func (c Config) artifactDir() string {
+	if c.ArtifactDir != "" {
+		return c.ArtifactDir
+	}
+	return filepath.Join(c.WorkDir, "artifacts")
+}
+
+// ListArtifacts returns the artifacts a finished job kept. Their files may
+// since have expired.
+func (s *Scheduler) ListArtifacts(jobID string) ([]Artifact, error) {
+	result, err := s.registry.result(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return result.Artifacts, nil
+}
+
+// OpenArtifact opens an artifact of a finished job for reading
+func (s *Scheduler) OpenArtifact(jobID, name string) (*os.File, error) {
+	artifacts, err := s.ListArtifacts(jobID)
+	if err != nil {
+		return nil, err
+	}
+	for _, artifact := range artifacts {
+		if artifact.Name != name {
+			continue
+		}
+		f, err := os.Open(filepath.Join(artifactPath(s.config.artifactDir(), jobID), filepath.FromSlash(name)))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("artifact %s of job %s has expired: %w", name, jobID, ErrArtifactNotFound)
+		}
+		return f, err
+	}
+	return nil, fmt.Errorf("artifact %s of job %s: %w", name, jobID, ErrArtifactNotFound)
+}
+
+// removeArtifacts deletes every artifact a job kept
+func (s *Scheduler) removeArtifacts(jobID string) {
+	os.RemoveAll(artifactPath(s.config.artifactDir(), jobID))
+}
+
+// sweepInterval is how often expired artifacts are looked for
+func sweepInterval(retention time.Duration) time.Duration {
+	return max(min(retention/4, time.Hour), time.Second)
+}
+
+// runRetention removes artifacts and kept scratch directories once they
+// are older than Config.ArtifactRetention, the files of jobs' output once
+// older than Config.OutputRetention, and finished jobs once older than
+// Config.JobRetention or beyond Config.MaxFinishedJobs, until the scheduler
+// shuts down
+func (s *Scheduler) runRetention() {
+	artifacts, output, jobs := s.config.ArtifactRetention, s.config.OutputRetention, s.config.JobRetention
+	var interval time.Duration
+	for _, retention := range []time.Duration{artifacts, output, jobs} {
+		if retention > 0 && (interval == 0 || retention < interval) {
+			interval = retention
+		}
+	}
+	if interval == 0 {
+		// Only the number of finished jobs is limited
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(sweepInterval(interval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if artifacts > 0 {
+				s.sweepArtifacts(now.Add(-artifacts))
+			}
+			if output > 0 {
+				s.sweepOutput(now.Add(-output))
+			}
+			if jobs > 0 || s.config.MaxFinishedJobs > 0 {
+				s.sweepJobs(now)
+			}
+		}
+	}
+}
+
+// sweepArtifacts removes the artifacts of jobs that collected them before
+// cutoff, and scratch directories last used before it. Artifacts also go
+// with their job whenever it is purged or forgotten, whatever their age.
+func (s *Scheduler) sweepArtifacts(cutoff time.Time) {
+	sweepDir(s.config.artifactDir(), cutoff)
+	s.executor.RemoveWorkDirs(cutoff)
+}
+
+// sweepJobs forgets the finished jobs that are past Config.JobRetention or
+// beyond Config.MaxFinishedJobs, along with their artifacts, output and log.
+// Their records are deleted from the store, which compacts itself once
+// enough of them are gone.
+func (s *Scheduler) sweepJobs(now time.Time) {
+	var cutoff time.Time
+	if s.config.JobRetention > 0 {
+		cutoff = now.Add(-s.config.JobRetention)
+	}
+	for _, jobID := range s.registry.evict(cutoff, s.config.MaxFinishedJobs) {
+		s.removeArtifacts(jobID)
+		s.removeOutput(jobID)
+		s.logs.remove(jobID)
+	}
+}
+
+// sweepDir removes the entries of dir last modified before cutoff
+func sweepDir(dir string, cutoff time.Time) {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err == nil && info.ModTime().Before(cutoff) {
+			os.RemoveAll(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package jobscheduler
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkingDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+	cfg.WorkDir = filepath.Join(tmpDir, "work")
+	cfg.WorkDirCleanup = WorkDirCleanupOnSuccess
+	cfg.Channels = []ChannelConfig{{Name: "parallel", Workers: 2}}
+
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	defer scheduler.Shutdown()
+
+	t.Run("Isolated", func(t *testing.T) {
+		// Both jobs write the same file at once, and each reads back its own
+		for _, id := range []string{"first", "second"} {
+			require.NoError(t, scheduler.SubmitJob(shellJob(id, "parallel", "echo "+id+" > out.txt; sleep 0.2; cat out.txt")))
+		}
+		for _, id := range []string{"first", "second"} {
+			waitForStatus(t, scheduler, id, JobStatusComplete)
+			result, err := scheduler.GetJobResult(id)
+			require.NoError(t, err)
+			assert.Equal(t, id+"\n", result.Output)
+			assert.Empty(t, result.WorkDir)
+		}
+	})
+
+	t.Run("KeptOnFailure", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(shellJob("kept", "parallel", "echo debug > trace.log; exit 1")))
+		waitForStatus(t, scheduler, "kept", JobStatusFailed)
+		result, err := scheduler.GetJobResult("kept")
+		require.NoError(t, err)
+		require.NotEmpty(t, result.WorkDir)
+		data, err := os.ReadFile(filepath.Join(result.WorkDir, "trace.log"))
+		require.NoError(t, err)
+		assert.Equal(t, "debug\n", string(data))
+
+		// Retention removes it again
+		scheduler.sweepArtifacts(time.Now().Add(time.Hour))
+		assert.NoDirExists(t, result.WorkDir)
+	})
+
+	t.Run("WorkingDirOutsideScratch", func(t *testing.T) {
+		job := shellJob("escape", "parallel", "true")
+		job.Application.WorkingDir = "../shared"
+		assert.Error(t, scheduler.SubmitJob(job))
+	})
+}
+
+func TestArtifacts(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	run := func(t *testing.T, job JobPayload, status JobStatus) *JobResult {
+		t.Helper()
+		require.NoError(t, scheduler.SubmitJob(job))
+		waitForStatus(t, scheduler, job.ID, status)
+		result, err := scheduler.GetJobResult(job.ID)
+		require.NoError(t, err)
+		return result
+	}
+
+	readArtifact := func(t *testing.T, jobID, name string) string {
+		t.Helper()
+		f, err := scheduler.OpenArtifact(jobID, name)
+		require.NoError(t, err)
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	producer := JobPayload{
+		ID:      "producer",
+		Channel: "artifacts-channel",
+		Application: &ApplicationConfig{
+			Name: "sh",
+			Path: "sh",
+			Args: []string{"-c", strings.Join([]string{
+				"mkdir out",
+				"echo a,b > out/report.csv",
+				"echo done > run.txt",
+				"echo skipped > notes.md",
+				"ln -s /etc/passwd secrets.txt",
+			}, " && ")},
+			Artifacts: []string{"out/*.csv", "*.txt"},
+		},
+	}
+
+	t.Run("Collect", func(t *testing.T) {
+		result := run(t, producer, JobStatusComplete)
+		assert.Equal(t, []Artifact{{Name: "out/report.csv", Size: 4}, {Name: "run.txt", Size: 5}}, result.Artifacts)
+		assert.Equal(t, "a,b\n", readArtifact(t, "producer", "out/report.csv"))
+
+		artifacts, err := scheduler.ListArtifacts("producer")
+		require.NoError(t, err)
+		assert.Len(t, artifacts, 2)
+
+		_, err = scheduler.OpenArtifact("producer", "secrets.txt")
+		assert.ErrorIs(t, err, ErrArtifactNotFound)
+		_, err = scheduler.OpenArtifact("producer", "../processing.log")
+		assert.ErrorIs(t, err, ErrArtifactNotFound)
+	})
+
+	t.Run("AsInput", func(t *testing.T) {
+		consumer := JobPayload{
+			ID:      "consumer",
+			Channel: "artifacts-channel",
+			Application: &ApplicationConfig{
+				Name:   "cat",
+				Path:   "cat",
+				Args:   []string{"report.csv"},
+				Inputs: []JobInput{{JobID: "producer", Source: InputArtifact, Artifact: "out/report.csv", File: "report.csv"}},
+			},
+		}
+		result := run(t, consumer, JobStatusComplete)
+		assert.Equal(t, "a,b\n", result.Output)
+	})
+
+	t.Run("FailedJob", func(t *testing.T) {
+		job := JobPayload{
+			ID:      "failed-producer",
+			Channel: "artifacts-channel",
+			Application: &ApplicationConfig{
+				Name:      "sh",
+				Path:      "sh",
+				Args:      []string{"-c", "echo partial > partial.txt; exit 2"},
+				Artifacts: []string{"*.txt"},
+			},
+		}
+		result := run(t, job, JobStatusFailed)
+		assert.Equal(t, []Artifact{{Name: "partial.txt", Size: 8}}, result.Artifacts)
+	})
+
+	t.Run("SymlinkEscape", func(t *testing.T) {
+		host := t.TempDir()
+		secret := filepath.Join(host, "id_rsa")
+		require.NoError(t, os.WriteFile(secret, []byte("key"), 0600))
+
+		// Neither a linked directory in a pattern nor a working directory
+		// swapped for a link reaches files outside the scratch directory
+		linked := JobPayload{
+			ID:      "linked-dir",
+			Channel: "artifacts-channel",
+			Application: &ApplicationConfig{
+				Name:      "sh",
+				Path:      "sh",
+				Args:      []string{"-c", "ln -s " + host + " link"},
+				Artifacts: []string{"link/*", "*/id_rsa"},
+			},
+		}
+		swapped := JobPayload{
+			ID:      "swapped-dir",
+			Channel: "artifacts-channel",
+			Application: &ApplicationConfig{
+				Name:       "sh",
+				Path:       "sh",
+				Args:       []string{"-c", "cd .. && rmdir work && ln -s " + host + " work"},
+				WorkingDir: "work",
+				Artifacts:  []string{"*"},
+			},
+		}
+		for _, job := range []JobPayload{linked, swapped} {
+			result := run(t, job, JobStatusComplete)
+			assert.Empty(t, result.Artifacts, job.ID)
+			assert.FileExists(t, secret, job.ID)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		scheduler.sweepArtifacts(time.Now().Add(time.Hour))
+		_, err := scheduler.OpenArtifact("producer", "run.txt")
+		assert.ErrorIs(t, err, ErrArtifactNotFound)
+	})
+
+	t.Run("InvalidPatterns", func(t *testing.T) {
+		for _, pattern := range []string{"../*.txt", "/etc/*", "["} {
+			job := producer
+			job.ID = "invalid-pattern"
+			job.Application = &ApplicationConfig{Name: "true", Path: "true", Artifacts: []string{pattern}}
+			assert.Error(t, scheduler.SubmitJob(job), pattern)
+		}
+
+		// Artifacts are never collected from outside the scratch directory
+		job := producer
+		job.ID = "absolute-working-dir"
+		job.Application = &ApplicationConfig{Name: "true", Path: "true", WorkingDir: t.TempDir(), Artifacts: []string{"*"}}
+		assert.Error(t, scheduler.SubmitJob(job))
+	})
+
+	t.Run("EscapedJobIDs", func(t *testing.T) {
+		for _, id := range []string{"..", "../other", "a/b", "."} {
+			path := artifactPath("/artifacts", id)
+			assert.Equal(t, "/artifacts", filepath.Dir(path), id)
+			assert.NotContains(t, []string{".", ".."}, filepath.Base(path), id)
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package jobscheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrChannelNotFound is returned when no channel exists with a name
+	ErrChannelNotFound = errors.New("channel not found")
+
+	// ErrChannelExists is returned when creating a channel that already exists
+	ErrChannelExists = errors.New("channel already exists")
+
+	// ErrChannelBusy is returned when deleting a channel with unfinished jobs
+	ErrChannelBusy = errors.New("channel has unfinished jobs")
+
+	// ErrChannelFull is returned when a channel cannot accept more jobs
+	ErrChannelFull = errors.New("channel is full")
+)
+
+// ChannelConfig declares the settings of a channel. Zero values take the
+// scheduler's defaults.
+type ChannelConfig struct {
+	Name         string        `json:"name"`
+	Workers      int           `json:"workers"`        // Jobs run at once
+	Timeout      time.Duration `json:"timeout"`        // Per-job timeout
+	KillTimeout  time.Duration `json:"kill_timeout"`   // Grace period between SIGTERM and SIGKILL
+	BufferSize   int           `json:"buffer_size"`    // Jobs waiting for a worker
+	MaxQueueSize int           `json:"max_queue_size"` // Unfinished jobs of any status
+	Sandbox      bool          `json:"sandbox"`        // Sandbox every application, whether or not it asks
+}
+
+// Validate checks if the channel configuration is valid
+func (c ChannelConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("channel name cannot be empty")
+	}
+	if c.Workers < 0 {
+		return fmt.Errorf("workers cannot be negative")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	if c.KillTimeout < 0 {
+		return fmt.Errorf("kill timeout cannot be negative")
+	}
+	if c.BufferSize < 0 {
+		return fmt.Errorf("buffer size cannot be negative")
+	}
+	if c.MaxQueueSize < 0 {
+		return fmt.Errorf("max queue size cannot be negative")
+	}
+	return nil
+}
+
+// ChannelStatus is a channel's settings together with its current load
+type ChannelStatus struct {
+	ChannelConfig
+	Declared bool `json:"declared"` // Created through CreateChannel or Config.Channels rather than by a job
+	Queued   int  `json:"queued"`   // Jobs waiting for a worker
+	Running  int  `json:"running"`
+}
+
+// Channel represents a processing channel
+type Channel struct {
+	Name      string
+	queue     *jobQueue
+	workers   *workerPool
+	processor *Processor
+	stop      context.CancelFunc // Stops the processor
+	declared  bool
+
+	mu     sync.RWMutex
+	config ChannelConfig
+}
+
+// settings returns the channel's current configuration
+func (c *Channel) settings() ChannelConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// reconfigure applies new settings. Jobs already running keep the timeout
+// they started with; a smaller worker pool takes effect as they finish.
+func (c *Channel) reconfigure(cfg ChannelConfig) {
+	c.mu.Lock()
+	c.config = cfg
+	c.mu.Unlock()
+
+	c.queue.setCapacity(cfg.BufferSize)
+	c.workers.resize(cfg.Workers)
+}
+
+// channelDefaults fills in the zero fields of a channel configuration
+func (s *Scheduler) channelDefaults(cfg ChannelConfig) ChannelConfig {
+	if cfg.Workers == 0 {
+		cfg.Workers = s.config.DefaultWorkers
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = s.config.DefaultTimeout
+	}
+	if cfg.KillTimeout == 0 {
+		cfg.KillTimeout = s.config.killTimeout()
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = s.config.ChannelBufferSize
+	}
+	if cfg.MaxQueueSize == 0 {
+		cfg.MaxQueueSize = s.config.MaxQueueSize
+	}
+	return cfg
+}
+
+// createChannelLocked creates a channel and starts its processor. The
+// caller must hold s.mu.
+func (s *Scheduler) createChannelLocked(cfg ChannelConfig, declared bool) *Channel {
+	cfg = s.channelDefaults(cfg)
+	ctx, stop := context.WithCancel(s.ctx)
+	channel := &Channel{
+		Name:     cfg.Name,
+		queue:    newJobQueue(cfg.BufferSize, s.config.PriorityAgingInterval),
+		workers:  newWorkerPool(cfg.Workers),
+		stop:     stop,
+		declared: declared,
+		config:   cfg,
+	}
+
+	// Initialize channel processor
+	processor := NewProcessor(ProcessorConfig{
+		Channel:       channel,
+		Executor:      s.executor,
+		MaxOutputSize: s.config.MaxOutputSize,
+		Registry:      s.registry,
+		Handlers:      s.handlers,
+		Applications:  s.applications,
+		Logs:          s.logs,
+
+		WorkDirCleanup: s.config.WorkDirCleanup,
+		ArtifactDir:    s.config.artifactDir(),
+		OutputDir:      s.config.outputDir(),
+
+		SandboxUntrusted: s.config.SandboxUntrusted,
+		SandboxNetwork:   s.config.SandboxNetwork,
+		Retry:            s.retryJob,
+		Finished:         s.jobFinished,
+		Record:           s.recordEvent,
+	})
+
+	channel.processor = processor
+	s.channels[cfg.Name] = channel
+	s.stats.open(cfg.Name, time.Now())
+
+	// Start the processor
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		processor.Start(ctx)
+	}()
+	return channel
+}
+
+// CreateChannel declares a channel with explicit settings. It fails if the
+// channel already exists, including one created implicitly by a job.
+func (s *Scheduler) CreateChannel(cfg ChannelConfig) (*ChannelStatus, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid channel: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.channels[cfg.Name]; exists {
+		return nil, fmt.Errorf("channel %s: %w", cfg.Name, ErrChannelExists)
+	}
+	channel := s.createChannelLocked(cfg, true)
+	return s.channelStatus(channel), nil
+}
+
+// UpdateChannel replaces the settings of an existing channel without
+// interrupting its jobs. Growing the worker pool lets queued jobs start at
+// once; shrinking it lets running jobs finish before fewer are started. A
+// new timeout applies to jobs that start afterwards, and a smaller buffer
+// only turns away new submissions.
+func (s *Scheduler) UpdateChannel(cfg ChannelConfig) (*ChannelStatus, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid channel: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel, exists := s.channels[cfg.Name]
+	if !exists {
+		return nil, fmt.Errorf("channel %s: %w", cfg.Name, ErrChannelNotFound)
+	}
+	channel.reconfigure(s.channelDefaults(cfg))
+	channel.declared = true
+	return s.channelStatus(channel), nil
+}
+
+// DeleteChannel stops a channel's processor and forgets its statistics. A
+// channel with jobs that are queued, running or waiting to run cannot be
+// deleted.
+func (s *Scheduler) DeleteChannel(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel, exists := s.channels[name]
+	if !exists {
+		return fmt.Errorf("channel %s: %w", name, ErrChannelNotFound)
+	}
+	if n := s.registry.unfinished(name); n > 0 {
+		return fmt.Errorf("channel %s: %w (%d)", name, ErrChannelBusy, n)
+	}
+
+	channel.stop()
+	delete(s.channels, name)
+	s.stats.forget(name)
+	return nil
+}
+
+// GetChannel returns the settings and load of a channel
+func (s *Scheduler) GetChannel(name string) (*ChannelStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channel, exists := s.channels[name]
+	if !exists {
+		return nil, fmt.Errorf("channel %s: %w", name, ErrChannelNotFound)
+	}
+	return s.channelStatus(channel), nil
+}
+
+// ListChannels returns every channel, ordered by name
+func (s *Scheduler) ListChannels() []ChannelStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channels := make([]ChannelStatus, 0, len(s.channels))
+	for _, channel := range s.channels {
+		channels = append(channels, *s.channelStatus(channel))
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Name < channels[j].Name
+	})
+	return channels
+}
+
+// channelStatus reports a channel's settings and load
+func (s *Scheduler) channelStatus(channel *Channel) *ChannelStatus {
+	return &ChannelStatus{
+		ChannelConfig: channel.settings(),
+		Declared:      channel.declared,
+		Queued:        channel.queue.len(),
+		Running:       len(channel.processor.GetActiveJobs()),
+	}
+}
+
+// workerPool limits how many jobs of a channel run at once. Unlike a
+// buffered channel it can be resized while jobs are running.
+type workerPool struct {
+	mu     sync.Mutex
+	size   int
+	active int
+	notify chan struct{} // Signalled when a slot is freed or the pool grows
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{
+		size:   size,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// acquire waits for a free slot, returning false if ctx is done first
+func (w *workerPool) acquire(ctx context.Context) bool {
+	for {
+		w.mu.Lock()
+		if w.active < w.size {
+			w.active++
+			w.mu.Unlock()
+			return true
+		}
+		w.mu.Unlock()
+
+		select {
+		case <-w.notify:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// release frees a slot taken by acquire
+func (w *workerPool) release() {
+	w.mu.Lock()
+	w.active--
+	w.mu.Unlock()
+	w.signal()
+}
+
+// resize changes the number of slots. Slots in use above a smaller size
+// are given up as they are released.
+func (w *workerPool) resize(size int) {
+	w.mu.Lock()
+	w.size = size
+	w.mu.Unlock()
+	w.signal()
+}
+
+func (w *workerPool) signal() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,186 @@
+package jobscheduler
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannels(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	running := func(channel string) int {
+		status, err := scheduler.GetChannel(channel)
+		require.NoError(t, err)
+		return status.Running
+	}
+
+	t.Run("CRUD", func(t *testing.T) {
+		created, err := scheduler.CreateChannel(ChannelConfig{Name: "managed", Workers: 3})
+		require.NoError(t, err)
+		assert.Equal(t, 3, created.Workers)
+		assert.Equal(t, 5*time.Second, created.Timeout)
+		assert.Equal(t, 10, created.BufferSize)
+		assert.Equal(t, 100, created.MaxQueueSize)
+		assert.True(t, created.Declared)
+
+		_, err = scheduler.CreateChannel(ChannelConfig{Name: "managed"})
+		assert.ErrorIs(t, err, ErrChannelExists)
+		_, err = scheduler.CreateChannel(ChannelConfig{Name: "negative", Workers: -1})
+		assert.Error(t, err)
+
+		updated, err := scheduler.UpdateChannel(ChannelConfig{Name: "managed", Workers: 5, Timeout: time.Minute})
+		require.NoError(t, err)
+		assert.Equal(t, 5, updated.Workers)
+		assert.Equal(t, time.Minute, updated.Timeout)
+		_, err = scheduler.UpdateChannel(ChannelConfig{Name: "missing"})
+		assert.ErrorIs(t, err, ErrChannelNotFound)
+
+		var names []string
+		for _, channel := range scheduler.ListChannels() {
+			names = append(names, channel.Name)
+		}
+		assert.Contains(t, names, "managed")
+		assert.Equal(t, 5, scheduler.GetChannelStats()["managed"].Workers)
+
+		require.NoError(t, scheduler.DeleteChannel("managed"))
+		_, err = scheduler.GetChannel("managed")
+		assert.ErrorIs(t, err, ErrChannelNotFound)
+		assert.ErrorIs(t, scheduler.DeleteChannel("managed"), ErrChannelNotFound)
+	})
+
+	t.Run("ImplicitChannel", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "implicit-job",
+			Channel:     "implicit",
+			Workers:     2,
+			Application: &ApplicationConfig{Name: "true", Path: "true"},
+		}))
+
+		status, err := scheduler.GetChannel("implicit")
+		require.NoError(t, err)
+		assert.Equal(t, 2, status.Workers)
+		assert.False(t, status.Declared)
+
+		_, err = scheduler.CreateChannel(ChannelConfig{Name: "implicit"})
+		assert.ErrorIs(t, err, ErrChannelExists)
+	})
+
+	t.Run("ResizeWorkers", func(t *testing.T) {
+		_, err := scheduler.CreateChannel(ChannelConfig{Name: "resized", Workers: 1})
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, scheduler.SubmitJob(sleepJob(fmt.Sprintf("resize-%d", i), "resized", "1")))
+		}
+		require.Eventually(t, func() bool { return running("resized") == 1 }, time.Second, 10*time.Millisecond)
+
+		// Growing the pool starts the queued jobs straight away
+		_, err = scheduler.UpdateChannel(ChannelConfig{Name: "resized", Workers: 3})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return running("resized") == 3 }, time.Second, 10*time.Millisecond)
+
+		// Shrinking it lets the running jobs finish
+		_, err = scheduler.UpdateChannel(ChannelConfig{Name: "resized", Workers: 1})
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			waitForStatus(t, scheduler, fmt.Sprintf("resize-%d", i), JobStatusComplete)
+		}
+
+		require.NoError(t, scheduler.SubmitJob(sleepJob("resize-a", "resized", "0.5")))
+		require.NoError(t, scheduler.SubmitJob(sleepJob("resize-b", "resized", "0.5")))
+		waitForStatus(t, scheduler, "resize-a", JobStatusRunning)
+		assert.Equal(t, 1, running("resized"))
+		waitForStatus(t, scheduler, "resize-b", JobStatusComplete)
+	})
+
+	t.Run("UpdateTimeout", func(t *testing.T) {
+		_, err := scheduler.CreateChannel(ChannelConfig{Name: "timeouts"})
+		require.NoError(t, err)
+		_, err = scheduler.UpdateChannel(ChannelConfig{Name: "timeouts", Timeout: 100 * time.Millisecond})
+		require.NoError(t, err)
+
+		require.NoError(t, scheduler.SubmitJob(sleepJob("short-timeout", "timeouts", "2")))
+		waitForStatus(t, scheduler, "short-timeout", JobStatusTimedOut)
+	})
+
+	t.Run("MaxQueueSize", func(t *testing.T) {
+		_, err := scheduler.CreateChannel(ChannelConfig{Name: "bounded", MaxQueueSize: 2})
+		require.NoError(t, err)
+
+		job := sleepJob("bounded-later", "bounded", "0")
+		job.Delay = time.Hour
+		require.NoError(t, scheduler.SubmitJob(job))
+		require.NoError(t, scheduler.SubmitJob(sleepJob("bounded-running", "bounded", "5")))
+		err = scheduler.SubmitJob(sleepJob("bounded-rejected", "bounded", "0"))
+		assert.ErrorIs(t, err, ErrChannelFull)
+
+		// A job that finishes makes room for another
+		require.NoError(t, scheduler.CancelJob("bounded-later"))
+		job.ID = "bounded-freed"
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		// Unfinished jobs keep the channel from being deleted
+		assert.ErrorIs(t, scheduler.DeleteChannel("bounded"), ErrChannelBusy)
+		require.NoError(t, scheduler.CancelJob("bounded-freed"))
+		require.NoError(t, scheduler.CancelJob("bounded-running"))
+		waitForStatus(t, scheduler, "bounded-running", JobStatusCancelled)
+		assert.NoError(t, scheduler.DeleteChannel("bounded"))
+	})
+
+	t.Run("BufferSize", func(t *testing.T) {
+		_, err := scheduler.CreateChannel(ChannelConfig{Name: "buffered", BufferSize: 1})
+		require.NoError(t, err)
+
+		require.NoError(t, scheduler.SubmitJob(sleepJob("buffered-running", "buffered", "5")))
+		waitForStatus(t, scheduler, "buffered-running", JobStatusRunning)
+		require.NoError(t, scheduler.SubmitJob(sleepJob("buffered-waiting", "buffered", "0")))
+		err = scheduler.SubmitJob(sleepJob("buffered-rejected", "buffered", "0"))
+		assert.ErrorIs(t, err, ErrChannelFull)
+
+		require.NoError(t, scheduler.CancelJob("buffered-running"))
+		waitForStatus(t, scheduler, "buffered-waiting", JobStatusComplete)
+	})
+}
+
+func TestDeclaredChannels(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+	cfg.WorkDir = tmpDir
+	cfg.KillTimeout = 0
+	cfg.Channels = []ChannelConfig{
+		{Name: "reports", Workers: 4, Timeout: time.Minute},
+		{Name: "emails"},
+	}
+
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	defer scheduler.Shutdown()
+
+	channels := scheduler.ListChannels()
+	require.Len(t, channels, 2)
+	assert.Equal(t, "emails", channels[0].Name)
+	assert.Equal(t, cfg.DefaultWorkers, channels[0].Workers)
+	// Applications are never killed without a grace period
+	assert.Equal(t, 10*time.Second, channels[0].KillTimeout)
+	assert.Equal(t, "reports", channels[1].Name)
+	assert.Equal(t, 4, channels[1].Workers)
+
+	// Job settings do not override a declared channel
+	require.NoError(t, scheduler.SubmitJob(JobPayload{
+		ID:          "report",
+		Channel:     "reports",
+		Workers:     1,
+		Application: &ApplicationConfig{Name: "true", Path: "true"},
+	}))
+	status, err := scheduler.GetChannel("reports")
+	require.NoError(t, err)
+	assert.Equal(t, 4, status.Workers)
+
+	cfg.Channels = append(cfg.Channels, ChannelConfig{Name: "emails"})
+	assert.Error(t, cfg.Validate())
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -19,6 +21,7 @@ var (
 )
 
 func main() {
+	jobscheduler.MaybeRunHelper()
 	flag.Parse()
 
 	// Initialize the scheduler
@@ -30,7 +33,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create scheduler: %v", err)
 	}
-	defer scheduler.Close()
+	defer scheduler.Shutdown()
 
 	// Set up graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -101,7 +104,22 @@ func submitExampleJobs(scheduler *jobscheduler.Scheduler, done chan struct{}) {
 		log.Printf("Failed to submit processing job: %v", err)
 	}
 
-	// Example 3: Regular job without application execution
+	// Example 3: Regular job handled in-process by a registered handler
+	err = scheduler.RegisterHandler("notifications", func(ctx context.Context, job jobscheduler.JobPayload) (jobscheduler.JobResult, error) {
+		var notification struct {
+			Type      string `json:"type"`
+			Recipient string `json:"recipient"`
+		}
+		if err := json.Unmarshal(job.Body, &notification); err != nil {
+			return jobscheduler.JobResult{}, fmt.Errorf("invalid notification: %v", err)
+		}
+		log.Printf("Sending %s notification to %s", notification.Type, notification.Recipient)
+		return jobscheduler.JobResult{}, nil
+	})
+	if err != nil {
+		log.Printf("Failed to register notification handler: %v", err)
+	}
+
 	regularJob := jobscheduler.JobPayload{
 		ID:      "notification-1",
 		Channel: "notifications",
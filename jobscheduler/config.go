@@ -7,8 +7,9 @@ import (
 
 // Config contains configuration options for the scheduler
 type Config struct {
-	// File path for processing log
+	// File path for processing log, and how it is written and rotated
 	ProcessingLogPath string
+	ProcessingLog     ProcessingLogConfig
 
 	// Default number of workers per channel if not specified
 	DefaultWorkers int
@@ -16,20 +17,96 @@ type Config struct {
 	// Default timeout for job processing
 	DefaultTimeout time.Duration
 
+	// Grace period between SIGTERM and SIGKILL (10 seconds if zero)
+	KillTimeout time.Duration
+
 	// Maximum number of jobs that can be queued per channel
 	MaxQueueSize int
 
 	// Working directory for job execution
 	WorkDir string
 
+	// When scratch directories are removed (always by default)
+	WorkDirCleanup WorkDirCleanup
+
+	// Directory for collected artifacts (WorkDir/artifacts by default)
+	ArtifactDir string
+
+	// How long artifacts and kept scratch directories are kept (forever if zero)
+	ArtifactRetention time.Duration
+
+	// Reap processes orphaned by jobs here rather than by init (Linux only)
+	Subreaper bool
+
+	// Delegated cgroup v2 directory for applications with resource limits
+	CgroupRoot string
+
+	// Sandbox the applications of jobs marked Untrusted
+	SandboxUntrusted bool
+
+	// Let applications with a forced sandbox share the host's network if they ask
+	SandboxNetwork bool
+
 	// Maximum output size to capture from job execution (bytes)
 	MaxOutputSize int64
 
+	// Directory for the whole output of jobs (WorkDir/output by default)
+	OutputDir string
+
+	// How long the whole output of jobs is kept (forever if zero)
+	OutputRetention time.Duration
+
+	// Lines of output kept per job for following it (1000 by default)
+	LogLines int
+
+	// How long a finished job's followable output is kept (an hour by default)
+	LogRetention time.Duration
+
+	// How long finished jobs are kept, and how many at most (no limit if zero)
+	JobRetention    time.Duration
+	MaxFinishedJobs int
+
 	// Grace period for shutdown
 	ShutdownTimeout time.Duration
 
 	// Channel buffer size
 	ChannelBufferSize int
+
+	// Store for jobs (a FileStore at StorePath, or in memory if that is empty)
+	Store JobStore
+
+	// File path for the persistent job store
+	StorePath string
+
+	// Waiting time each priority level is worth (strict priority if zero)
+	PriorityAgingInterval time.Duration
+
+	// Retry policy for failed and timed-out jobs (retries are off by default)
+	RetryPolicy RetryPolicy
+
+	// Retry policies for specific channels, overriding RetryPolicy
+	ChannelRetryPolicies map[string]RetryPolicy
+
+	// Channels created at startup
+	Channels []ChannelConfig
+
+	// Applications that jobs can run by name, registered at startup
+	Applications []Application
+
+	// Only run registered applications; a job's application must name one
+	RestrictApplications bool
+}
+
+// defaultKillTimeout is the grace period a timed-out or cancelled
+// application gets when none is configured
+const defaultKillTimeout = 10 * time.Second
+
+// killTimeout returns the grace period applications get before being killed
+func (c Config) killTimeout() time.Duration {
+	if c.KillTimeout > 0 {
+		return c.KillTimeout
+	}
+	return defaultKillTimeout
 }
 
 // DefaultConfig returns a configuration with default values
@@ -38,11 +115,14 @@ func DefaultConfig() Config {
 		ProcessingLogPath: "processing.log",
 		DefaultWorkers:    1,
 		DefaultTimeout:    5 * time.Minute,
+		KillTimeout:       defaultKillTimeout,
 		MaxQueueSize:      10000,
 		WorkDir:           "/tmp/jobscheduler",
 		MaxOutputSize:     1024 * 1024, // 1MB
 		ShutdownTimeout:   30 * time.Second,
 		ChannelBufferSize: 1000,
+
+		PriorityAgingInterval: 30 * time.Second,
 	}
 }
 
@@ -51,23 +131,81 @@ func (c *Config) Validate() error {
 	if c.ProcessingLogPath == "" {
 		return fmt.Errorf("processing log path cannot be empty")
 	}
+	if err := c.ProcessingLog.Validate(); err != nil {
+		return fmt.Errorf("invalid processing log: %v", err)
+	}
 	if c.DefaultWorkers < 1 {
 		return fmt.Errorf("default workers must be at least 1")
 	}
 	if c.DefaultTimeout < time.Second {
 		return fmt.Errorf("default timeout must be at least 1 second")
 	}
+	if c.KillTimeout < 0 {
+		return fmt.Errorf("kill timeout cannot be negative")
+	}
 	if c.MaxQueueSize < 1 {
 		return fmt.Errorf("max queue size must be at least 1")
 	}
 	if c.WorkDir == "" {
 		return fmt.Errorf("work directory cannot be empty")
 	}
+	if !c.WorkDirCleanup.Valid() {
+		return fmt.Errorf("unknown work directory cleanup policy: %s", c.WorkDirCleanup)
+	}
+	if c.ArtifactRetention < 0 {
+		return fmt.Errorf("artifact retention cannot be negative")
+	}
+	if c.OutputRetention < 0 {
+		return fmt.Errorf("output retention cannot be negative")
+	}
+	if c.JobRetention < 0 {
+		return fmt.Errorf("job retention cannot be negative")
+	}
+	if c.MaxFinishedJobs < 0 {
+		return fmt.Errorf("max finished jobs cannot be negative")
+	}
+	if c.LogLines < 0 {
+		return fmt.Errorf("log lines cannot be negative")
+	}
+	if c.LogRetention < 0 {
+		return fmt.Errorf("log retention cannot be negative")
+	}
 	if c.ShutdownTimeout < time.Second {
 		return fmt.Errorf("shutdown timeout must be at least 1 second")
 	}
 	if c.ChannelBufferSize < 1 {
 		return fmt.Errorf("channel buffer size must be at least 1")
 	}
+	if c.PriorityAgingInterval < 0 {
+		return fmt.Errorf("priority aging interval cannot be negative")
+	}
+	if err := c.RetryPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %v", err)
+	}
+	for channel, policy := range c.ChannelRetryPolicies {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("invalid retry policy for channel %s: %v", channel, err)
+		}
+	}
+	names := make(map[string]bool)
+	for _, channel := range c.Channels {
+		if err := channel.Validate(); err != nil {
+			return fmt.Errorf("invalid channel %s: %v", channel.Name, err)
+		}
+		if names[channel.Name] {
+			return fmt.Errorf("channel %s is declared more than once", channel.Name)
+		}
+		names[channel.Name] = true
+	}
+	apps := make(map[string]bool)
+	for _, app := range c.Applications {
+		if err := app.Validate(); err != nil {
+			return fmt.Errorf("invalid application %s: %v", app.Name, err)
+		}
+		if apps[app.Name] {
+			return fmt.Errorf("application %s is declared more than once", app.Name)
+		}
+		apps[app.Name] = true
+	}
 	return nil
 }
@@ -0,0 +1,268 @@
+package jobscheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule computes the activation times of a recurring schedule
+type CronSchedule interface {
+	// Next returns the first activation time strictly after t, or the zero
+	// time if there is none
+	Next(t time.Time) time.Time
+}
+
+// Shorthands accepted in place of a 5-field expression
+var cronShorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes the valid range and names of one expression field
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	// 7 is accepted as a second name for Sunday
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// Minimum interval accepted by @every
+const minCronInterval = time.Second
+
+// ParseCron parses a standard 5-field cron expression
+// (minute hour day-of-month month day-of-week), one of the @yearly,
+// @monthly, @weekly, @daily, @midnight or @hourly shorthands, or
+// "@every <duration>"
+func ParseCron(expr string) (CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if strings.HasPrefix(expr, "@every") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid @every interval: %v", err)
+		}
+		if interval < minCronInterval {
+			return nil, fmt.Errorf("@every interval must be at least %v", minCronInterval)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+	if strings.HasPrefix(expr, "@") {
+		full, ok := cronShorthands[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cron shorthand: %s", expr)
+		}
+		expr = full
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields, got %d", len(cronFields), len(fields))
+	}
+
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		masks[i] = mask
+	}
+
+	// Sunday may be written as 0 or 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+
+	return &cronExpr{
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     masks[4],
+		domStar: fields[2] == "*" || fields[2] == "?",
+		dowStar: fields[4] == "*" || fields[4] == "?",
+	}, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps
+// into a bit mask of the values it matches
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %s field: %s", spec.name, part)
+			}
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			low, high = spec.min, spec.max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], spec); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(bounds[1], spec); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range in %s field: %s", spec.name, rangePart)
+			}
+		default:
+			var err error
+			if low, err = parseCronValue(rangePart, spec); err != nil {
+				return 0, err
+			}
+			high = low
+			if step > 1 {
+				// "5/15" means every 15 starting at 5
+				high = spec.max
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// parseCronValue parses a single number or name within a field's range
+func parseCronValue(value string, spec cronField) (int, error) {
+	if n, ok := spec.names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %s", spec.name, value)
+	}
+	if n < spec.min || n > spec.max {
+		return 0, fmt.Errorf("%s must be between %d and %d, got %d", spec.name, spec.min, spec.max, n)
+	}
+	return n, nil
+}
+
+// cronExpr is a parsed 5-field cron expression
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// Search at most this many years ahead before giving up on an expression
+// that can never match, such as 30 February
+const cronSearchYears = 5
+
+// Next returns the first matching minute strictly after t, in t's location.
+// Local times skipped when clocks go forward are never matched, and those
+// repeated when they go back are only matched the first time.
+func (c *cronExpr) Next(t time.Time) time.Time {
+	loc := t.Location()
+	from := wallClock(t)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + cronSearchYears
+
+wrap:
+	for t.Year() <= limit {
+		for !hasBit(c.month, int(t.Month())) {
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			if t.Month() == time.January {
+				continue wrap
+			}
+		}
+		for !c.dayMatches(t) {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			if t.Day() == 1 {
+				continue wrap
+			}
+		}
+		// Hours and minutes are stepped in absolute time, as time.Date
+		// cannot step over a gap in local time
+		for !hasBit(c.hour, t.Hour()) {
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+			if t.Hour() == 0 {
+				continue wrap
+			}
+		}
+		for !hasBit(c.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			if t.Minute() == 0 {
+				continue wrap
+			}
+		}
+		if !wallClock(t).After(from) {
+			// Repeated local time
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// advance moves t on to next, a local time from time.Date. If a clock
+// change skips next, time.Date can normalise it to a time no later than t,
+// so t moves on by an hour instead.
+func advance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Hour)
+}
+
+// wallClock returns t's local date and time, as if it were UTC, so that
+// local times compare by how they read rather than when they happen
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// dayMatches applies the usual cron rule: when both day fields are
+// restricted, a day matching either of them matches
+func (c *cronExpr) dayMatches(t time.Time) bool {
+	domMatch := hasBit(c.dom, t.Day())
+	dowMatch := hasBit(c.dow, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func hasBit(mask uint64, n int) bool {
+	return mask&(1<<uint(n)) != 0
+}
+
+// everySchedule activates at a fixed interval
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Next returns t plus the interval
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(e.interval)
+}
@@ -0,0 +1,116 @@
+package jobscheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	from := time.Date(2024, time.January, 15, 10, 30, 0, 0, time.UTC) // A Monday
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, 1, 16, 2, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * sat,sun", time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Restricting both day fields matches either
+		{"0 0 20 * fri", time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2024, 1, 15, 10, 31, 30, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.next, schedule.Next(from))
+		})
+	}
+
+	t.Run("Timezone", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+		schedule, err := ParseCron("0 2 * * *")
+		require.NoError(t, err)
+		next := schedule.Next(from.In(loc))
+		assert.Equal(t, time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC), next.UTC())
+	})
+
+	t.Run("ClocksChange", func(t *testing.T) {
+		newYork, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+		santiago, err := time.LoadLocation("America/Santiago")
+		require.NoError(t, err)
+
+		tests := []struct {
+			name string
+			expr string
+			from time.Time
+			next time.Time
+		}{
+			// 02:00 to 02:59 never happen on 8 March
+			{"SkippedHour", "0 2 * * *", time.Date(2026, 3, 7, 20, 30, 0, 0, newYork), time.Date(2026, 3, 9, 6, 0, 0, 0, time.UTC)},
+			{"AcrossGap", "30 * * * *", time.Date(2026, 3, 8, 1, 45, 0, 0, newYork), time.Date(2026, 3, 8, 7, 30, 0, 0, time.UTC)},
+			// 01:00 to 01:59 happen twice on 1 November
+			{"RepeatedHour", "30 1 * * *", time.Date(2026, 11, 1, 0, 0, 0, 0, newYork), time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC)},
+			{"RepeatedHourOnce", "30 1 * * *", time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC).In(newYork), time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC)},
+			// Midnight never happens on 6 September, so that day is skipped
+			{"SkippedMidnight", "@daily", time.Date(2026, 9, 5, 12, 0, 0, 0, santiago), time.Date(2026, 9, 7, 3, 0, 0, 0, time.UTC)},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				schedule, err := ParseCron(tt.expr)
+				require.NoError(t, err)
+
+				// Guard against searching forever
+				next := make(chan time.Time, 1)
+				go func() { next <- schedule.Next(tt.from) }()
+				select {
+				case got := <-next:
+					assert.Equal(t, tt.next, got.UTC())
+				case <-time.After(time.Second):
+					t.Fatalf("%s from %s never returned", tt.expr, tt.from)
+				}
+			})
+		}
+	})
+
+	t.Run("NeverMatches", func(t *testing.T) {
+		schedule, err := ParseCron("0 0 30 feb *")
+		require.NoError(t, err)
+		assert.True(t, schedule.Next(from).IsZero())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, expr := range []string{
+			"",
+			"* * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"* * * 13 *",
+			"*/0 * * * *",
+			"5-1 * * * *",
+			"@fortnightly",
+			"@every 10ms",
+			"@every soon",
+		} {
+			_, err := ParseCron(expr)
+			assert.Error(t, err, "expression %q", expr)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package jobscheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DeadLetter is a job that ended failed or timed out after exhausting its
+// retries, kept so that it can be inspected and replayed
+type DeadLetter struct {
+	Job            JobPayload   `json:"job"`      // The job as originally submitted
+	Attempts       []JobAttempt `json:"attempts"` // Every run of the job, oldest first
+	Status         JobStatus    `json:"status"`   // Status of the final attempt
+	Error          string       `json:"error,omitempty"`
+	ExitCode       int          `json:"exit_code"`
+	FirstAttemptAt time.Time    `json:"first_attempt_at"`
+	DeadLetteredAt time.Time    `json:"dead_lettered_at"`
+}
+
+// submission returns the job with its runtime state cleared, as it was
+// when it was submitted
+func (j JobPayload) submission() JobPayload {
+	j.Status = JobStatusPending
+	j.Error = ""
+	j.StartTime = time.Time{}
+	j.EndTime = time.Time{}
+	j.RetryCount = 0
+	j.Progress = nil
+	return j
+}
+
+// deadLetter builds the dead-letter view of an entry
+func (e *jobEntry) deadLetter() DeadLetter {
+	dl := DeadLetter{
+		Job:            e.job.submission(),
+		Attempts:       append([]JobAttempt(nil), e.attempts...),
+		Status:         e.job.Status,
+		Error:          e.job.Error,
+		DeadLetteredAt: e.deadLetteredAt,
+	}
+	if e.result != nil {
+		dl.ExitCode = e.result.ExitCode
+	}
+	if len(e.attempts) > 0 {
+		dl.FirstAttemptAt = e.attempts[0].StartTime
+	}
+	return dl
+}
+
+// deadLetters returns dead-lettered jobs in submission order, optionally
+// filtered by channel
+func (r *jobRegistry) deadLetters(channel string) []DeadLetter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var letters []DeadLetter
+	for _, id := range r.order {
+		entry := r.jobs[id]
+		if entry.deadLetteredAt.IsZero() {
+			continue
+		}
+		if channel != "" && entry.job.Channel != channel {
+			continue
+		}
+		letters = append(letters, entry.deadLetter())
+	}
+	return letters
+}
+
+// deadLetter returns a single dead-lettered job
+func (r *jobRegistry) deadLetter(id string) (DeadLetter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.jobs[id]
+	if !exists {
+		return DeadLetter{}, fmt.Errorf("job %s: %w", id, ErrJobNotFound)
+	}
+	if entry.deadLetteredAt.IsZero() {
+		return DeadLetter{}, fmt.Errorf("job %s: %w", id, ErrJobNotDeadLettered)
+	}
+	return entry.deadLetter(), nil
+}
+
+// requeueDeadLetter takes a job off the dead-letter list and resets it to
+// pending with a fresh retry budget. Earlier attempts are kept.
+func (r *jobRegistry) requeueDeadLetter(id string) (JobPayload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[id]
+	if !exists {
+		return JobPayload{}, fmt.Errorf("job %s: %w", id, ErrJobNotFound)
+	}
+	if entry.deadLetteredAt.IsZero() {
+		return JobPayload{}, fmt.Errorf("job %s: %w", id, ErrJobNotDeadLettered)
+	}
+
+	entry.job = entry.job.submission()
+	entry.result = nil
+	entry.deadLetteredAt = time.Time{}
+	r.persist(entry)
+	return entry.job, nil
+}
+
+// purgeDeadLetter forgets a dead-lettered job entirely
+func (r *jobRegistry) purgeDeadLetter(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[id]
+	if !exists {
+		return fmt.Errorf("job %s: %w", id, ErrJobNotFound)
+	}
+	if entry.deadLetteredAt.IsZero() {
+		return fmt.Errorf("job %s: %w", id, ErrJobNotDeadLettered)
+	}
+	r.removeLocked(id)
+	return nil
+}
+
+// ListDeadLetters returns every dead-lettered job. An empty channel matches
+// every channel.
+func (s *Scheduler) ListDeadLetters(channel string) []DeadLetter {
+	return s.registry.deadLetters(channel)
+}
+
+// GetDeadLetter returns a dead-lettered job with its full attempt history
+func (s *Scheduler) GetDeadLetter(jobID string) (*DeadLetter, error) {
+	dl, err := s.registry.deadLetter(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return &dl, nil
+}
+
+// RequeueDeadLetter submits a dead-lettered job again under its original
+// ID, with its retry count reset
+func (s *Scheduler) RequeueDeadLetter(jobID string) error {
+	return s.RequeueDeadLetterAs(jobID, "")
+}
+
+// RequeueDeadLetterAs requeues a job like RequeueDeadLetter, recording who
+// requeued it in the job's history
+func (s *Scheduler) RequeueDeadLetterAs(jobID, actor string) error {
+	job, err := s.registry.requeueDeadLetter(jobID)
+	if err != nil {
+		return err
+	}
+	requeued := newJobEvent(EventRequeued, job)
+	requeued.Actor = actor
+	s.recordEvent(requeued)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueueWaiting(job)
+	return nil
+}
+
+// RequeueDeadLetters requeues every dead-lettered job in a channel, or in
+// all channels if channel is empty, and returns the IDs it requeued
+func (s *Scheduler) RequeueDeadLetters(channel string) ([]string, error) {
+	return s.RequeueDeadLettersAs(channel, "")
+}
+
+// RequeueDeadLettersAs requeues jobs like RequeueDeadLetters, recording who
+// requeued them in each job's history
+func (s *Scheduler) RequeueDeadLettersAs(channel, actor string) ([]string, error) {
+	var requeued []string
+	for _, dl := range s.registry.deadLetters(channel) {
+		if err := s.RequeueDeadLetterAs(dl.Job.ID, actor); err != nil {
+			if errors.Is(err, ErrJobNotDeadLettered) {
+				continue // Requeued or purged concurrently
+			}
+			return requeued, err
+		}
+		requeued = append(requeued, dl.Job.ID)
+	}
+	return requeued, nil
+}
+
+// PurgeDeadLetter permanently removes a dead-lettered job, along with any
+// artifacts, output and log it kept
+func (s *Scheduler) PurgeDeadLetter(jobID string) error {
+	if err := s.registry.purgeDeadLetter(jobID); err != nil {
+		return err
+	}
+	s.removeArtifacts(jobID)
+	s.removeOutput(jobID)
+	s.logs.remove(jobID)
+	return nil
+}
+
+// PurgeDeadLetters permanently removes every dead-lettered job in a
+// channel, or in all channels if channel is empty, and returns how many
+// were removed
+func (s *Scheduler) PurgeDeadLetters(channel string) (int, error) {
+	purged := 0
+	for _, dl := range s.registry.deadLetters(channel) {
+		if err := s.PurgeDeadLetter(dl.Job.ID); err != nil {
+			if errors.Is(err, ErrJobNotDeadLettered) || errors.Is(err, ErrJobNotFound) {
+				continue // Requeued or purged concurrently
+			}
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,157 @@
+package jobscheduler
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// delayQueue holds jobs that are waiting for a point in time, either a
+// scheduled run time or the end of a retry backoff. A single goroutine
+// sleeps until the earliest one is due and hands it back to the scheduler.
+type delayQueue struct {
+	mu     sync.Mutex
+	items  delayHeap
+	index  map[string]*delayItem
+	notify chan struct{} // Signalled when the earliest due time may have changed
+}
+
+// delayItem is a job waiting in a delayQueue
+type delayItem struct {
+	jobID string
+	due   time.Time
+	index int // Position in the heap
+}
+
+// newDelayQueue creates an empty delay queue
+func newDelayQueue() *delayQueue {
+	return &delayQueue{
+		index:  make(map[string]*delayItem),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// add schedules a job to become due at the given time, replacing any
+// earlier entry for the same job
+func (d *delayQueue) add(jobID string, due time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if item, exists := d.index[jobID]; exists {
+		item.due = due
+		heap.Fix(&d.items, item.index)
+	} else {
+		item := &delayItem{jobID: jobID, due: due}
+		heap.Push(&d.items, item)
+		d.index[jobID] = item
+	}
+
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// remove drops a waiting job, reporting whether it was found
+func (d *delayQueue) remove(jobID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item, exists := d.index[jobID]
+	if !exists {
+		return false
+	}
+	heap.Remove(&d.items, item.index)
+	delete(d.index, jobID)
+	return true
+}
+
+// len returns the number of waiting jobs
+func (d *delayQueue) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.items)
+}
+
+// popDue removes and returns every job due at or before now, and the time
+// the next remaining job is due
+func (d *delayQueue) popDue(now time.Time) ([]string, time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var due []string
+	for len(d.items) > 0 && !d.items[0].due.After(now) {
+		item := heap.Pop(&d.items).(*delayItem)
+		delete(d.index, item.jobID)
+		due = append(due, item.jobID)
+	}
+
+	var next time.Time
+	if len(d.items) > 0 {
+		next = d.items[0].due
+	}
+	return due, next
+}
+
+// run calls release for each job as it becomes due, until ctx is done
+func (d *delayQueue) run(ctx context.Context, release func(jobID string)) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		due, next := d.popDue(time.Now())
+		for _, jobID := range due {
+			release(jobID)
+		}
+
+		// Sleep until the next job is due, or until a new job arrives
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.notify:
+		case <-timer.C:
+		}
+	}
+}
+
+// delayHeap implements heap.Interface ordered by due time
+type delayHeap []*delayItem
+
+func (h delayHeap) Len() int { return len(h) }
+
+func (h delayHeap) Less(i, j int) bool { return h[i].due.Before(h[j].due) }
+
+func (h delayHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *delayHeap) Push(x interface{}) {
+	item := x.(*delayItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *delayHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,82 @@
+package jobscheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledJobs(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	echoJob := func(id string) JobPayload {
+		return JobPayload{
+			ID:      id,
+			Channel: "scheduled-channel",
+			Application: &ApplicationConfig{
+				Name: "echo",
+				Path: "echo",
+				Args: []string{"on time"},
+			},
+		}
+	}
+
+	t.Run("Delay", func(t *testing.T) {
+		job := echoJob("delayed")
+		job.Delay = 200 * time.Millisecond
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		status, err := scheduler.GetJobStatus("delayed")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusScheduled, status.Status)
+
+		jobs, err := scheduler.ListJobs("", string(JobStatusScheduled))
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "delayed", jobs[0].ID)
+
+		waitForStatus(t, scheduler, "delayed", JobStatusComplete)
+		result, err := scheduler.GetJobResult("delayed")
+		require.NoError(t, err)
+		assert.False(t, result.StartTime.Before(status.RunAt), "job started before its run time")
+	})
+
+	t.Run("RunAt", func(t *testing.T) {
+		job := echoJob("run-at")
+		job.RunAt = time.Now().Add(100 * time.Millisecond)
+		require.NoError(t, scheduler.SubmitJob(job))
+		waitForStatus(t, scheduler, "run-at", JobStatusComplete)
+
+		// A run time in the past runs immediately
+		job = echoJob("run-at-past")
+		job.RunAt = time.Now().Add(-time.Hour)
+		require.NoError(t, scheduler.SubmitJob(job))
+		waitForStatus(t, scheduler, "run-at-past", JobStatusComplete)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		job := echoJob("cancelled-schedule")
+		job.Delay = 100 * time.Millisecond
+		require.NoError(t, scheduler.SubmitJob(job))
+		require.NoError(t, scheduler.CancelJob("cancelled-schedule"))
+
+		time.Sleep(200 * time.Millisecond)
+		status, err := scheduler.GetJobStatus("cancelled-schedule")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCancelled, status.Status)
+		assert.Zero(t, scheduler.delays.len())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		job := echoJob("both")
+		job.Delay = time.Second
+		job.RunAt = time.Now().Add(time.Second)
+		assert.Error(t, scheduler.SubmitJob(job))
+
+		job = echoJob("negative")
+		job.Delay = -time.Second
+		assert.Error(t, scheduler.SubmitJob(job))
+	})
+}
@@ -0,0 +1,408 @@
+package jobscheduler
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogFormat is how events are written to the processing log
+type LogFormat string
+
+const (
+	// LogFormatJSON writes each event as a JSON object on a line of its
+	// own. It is the default.
+	LogFormatJSON LogFormat = "json"
+
+	// LogFormatText writes each event as a line of text
+	LogFormatText LogFormat = "text"
+)
+
+// Valid reports whether the format is known; empty means the default
+func (f LogFormat) Valid() bool {
+	switch f {
+	case "", LogFormatJSON, LogFormatText:
+		return true
+	}
+	return false
+}
+
+// LogLevel is how severe an event is
+type LogLevel string
+
+const (
+	LogLevelDebug LogLevel = "debug"
+	LogLevelInfo  LogLevel = "info"
+	LogLevelWarn  LogLevel = "warn"
+	LogLevelError LogLevel = "error"
+)
+
+// severity orders levels from least to most severe; empty means info
+func (l LogLevel) severity() int {
+	switch l {
+	case LogLevelDebug:
+		return 0
+	case "", LogLevelInfo:
+		return 1
+	case LogLevelWarn:
+		return 2
+	case LogLevelError:
+		return 3
+	}
+	return -1
+}
+
+// Valid reports whether the level is known; empty means info
+func (l LogLevel) Valid() bool {
+	return l.severity() >= 0
+}
+
+// EventType names something that happened to a job
+type EventType string
+
+const (
+	EventSubmitted EventType = "submitted"
+	EventQueued    EventType = "queued" // Waiting for a worker
+	EventStarted   EventType = "started"
+	EventSkipped   EventType = "skipped"   // Cancelled before it started
+	EventSurvivors EventType = "survivors" // Processes outlived the attempt
+	EventRetrying  EventType = "retrying"
+	EventCancelled EventType = "cancelled" // Asked to stop, which a running job does once its process has exited
+	EventRequeued  EventType = "requeued"  // Taken off the dead-letter list to run again
+	EventFinished  EventType = "finished"
+)
+
+// JobEvent is something that happened to a job. Every event is kept in
+// the job's history and written to the processing log.
+type JobEvent struct {
+	Time     time.Time `json:"time"`
+	Level    LogLevel  `json:"level"`
+	Event    EventType `json:"event"`
+	JobID    string    `json:"job_id"`
+	Channel  string    `json:"channel"`
+	Attempt  int       `json:"attempt"` // Counting from 1
+	Status   JobStatus `json:"status"`
+	Actor    string    `json:"actor,omitempty"`     // Who submitted, cancelled or requeued the job, if known
+	Duration float64   `json:"duration,omitempty"`  // Seconds the attempt ran for
+	ExitCode *int      `json:"exit_code,omitempty"` // Set once an attempt has ended
+	Error    string    `json:"error,omitempty"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// newJobEvent describes an event in a job's current state
+func newJobEvent(event EventType, job JobPayload) JobEvent {
+	e := JobEvent{
+		Time:    time.Now(),
+		Level:   LogLevelInfo,
+		Event:   event,
+		JobID:   job.ID,
+		Channel: job.Channel,
+		Attempt: job.RetryCount + 1,
+		Status:  job.Status,
+		Error:   job.Error,
+	}
+	switch {
+	case event == EventSurvivors || event == EventRetrying:
+		e.Level = LogLevelWarn
+	case event == EventFinished && job.Status != JobStatusComplete && job.Status != JobStatusCancelled:
+		e.Level = LogLevelError
+	}
+	return e
+}
+
+// ProcessingLogConfig controls how the processing log is written and when
+// it is rotated. Zero values write every event as JSON to a single file.
+type ProcessingLogConfig struct {
+	// Format of each event (JSON by default)
+	Format LogFormat
+
+	// Least severe events that are written (info by default)
+	Level LogLevel
+
+	// Size in bytes the log is rotated before outgrowing (never when zero)
+	MaxSize int64
+
+	// Age the log is rotated at, counted from when it was opened, and
+	// beyond which rotated logs are removed (never when zero)
+	MaxAge time.Duration
+
+	// Number of rotated logs kept (all of them when zero)
+	MaxBackups int
+
+	// Gzip logs once they are rotated
+	Compress bool
+}
+
+// Validate checks the processing log settings
+func (c ProcessingLogConfig) Validate() error {
+	if !c.Format.Valid() {
+		return fmt.Errorf("unknown log format: %s", c.Format)
+	}
+	if !c.Level.Valid() {
+		return fmt.Errorf("unknown log level: %s", c.Level)
+	}
+	if c.MaxSize < 0 {
+		return fmt.Errorf("max size cannot be negative")
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("max age cannot be negative")
+	}
+	if c.MaxBackups < 0 {
+		return fmt.Errorf("max backups cannot be negative")
+	}
+	return nil
+}
+
+// backupTimeFormat stamps the names of rotated logs, sorting by age
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// eventLog writes events to the processing log, rotating it as configured
+type eventLog struct {
+	mu     sync.Mutex
+	config ProcessingLogConfig
+	path   string
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	tidyMu  sync.Mutex     // Compressing and pruning rotated logs
+	tidying sync.WaitGroup // Rotated logs still being compressed or pruned
+}
+
+// openEventLog opens the processing log at path, appending to it
+func openEventLog(path string, cfg ProcessingLogConfig) (*eventLog, error) {
+	l := &eventLog{config: cfg, path: path}
+	if err := l.open(time.Now()); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the log file itself
+func (l *eventLog) open(now time.Time) error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.size, l.opened = file, info.Size(), now
+	return nil
+}
+
+// write writes an event if it is severe enough. Failing to write or
+// rotate the log does not hold up jobs, so failures are only reported.
+func (l *eventLog) write(event JobEvent) {
+	if event.Level.severity() < l.config.Level.severity() {
+		return
+	}
+	line := l.format(event)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	if l.due(event.Time, len(line)) {
+		if err := l.rotate(event.Time); err != nil {
+			log.Printf("Failed to rotate process log: %v", err)
+			if l.file == nil {
+				return
+			}
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		log.Printf("Failed to write process log: %v", err)
+	}
+}
+
+// format renders an event as a line of the log
+func (l *eventLog) format(event JobEvent) []byte {
+	if l.config.Format != LogFormatText {
+		line, _ := json.Marshal(event)
+		return append(line, '\n')
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - %s - %s - Channel: %s, JobID: %s, Attempt: %d, Status: %s",
+		event.Time.Format("2006-01-02 15:04:05.000"), strings.ToUpper(string(event.Level)), event.Event,
+		event.Channel, event.JobID, event.Attempt, event.Status)
+	if event.Actor != "" {
+		fmt.Fprintf(&b, ", Actor: %s", event.Actor)
+	}
+	if event.ExitCode != nil {
+		fmt.Fprintf(&b, ", Duration: %.3fs, ExitCode: %d", event.Duration, *event.ExitCode)
+	}
+	if event.Error != "" {
+		fmt.Fprintf(&b, ", Error: %q", event.Error)
+	}
+	if event.Message != "" {
+		fmt.Fprintf(&b, " - %s", event.Message)
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// due reports whether the log should be rotated before writing n more
+// bytes to it. An empty log is never rotated.
+func (l *eventLog) due(now time.Time, n int) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.config.MaxSize > 0 && l.size+int64(n) > l.config.MaxSize {
+		return true
+	}
+	return l.config.MaxAge > 0 && now.Sub(l.opened) >= l.config.MaxAge
+}
+
+// rotate moves the log aside and starts a new one, then compresses and
+// prunes rotated logs in the background
+func (l *eventLog) rotate(now time.Time) error {
+	if err := l.file.Close(); err != nil {
+		log.Printf("Failed to close process log: %v", err)
+	}
+	l.file = nil
+
+	// Rotations within a millisecond of each other still get names of
+	// their own
+	stamp := now
+	backup := l.backupName(stamp)
+	for fileExists(backup) || fileExists(backup+".gz") {
+		stamp = stamp.Add(time.Millisecond)
+		backup = l.backupName(stamp)
+	}
+	renameErr := os.Rename(l.path, backup)
+	if err := l.open(now); err != nil {
+		return fmt.Errorf("failed to reopen process log: %v", err)
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	l.tidying.Add(1)
+	go l.tidy(backup, now)
+	return nil
+}
+
+// backupName returns the name the log is rotated to at a time
+func (l *eventLog) backupName(t time.Time) string {
+	ext := filepath.Ext(l.path)
+	return strings.TrimSuffix(l.path, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// fileExists reports whether there is a file at path
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// tidy compresses a newly rotated log, then removes the rotated logs that
+// are too many or too old
+func (l *eventLog) tidy(rotated string, now time.Time) {
+	defer l.tidying.Done()
+	l.tidyMu.Lock()
+	defer l.tidyMu.Unlock()
+
+	if l.config.Compress {
+		if err := compressFile(rotated); err != nil {
+			log.Printf("Failed to compress process log: %v", err)
+		}
+	}
+
+	backups := l.backups()
+	for i, backup := range backups {
+		tooMany := l.config.MaxBackups > 0 && i >= l.config.MaxBackups
+		tooOld := l.config.MaxAge > 0 && now.Sub(backup.rotated) > l.config.MaxAge
+		if tooMany || tooOld {
+			os.Remove(backup.path)
+		}
+	}
+}
+
+// logBackup is a rotated log
+type logBackup struct {
+	path    string
+	rotated time.Time
+}
+
+// backups returns the rotated logs, newest first
+func (l *eventLog) backups() []logBackup {
+	dir := filepath.Dir(l.path)
+	ext := filepath.Ext(l.path)
+	prefix := strings.TrimSuffix(filepath.Base(l.path), ext) + "-"
+
+	entries, _ := os.ReadDir(dir)
+	var backups []logBackup
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(strings.TrimSuffix(stamp, ".gz"), ext)
+		if !ok {
+			continue
+		}
+		rotated, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{path: filepath.Join(dir, entry.Name()), rotated: rotated})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotated.After(backups[j].rotated)
+	})
+	return backups
+}
+
+// compressFile gzips a file alongside itself, then removes it
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close closes the log once rotated logs have been tidied up
+func (l *eventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tidying.Wait()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
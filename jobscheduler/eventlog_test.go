@@ -0,0 +1,208 @@
+package jobscheduler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessingLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+	cfg.WorkDir = tmpDir
+
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	defer scheduler.Shutdown()
+
+	// finished waits for the event that a job has finished, returning all
+	// of the job's events
+	finished := func(t *testing.T, jobID string) []JobEvent {
+		t.Helper()
+		var events []JobEvent
+		require.Eventually(t, func() bool {
+			events = nil
+			for _, event := range readEvents(t, cfg.ProcessingLogPath) {
+				if event.JobID == jobID {
+					events = append(events, event)
+				}
+			}
+			return len(events) > 0 && events[len(events)-1].Event == EventFinished
+		}, 5*time.Second, 10*time.Millisecond)
+		return events
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "logged",
+			Channel:     "log-channel",
+			Application: &ApplicationConfig{Name: "sleep", Path: "sleep", Args: []string{"0.1"}},
+		}))
+		events := finished(t, "logged")
+		require.Len(t, events, 4)
+
+		started, done := events[2], events[3]
+		assert.Equal(t, EventStarted, started.Event)
+		assert.Equal(t, LogLevelInfo, started.Level)
+		assert.Equal(t, "log-channel", started.Channel)
+		assert.Equal(t, 1, started.Attempt)
+		assert.Equal(t, JobStatusRunning, started.Status)
+		assert.Nil(t, started.ExitCode)
+
+		assert.Equal(t, LogLevelInfo, done.Level)
+		assert.Equal(t, JobStatusComplete, done.Status)
+		require.NotNil(t, done.ExitCode)
+		assert.Equal(t, 0, *done.ExitCode)
+		assert.GreaterOrEqual(t, done.Duration, 0.1)
+		assert.Empty(t, done.Error)
+	})
+
+	t.Run("Retried", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "logged-failure",
+			Channel:     "log-channel",
+			Application: &ApplicationConfig{Name: "sh", Path: "sh", Args: []string{"-c", "exit 3"}},
+			RetryPolicy: &RetryPolicy{MaxRetries: 1, InitialDelay: 10 * time.Millisecond},
+		}))
+		events := finished(t, "logged-failure")
+
+		var kinds []EventType
+		for _, event := range events {
+			kinds = append(kinds, event.Event)
+		}
+		assert.Equal(t, []EventType{EventSubmitted, EventQueued, EventStarted, EventRetrying, EventQueued, EventStarted, EventFinished}, kinds)
+
+		retrying, requeued, done := events[3], events[4], events[6]
+		assert.Equal(t, LogLevelWarn, retrying.Level)
+		assert.Equal(t, 1, retrying.Attempt)
+		assert.Equal(t, 2, requeued.Attempt)
+		assert.Equal(t, 2, done.Attempt)
+		assert.Equal(t, LogLevelError, done.Level)
+		assert.Equal(t, JobStatusFailed, done.Status)
+		require.NotNil(t, done.ExitCode)
+		assert.Equal(t, 3, *done.ExitCode)
+		assert.NotEmpty(t, done.Error)
+	})
+}
+
+func TestEventLog(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := func(jobID string, at time.Duration) JobEvent {
+		return JobEvent{Time: start.Add(at), Level: LogLevelInfo, Event: EventStarted, JobID: jobID, Channel: "c", Attempt: 1, Status: JobStatusRunning}
+	}
+
+	t.Run("RotatesBySize", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "processing.log")
+		eventLog, err := openEventLog(path, ProcessingLogConfig{MaxSize: 300, MaxBackups: 2, Compress: true})
+		require.NoError(t, err)
+		for i := 0; i < 20; i++ {
+			eventLog.write(event(strings.Repeat("x", 100), time.Duration(i)*time.Second))
+		}
+		require.NoError(t, eventLog.Close())
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(300))
+
+		// Only the newest rotated logs are kept, compressed
+		backups := eventLog.backups()
+		require.Len(t, backups, 2)
+		for _, backup := range backups {
+			assert.True(t, strings.HasSuffix(backup.path, ".gz"), backup.path)
+			assert.NotEmpty(t, readEvents(t, backup.path))
+		}
+		assert.True(t, backups[0].rotated.After(backups[1].rotated))
+	})
+
+	t.Run("RotatesByAge", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "processing.log")
+		eventLog, err := openEventLog(path, ProcessingLogConfig{MaxAge: time.Hour})
+		require.NoError(t, err)
+		eventLog.opened = start
+
+		eventLog.write(event("first", 0))
+		eventLog.write(event("second", 30*time.Minute))
+		eventLog.write(event("third", 90*time.Minute))
+		require.Len(t, eventLog.backups(), 1)
+
+		// Rotated logs expire as well
+		eventLog.write(event("fourth", 4*time.Hour))
+		require.NoError(t, eventLog.Close())
+		backups := eventLog.backups()
+		require.Len(t, backups, 1)
+		assert.Equal(t, "third", readEvents(t, backups[0].path)[0].JobID)
+		assert.Equal(t, "fourth", readEvents(t, path)[0].JobID)
+	})
+
+	t.Run("Level", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "processing.log")
+		eventLog, err := openEventLog(path, ProcessingLogConfig{Level: LogLevelWarn})
+		require.NoError(t, err)
+		eventLog.write(event("quiet", 0))
+		loud := event("loud", 0)
+		loud.Level = LogLevelError
+		eventLog.write(loud)
+		require.NoError(t, eventLog.Close())
+
+		events := readEvents(t, path)
+		require.Len(t, events, 1)
+		assert.Equal(t, "loud", events[0].JobID)
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "processing.log")
+		eventLog, err := openEventLog(path, ProcessingLogConfig{Format: LogFormatText})
+		require.NoError(t, err)
+		exitCode := 1
+		failed := event("text-job", 0)
+		failed.Event, failed.Level, failed.Status = EventFinished, LogLevelError, JobStatusFailed
+		failed.ExitCode, failed.Duration, failed.Error = &exitCode, 1.5, "exit status 1"
+		eventLog.write(failed)
+		require.NoError(t, eventLog.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "ERROR - finished - Channel: c, JobID: text-job, Attempt: 1, Status: failed, Duration: 1.500s, ExitCode: 1, Error: \"exit status 1\"\n")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Error(t, ProcessingLogConfig{Format: "xml"}.Validate())
+		assert.Error(t, ProcessingLogConfig{Level: "loud"}.Validate())
+		assert.Error(t, ProcessingLogConfig{MaxSize: -1}.Validate())
+	})
+}
+
+// readEvents decodes the events in a processing log, gzipped or not
+func readEvents(t *testing.T, path string) []JobEvent {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var scanner *bufio.Scanner
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		scanner = bufio.NewScanner(gz)
+	} else {
+		scanner = bufio.NewScanner(file)
+	}
+
+	var events []JobEvent
+	for scanner.Scan() {
+		var event JobEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event), scanner.Text())
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+	return events
+}
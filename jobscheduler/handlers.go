@@ -0,0 +1,92 @@
+package jobscheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoHandler is returned for a job without an Application when no handler
+// is registered for its type or channel
+var ErrNoHandler = errors.New("no handler registered")
+
+// HandlerFunc processes a job in-process. It should return once ctx is done,
+// which happens when the job times out, is cancelled or the scheduler shuts
+// down. The returned result's exit code and output are recorded with the
+// job; a non-nil error fails the attempt, which may then be retried.
+type HandlerFunc func(ctx context.Context, job JobPayload) (JobResult, error)
+
+// handlerRegistry maps job types and channel names to handlers
+type handlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+}
+
+func newHandlerRegistry() *handlerRegistry {
+	return &handlerRegistry{handlers: make(map[string]HandlerFunc)}
+}
+
+// lookup returns the handler for a job's type, falling back to its channel
+func (h *handlerRegistry) lookup(job JobPayload) (HandlerFunc, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if job.Type != "" {
+		handler, exists := h.handlers[job.Type]
+		return handler, exists
+	}
+	handler, exists := h.handlers[job.Channel]
+	return handler, exists
+}
+
+// RegisterHandler registers the handler for jobs without an Application
+// whose Type, or whose channel if they have no Type, matches key.
+// Registering a key again replaces its handler.
+func (s *Scheduler) RegisterHandler(key string, handler HandlerFunc) error {
+	if key == "" {
+		return fmt.Errorf("handler key cannot be empty")
+	}
+	if handler == nil {
+		return fmt.Errorf("handler for %s cannot be nil", key)
+	}
+
+	s.handlers.mu.Lock()
+	defer s.handlers.mu.Unlock()
+	s.handlers.handlers[key] = handler
+	return nil
+}
+
+// UnregisterHandler removes the handler registered for key
+func (s *Scheduler) UnregisterHandler(key string) {
+	s.handlers.mu.Lock()
+	defer s.handlers.mu.Unlock()
+	delete(s.handlers.handlers, key)
+}
+
+// runHandler calls a handler, returning early with ctx's error if the
+// handler does not stop when ctx is done, and turning a panic into an error
+func runHandler(ctx context.Context, handler HandlerFunc, job JobPayload) (JobResult, error) {
+	type outcome struct {
+		result JobResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("handler panicked: %v", r)}
+			}
+		}()
+		result, err := handler(ctx, job)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+		return JobResult{}, ctx.Err()
+	}
+}
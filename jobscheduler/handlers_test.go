@@ -0,0 +1,119 @@
+package jobscheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	t.Run("ByType", func(t *testing.T) {
+		require.NoError(t, scheduler.RegisterHandler("greet", func(ctx context.Context, job JobPayload) (JobResult, error) {
+			var body struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(job.Body, &body); err != nil {
+				return JobResult{}, err
+			}
+			return JobResult{Output: "hello " + body.Name}, nil
+		}))
+
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:      "greet-job",
+			Channel: "handler-channel",
+			Type:    "greet",
+			Body:    json.RawMessage(`{"name": "world"}`),
+		}))
+		waitForStatus(t, scheduler, "greet-job", JobStatusComplete)
+
+		result, err := scheduler.GetJobResult("greet-job")
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", result.Output)
+	})
+
+	t.Run("ByChannel", func(t *testing.T) {
+		require.NoError(t, scheduler.RegisterHandler("channel-handled", func(ctx context.Context, job JobPayload) (JobResult, error) {
+			return JobResult{Output: job.ID}, nil
+		}))
+
+		require.NoError(t, scheduler.SubmitJob(JobPayload{ID: "channel-job", Channel: "channel-handled"}))
+		waitForStatus(t, scheduler, "channel-job", JobStatusComplete)
+	})
+
+	t.Run("NoHandler", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{ID: "unhandled-job", Channel: "handler-channel", Type: "unknown"}))
+		waitForStatus(t, scheduler, "unhandled-job", JobStatusFailed)
+
+		job, err := scheduler.GetJobStatus("unhandled-job")
+		require.NoError(t, err)
+		assert.Contains(t, job.Error, "unknown")
+		assert.Contains(t, job.Error, ErrNoHandler.Error())
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		var calls atomic.Int32
+		require.NoError(t, scheduler.RegisterHandler("flaky", func(ctx context.Context, job JobPayload) (JobResult, error) {
+			if calls.Add(1) < 3 {
+				return JobResult{ExitCode: 3}, fmt.Errorf("not yet")
+			}
+			return JobResult{}, nil
+		}))
+
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "flaky-handler-job",
+			Channel:     "handler-channel",
+			Type:        "flaky",
+			RetryPolicy: &RetryPolicy{MaxRetries: 3, InitialDelay: 10 * time.Millisecond},
+		}))
+		waitForStatus(t, scheduler, "flaky-handler-job", JobStatusComplete)
+
+		job, err := scheduler.GetJobStatus("flaky-handler-job")
+		require.NoError(t, err)
+		assert.Equal(t, 2, job.RetryCount)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		require.NoError(t, scheduler.RegisterHandler("stuck", func(ctx context.Context, job JobPayload) (JobResult, error) {
+			<-release // Ignores ctx
+			return JobResult{}, nil
+		}))
+
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:      "stuck-job",
+			Channel: "handler-timeout-channel",
+			Timeout: 100 * time.Millisecond,
+			Type:    "stuck",
+		}))
+		waitForStatus(t, scheduler, "stuck-job", JobStatusTimedOut)
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		require.NoError(t, scheduler.RegisterHandler("panics", func(ctx context.Context, job JobPayload) (JobResult, error) {
+			panic("boom")
+		}))
+
+		require.NoError(t, scheduler.SubmitJob(JobPayload{ID: "panic-job", Channel: "handler-channel", Type: "panics"}))
+		waitForStatus(t, scheduler, "panic-job", JobStatusFailed)
+
+		job, err := scheduler.GetJobStatus("panic-job")
+		require.NoError(t, err)
+		assert.Contains(t, job.Error, "boom")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Error(t, scheduler.RegisterHandler("", func(ctx context.Context, job JobPayload) (JobResult, error) {
+			return JobResult{}, nil
+		}))
+		assert.Error(t, scheduler.RegisterHandler("nil", nil))
+	})
+}
@@ -0,0 +1,114 @@
+package jobscheduler
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// recordEvent appends an event to its job's history, counts it in its
+// channel's statistics, writes it to the processing log and passes it to
+// subscribers
+func (s *Scheduler) recordEvent(event JobEvent) {
+	s.registry.addEvent(event)
+	s.stats.record(event)
+	s.processLog.write(event)
+	s.subscribers.publish(event)
+}
+
+// subscriberList holds the functions events are passed to
+type subscriberList struct {
+	mu     sync.RWMutex
+	fns    map[int]func(JobEvent)
+	nextID int
+}
+
+// add subscribes fn, returning a function that unsubscribes it
+func (l *subscriberList) add(fn func(JobEvent)) func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fns == nil {
+		l.fns = make(map[int]func(JobEvent))
+	}
+	id := l.nextID
+	l.nextID++
+	l.fns[id] = fn
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			delete(l.fns, id)
+		})
+	}
+}
+
+// publish passes an event to every subscriber
+func (l *subscriberList) publish(event JobEvent) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, fn := range l.fns {
+		fn(event)
+	}
+}
+
+// Subscribe passes every event recorded from now on to fn, until the
+// returned function is called. Events are passed as they are recorded, in
+// the order they happened to each job, which makes subscribing suited to
+// keeping counts or metrics. fn may be called from several goroutines at
+// once and while the scheduler holds locks, so it must be quick and must
+// not call back into the scheduler.
+func (s *Scheduler) Subscribe(fn func(event JobEvent)) (unsubscribe func()) {
+	return s.subscribers.add(fn)
+}
+
+// addEvent appends an event to the history of its job, if the job is
+// still tracked
+func (r *jobRegistry) addEvent(event JobEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[event.JobID]
+	if !exists {
+		return
+	}
+	entry.events = append(entry.events, event)
+	if store, ok := r.store.(EventStore); ok {
+		if err := store.AppendEvent(event); err != nil {
+			log.Printf("Failed to persist event for job %s: %v", event.JobID, err)
+		}
+		return
+	}
+	r.persist(entry)
+}
+
+// events returns a copy of a job's history
+func (r *jobRegistry) events(id string) ([]JobEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("job %s: %w", id, ErrJobNotFound)
+	}
+	return append([]JobEvent(nil), entry.events...), nil
+}
+
+// JobEvents returns everything that has happened to a job, oldest first:
+// who submitted it, each time it was queued, started and retried, who
+// cancelled it, and how it finished. The history is kept for as long as
+// the job is, including across restarts with a persistent store.
+func (s *Scheduler) JobEvents(jobID string) ([]JobEvent, error) {
+	return s.registry.events(jobID)
+}
+
+// finishCancelled records the end of jobs that were cancelled without
+// running, and releases whatever was waiting on them
+func (s *Scheduler) finishCancelled(jobs []JobPayload) {
+	for _, job := range jobs {
+		s.recordEvent(newJobEvent(EventFinished, job))
+		s.jobFinished(job)
+	}
+}
@@ -0,0 +1,143 @@
+package jobscheduler
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+	cfg.WorkDir = tmpDir
+	cfg.StorePath = filepath.Join(tmpDir, "jobs.wal")
+
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+
+	kinds := func(events []JobEvent) []EventType {
+		var kinds []EventType
+		for _, event := range events {
+			kinds = append(kinds, event.Event)
+		}
+		return kinds
+	}
+
+	// finished waits for a job's history to end
+	finished := func(t *testing.T, jobID string) []JobEvent {
+		t.Helper()
+		var events []JobEvent
+		require.Eventually(t, func() bool {
+			var err error
+			events, err = scheduler.JobEvents(jobID)
+			return err == nil && len(events) > 0 && events[len(events)-1].Event == EventFinished
+		}, 5*time.Second, 10*time.Millisecond)
+		return events
+	}
+
+	t.Run("CancelledWhileRunning", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "audited",
+			Channel:     "history-channel",
+			Application: &ApplicationConfig{Name: "sleep", Path: "sleep", Args: []string{"10"}},
+			SubmittedBy: "alice",
+		}))
+		waitForStatus(t, scheduler, "audited", JobStatusRunning)
+		require.NoError(t, scheduler.CancelJobAs("audited", "bob"))
+
+		events := finished(t, "audited")
+		assert.Equal(t, []EventType{EventSubmitted, EventQueued, EventStarted, EventCancelled, EventFinished}, kinds(events))
+		assert.Equal(t, "alice", events[0].Actor)
+		assert.Equal(t, JobStatusPending, events[0].Status)
+		assert.Equal(t, "bob", events[3].Actor)
+		assert.Equal(t, JobStatusRunning, events[3].Status)
+		assert.Equal(t, JobStatusCancelled, events[4].Status)
+		assert.NotNil(t, events[4].ExitCode)
+		for i := 1; i < len(events); i++ {
+			assert.False(t, events[i].Time.Before(events[i-1].Time))
+		}
+	})
+
+	t.Run("CancelledWhileQueued", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:      "audited-delayed",
+			Channel: "history-channel",
+			Delay:   time.Hour,
+		}))
+		require.NoError(t, scheduler.CancelJobAs("audited-delayed", "carol"))
+
+		events, err := scheduler.JobEvents("audited-delayed")
+		require.NoError(t, err)
+		assert.Equal(t, []EventType{EventSubmitted, EventCancelled, EventFinished}, kinds(events))
+		assert.Equal(t, JobStatusScheduled, events[0].Status)
+		assert.Empty(t, events[0].Actor)
+		assert.Equal(t, "carol", events[1].Actor)
+		assert.Equal(t, JobStatusCancelled, events[2].Status)
+	})
+
+	t.Run("UnknownJob", func(t *testing.T) {
+		_, err := scheduler.JobEvents("missing")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+
+	t.Run("SurvivesRestart", func(t *testing.T) {
+		require.NoError(t, scheduler.Shutdown())
+		scheduler, err = NewScheduler(cfg)
+		require.NoError(t, err)
+		defer scheduler.Shutdown()
+
+		events, err := scheduler.JobEvents("audited")
+		require.NoError(t, err)
+		assert.Equal(t, []EventType{EventSubmitted, EventQueued, EventStarted, EventCancelled, EventFinished}, kinds(events))
+		assert.Equal(t, "bob", events[3].Actor)
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	var mu sync.Mutex
+	var events []JobEvent
+	unsubscribe := scheduler.Subscribe(func(event JobEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	received := func() []JobEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]JobEvent(nil), events...)
+	}
+
+	require.NoError(t, scheduler.SubmitJob(JobPayload{
+		ID:          "subscribed",
+		Channel:     "subscribe-channel",
+		Application: &ApplicationConfig{Name: "true", Path: "true"},
+	}))
+	require.Eventually(t, func() bool {
+		events := received()
+		return len(events) > 0 && events[len(events)-1].Event == EventFinished
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Subscribers see the same events as the job's history
+	history, err := scheduler.JobEvents("subscribed")
+	require.NoError(t, err)
+	assert.Equal(t, history, received())
+
+	// Nothing is passed on once unsubscribed
+	unsubscribe()
+	unsubscribe()
+	require.NoError(t, scheduler.SubmitJob(JobPayload{
+		ID:          "unsubscribed",
+		Channel:     "subscribe-channel",
+		Application: &ApplicationConfig{Name: "true", Path: "true"},
+	}))
+	waitForStatus(t, scheduler, "unsubscribed", JobStatusComplete)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, received(), len(history))
+}
@@ -2,26 +2,95 @@
 package executor
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	_ "strings"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
+// Termination describes how a process came to stop
+type Termination string
+
+const (
+	TerminationExited     Termination = "exited"     // Exited on its own
+	TerminationTerminated Termination = "terminated" // Exited after SIGTERM, within the grace period
+	TerminationKilled     Termination = "killed"     // Killed once the grace period ran out
+)
+
+// FailureReason names the resource limit that made a process fail
+type FailureReason string
+
+const (
+	FailureOOMKilled     FailureReason = "oom_killed"      // Killed for exceeding its cgroup's memory limit
+	FailureFileSizeLimit FailureReason = "file_size_limit" // Killed by SIGXFSZ for writing too large a file
+	FailureProcessLimit  FailureReason = "process_limit"   // Failed after its cgroup refused to start more processes
+)
+
+// Limits bounds the resources a process may use. Zero fields are unlimited.
+type Limits struct {
+	MemoryMax    int64   // Bytes of memory
+	CPUQuota     float64 // CPUs' worth of time, e.g. 0.5 for half of one
+	MaxProcesses int     // Processes and threads at once
+	MaxOpenFiles int     // Open file descriptors per process
+	MaxFileSize  int64   // Bytes in any one file written
+}
+
+// IsZero reports whether no limit is set
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// CleanupPolicy decides when an execution's scratch directory is removed
+type CleanupPolicy string
+
+const (
+	CleanupAlways    CleanupPolicy = "always"     // Remove it once the process exits (default)
+	CleanupOnSuccess CleanupPolicy = "on_success" // Keep it if the process failed
+	CleanupNever     CleanupPolicy = "never"      // Always keep it
+)
+
+// Artifact is a file collected from a process's working directory
+type Artifact struct {
+	Name string // Path relative to the working directory, with forward slashes
+	Size int64
+}
+
+// Sandbox isolates a process in Linux namespaces. It sees a read-only copy
+// of the filesystem with a writable scratch directory of its own, which is
+// removed afterwards, and its own PIDs, and it has no network unless
+// Network is set.
+type Sandbox struct {
+	Network bool // Share the host's network
+}
+
 // ExecutionResult contains the output and status of an executed command
 type ExecutionResult struct {
 	ExitCode    int
-	Stdout      string
+	Stdout      string // Head and tail of stdout, if it outgrew Config.OutputLimit
 	Stderr      string
 	StartTime   time.Time
 	EndTime     time.Time
 	ExecutionID string
+	Termination Termination
+	Survivors   []int      // Processes of the job still alive after it was cleaned up
+	Artifacts   []Artifact // Files collected into Config.ArtifactDir
+	WorkDir     string     // Scratch directory, if it was kept
+
+	// Bytes omitted from the middle of Stdout and Stderr, which are in
+	// Config.StdoutFile and Config.StderrFile
+	StdoutOmitted int64
+	StderrOmitted int64
+
+	// Resource limit that caused the process to fail, if any
+	FailureReason FailureReason
 }
 
 // Config contains the configuration for executing an application
@@ -30,33 +99,73 @@ type Config struct {
 	Path string
 	Args []string
 
-	// Execution environment
+	// Execution environment. Each execution gets a scratch directory of its
+	// own, in which it starts; a relative WorkingDir is a subdirectory of it.
 	WorkingDir string
 	Env        map[string]string
+	Cleanup    CleanupPolicy
 
 	// Input/Output configuration
 	Stdin       io.Reader
-	OutputLimit int64 // Maximum bytes to capture from stdout/stderr (0 for unlimited)
+	Files       map[string]io.Reader // Copied into the working directory before the process starts
+	OutputLimit int64                // Bytes of stdout/stderr each kept in memory, split between head and tail (0 for unlimited)
+
+	// Where the whole of stdout/stderr is written, gzipped, if it outgrows
+	// OutputLimit
+	StdoutFile string
+	StderrFile string
+
+	// Called with each progress report the process writes to the file
+	// descriptor named by $JOB_PROGRESS_FD
+	Progress func(Progress)
+
+	// Also receive stdout/stderr as the process writes them, whatever
+	// OutputLimit is. Errors they return are ignored.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Glob patterns, relative to the working directory, of files to move to
+	// ArtifactDir once the process exits. Only files within the scratch
+	// directory are collected, so WorkingDir cannot then be absolute.
+	Artifacts   []string
+	ArtifactDir string
 
 	// Process management
 	KillTimeout time.Duration // Time to wait after sending SIGTERM before SIGKILL
+	Limits      Limits
+	Sandbox     *Sandbox // Run isolated; WorkingDir is then relative to the scratch directory
 }
 
-// Executor manages the execution of external applications
+const (
+	// waitDelay bounds how long Execute waits for output once the process
+	// has exited, in case processes it left behind keep its pipes open
+	waitDelay = 200 * time.Millisecond
+
+	// survivorTimeout is how long Execute waits for the rest of a job's
+	// process group to die after killing it
+	survivorTimeout = time.Second
+)
+
+// Executor manages the execution of external applications. Each process
+// runs in its own process group, so signals reach everything it spawns.
 type Executor struct {
 	workDir     string
+	cgroupRoot  string // cgroup v2 directory under which limited processes run
 	mu          sync.RWMutex
 	processes   map[string]*exec.Cmd
 	execCounter uint64
 }
 
-// NewExecutor creates a new executor instance
+// NewExecutor creates a new executor instance. Executions get scratch
+// directories under workDir, or under the current directory if it is empty.
 func NewExecutor(workDir string) (*Executor, error) {
 	// Ensure working directory exists and is accessible
-	if workDir != "" {
-		if err := os.MkdirAll(workDir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create working directory: %v", err)
-		}
+	workDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve working directory: %v", err)
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %v", err)
 	}
 
 	return &Executor{
@@ -68,17 +177,59 @@ func NewExecutor(workDir string) (*Executor, error) {
 // Execute runs an application with the given configuration
 func (e *Executor) Execute(ctx context.Context, cfg Config) (*ExecutionResult, error) {
 	// Generate unique execution ID
-	execID := fmt.Sprintf("exec_%d_%d", time.Now().UnixNano(), e.execCounter)
-
-	// Create command with context
-	cmd := exec.CommandContext(ctx, cfg.Path, cfg.Args...)
+	execID := fmt.Sprintf("%s%d_%d", execPrefix, time.Now().UnixNano(), atomic.AddUint64(&e.execCounter, 1))
+
+	// Create command; cancellation is handled below so that the process
+	// gets a chance to exit gracefully
+	cmd := exec.Command(cfg.Path, cfg.Args...)
+	configureProcess(cmd)
+	cmd.WaitDelay = waitDelay
+
+	// Set up the scratch directory, which is removed according to the
+	// cleanup policy once the process has exited
+	scratch := filepath.Join(e.workDir, execID)
+	var keepScratch bool
+	defer func() {
+		if !keepScratch {
+			os.RemoveAll(scratch)
+		}
+	}()
+	if err := e.setupWorkingDir(cmd, scratch, cfg); err != nil {
+		now := time.Now()
+		return &ExecutionResult{ExecutionID: execID, ExitCode: -1, StartTime: now, EndTime: now}, err
+	}
+	if cfg.Sandbox != nil {
+		cleanup, err := configureSandbox(cmd, cfg.Sandbox, e.workDir, execID, scratch)
+		defer cleanup()
+		if err != nil {
+			now := time.Now()
+			return &ExecutionResult{ExecutionID: execID, ExitCode: -1, StartTime: now, EndTime: now}, err
+		}
+	}
+	dir := cmd.Dir
 
-	// Set up working directory
-	if cfg.WorkingDir != "" {
-		if !filepath.IsAbs(cfg.WorkingDir) {
-			cfg.WorkingDir = filepath.Join(e.workDir, cfg.WorkingDir)
+	// Place input files, removing them again once the process exits
+	written, err := writeFiles(dir, cfg.Files)
+	defer func() {
+		for _, path := range written {
+			os.Remove(path)
 		}
-		cmd.Dir = cfg.WorkingDir
+	}()
+	if err != nil {
+		now := time.Now()
+		return &ExecutionResult{ExecutionID: execID, ExitCode: -1, StartTime: now, EndTime: now}, err
+	}
+
+	// Prepare resource limits, which the process has from the moment it
+	// starts
+	e.mu.RLock()
+	cgroupRoot := e.cgroupRoot
+	e.mu.RUnlock()
+	limits, err := newResourceControl(cgroupRoot, execID, cfg.Limits, cmd)
+	defer limits.close()
+	if err != nil {
+		now := time.Now()
+		return &ExecutionResult{ExecutionID: execID, ExitCode: -1, StartTime: now, EndTime: now}, err
 	}
 
 	// Set up environment
@@ -87,19 +238,43 @@ func (e *Executor) Execute(ctx context.Context, cfg Config) (*ExecutionResult, e
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	// Read progress reports, if anyone wants them
+	var progress *progressPipe
+	if cfg.Progress != nil {
+		if progress, err = watchProgress(cmd); err != nil {
+			now := time.Now()
+			return &ExecutionResult{ExecutionID: execID, ExitCode: -1, StartTime: now, EndTime: now}, err
+		}
+	}
+
+	// Hear from a helper that could not set the process up
+	var status *statusPipe
+	if isHelper(cmd) {
+		if status, err = watchStatus(cmd); err != nil {
+			if progress != nil {
+				progress.close()
+			}
+			now := time.Now()
+			return &ExecutionResult{ExecutionID: execID, ExitCode: -1, StartTime: now, EndTime: now}, err
+		}
+	}
+
 	// Set up input if provided
 	if cfg.Stdin != nil {
 		cmd.Stdin = cfg.Stdin
 	}
 
 	// Set up output capture
-	var stdout, stderr bytes.Buffer
-	if cfg.OutputLimit > 0 {
-		cmd.Stdout = &LimitedWriter{W: &stdout, N: cfg.OutputLimit}
-		cmd.Stderr = &LimitedWriter{W: &stderr, N: cfg.OutputLimit}
-	} else {
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+	stdout := newOutputBuffer(cfg.OutputLimit, cfg.StdoutFile)
+	stderr := newOutputBuffer(cfg.OutputLimit, cfg.StderrFile)
+	defer stdout.Close()
+	defer stderr.Close()
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if cfg.Stdout != nil {
+		cmd.Stdout = io.MultiWriter(ignoreErrors{cfg.Stdout}, cmd.Stdout)
+	}
+	if cfg.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(ignoreErrors{cfg.Stderr}, cmd.Stderr)
 	}
 
 	// Track the process
@@ -122,8 +297,22 @@ func (e *Executor) Execute(ctx context.Context, cfg Config) (*ExecutionResult, e
 
 	// Start the process
 	if err := cmd.Start(); err != nil {
+		if progress != nil {
+			progress.close()
+		}
+		if status != nil {
+			status.close()
+		}
+		result.ExitCode = -1
+		result.EndTime = time.Now()
 		return result, fmt.Errorf("failed to start process: %v", err)
 	}
+	if progress != nil {
+		progress.start(cfg.Progress)
+	}
+	if status != nil {
+		status.start()
+	}
 
 	// Create a channel for the command completion
 	done := make(chan error, 1)
@@ -136,16 +325,51 @@ func (e *Executor) Execute(ctx context.Context, cfg Config) (*ExecutionResult, e
 	select {
 	case err := <-done:
 		execErr = err
+		result.Termination = TerminationExited
 	case <-ctx.Done():
-		execErr = e.handleTimeout(cmd, cfg.KillTimeout)
+		result.Termination, execErr = e.handleTimeout(cmd, done, cfg.KillTimeout)
+	}
+	if errors.Is(execErr, exec.ErrWaitDelay) {
+		execErr = nil // Exited successfully, but left processes holding its output
+	}
+
+	// Kill whatever the process left running in its group
+	result.Survivors = e.killGroup(cmd)
+	if progress != nil {
+		progress.stop()
 	}
 
 	// Record end time
 	result.EndTime = time.Now()
 
-	// Capture output
-	result.Stdout = stdout.String()
-	result.Stderr = stderr.String()
+	// A process that a helper could not set up never ran
+	if status != nil {
+		if failure := status.failure(); failure != "" {
+			result.ExitCode = -1
+			return result, fmt.Errorf("failed to start process: %s", failure)
+		}
+	}
+
+	// Capture output, and finish writing any that outgrew the limit
+	result.Stdout, result.StdoutOmitted = stdout.captured()
+	result.Stderr, result.StderrOmitted = stderr.captured()
+	for _, output := range []*outputBuffer{stdout, stderr} {
+		if err := output.Close(); err != nil && execErr == nil {
+			execErr = err
+		}
+	}
+
+	// Collect artifacts, whether or not the process succeeded
+	result.Artifacts, err = collectArtifacts(scratch, dir, cfg.Artifacts, cfg.ArtifactDir)
+	if err != nil && execErr == nil {
+		execErr = err
+	}
+
+	// Keep the scratch directory if the policy asks for it
+	keepScratch = cfg.Cleanup == CleanupNever || (cfg.Cleanup == CleanupOnSuccess && execErr != nil)
+	if keepScratch {
+		result.WorkDir = scratch
+	}
 
 	// Get exit code
 	if execErr != nil {
@@ -154,6 +378,9 @@ func (e *Executor) Execute(ctx context.Context, cfg Config) (*ExecutionResult, e
 		} else {
 			result.ExitCode = -1 // Indicate non-exit error
 		}
+		if result.FailureReason = limits.failure(cmd.ProcessState); result.FailureReason != "" {
+			return result, fmt.Errorf("execution failed: %v (%s)", execErr, result.FailureReason)
+		}
 		return result, fmt.Errorf("execution failed: %v", execErr)
 	}
 
@@ -161,40 +388,252 @@ func (e *Executor) Execute(ctx context.Context, cfg Config) (*ExecutionResult, e
 	return result, nil
 }
 
-// handleTimeout handles graceful shutdown of a process
-func (e *Executor) handleTimeout(cmd *exec.Cmd, killTimeout time.Duration) error {
-	if cmd.Process == nil {
+// execPrefix starts every execution ID, and so the name of every scratch
+// directory
+const execPrefix = "exec_"
+
+// setupWorkingDir creates the scratch directory of an execution and sets
+// the directory cmd starts in. An absolute WorkingDir is used as it is,
+// unless the process is sandboxed; a relative one must stay within the
+// scratch directory.
+func (e *Executor) setupWorkingDir(cmd *exec.Cmd, scratch string, cfg Config) error {
+	if err := os.Mkdir(scratch, 0755); err != nil {
+		return fmt.Errorf("failed to create scratch directory: %v", err)
+	}
+
+	if len(cfg.Artifacts) > 0 && cfg.WorkingDir != "" && !filepath.IsLocal(cfg.WorkingDir) {
+		return fmt.Errorf("artifacts can only be collected from a working directory within the scratch directory, not %q", cfg.WorkingDir)
+	}
+
+	switch {
+	case cfg.WorkingDir == "":
+		cmd.Dir = scratch
+	case filepath.IsAbs(cfg.WorkingDir) && cfg.Sandbox == nil:
+		cmd.Dir = cfg.WorkingDir
+	case filepath.IsLocal(cfg.WorkingDir):
+		cmd.Dir = filepath.Join(scratch, cfg.WorkingDir)
+		if err := os.MkdirAll(cmd.Dir, 0755); err != nil {
+			return fmt.Errorf("failed to create working directory: %v", err)
+		}
+	case cfg.Sandbox != nil:
+		return fmt.Errorf("sandboxed working directory %q must be a relative path", cfg.WorkingDir)
+	default:
+		return fmt.Errorf("working directory %q must be absolute or within the scratch directory", cfg.WorkingDir)
+	}
+	return nil
+}
+
+// collectArtifacts moves the regular files under dir that match patterns
+// into dest, keeping their relative paths. Only files that really are
+// within root, the execution's scratch directory, are collected: neither a
+// symbolic link nor a linked directory on the way to a file is followed out
+// of it, so that a process cannot have files it could not touch moved away.
+func collectArtifacts(root, dir string, patterns []string, dest string) ([]Artifact, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve scratch directory: %v", err)
+	}
+
+	var artifacts []Artifact
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return artifacts, fmt.Errorf("invalid artifact pattern %q: %v", pattern, err)
+		}
+		for _, path := range matches {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil || !filepath.IsLocal(rel) || seen[rel] {
+				continue
+			}
+			info, err := os.Lstat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			real, err := filepath.EvalSymlinks(path)
+			if err != nil || !within(root, real) {
+				continue
+			}
+			seen[rel] = true
+
+			if err := moveFile(real, filepath.Join(dest, rel)); err != nil {
+				return artifacts, fmt.Errorf("failed to collect artifact %s: %v", rel, err)
+			}
+			artifacts = append(artifacts, Artifact{Name: filepath.ToSlash(rel), Size: info.Size()})
+		}
+	}
+	return artifacts, nil
+}
+
+// within reports whether path is inside dir
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// moveFile moves a file, copying it if it cannot be renamed
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
 		return nil
 	}
 
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// writeFiles writes files under dir, returning the paths it created. File
+// names must be relative paths that stay within dir.
+func writeFiles(dir string, files map[string]io.Reader) ([]string, error) {
+	var written []string
+	for name, data := range files {
+		if !filepath.IsLocal(name) {
+			return written, fmt.Errorf("input file %q must be a relative path within the working directory", name)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return written, fmt.Errorf("failed to create directory for input file %s: %v", name, err)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return written, fmt.Errorf("failed to write input file %s: %v", name, err)
+		}
+		written = append(written, path)
+		_, err = io.Copy(f, data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to write input file %s: %v", name, err)
+		}
+	}
+	return written, nil
+}
+
+// handleTimeout handles graceful shutdown of a process group: it sends
+// SIGTERM, then SIGKILL if the process is still running after killTimeout.
+// done receives the result of the single cmd.Wait call made by Execute.
+func (e *Executor) handleTimeout(cmd *exec.Cmd, done <-chan error, killTimeout time.Duration) (Termination, error) {
 	// Try graceful shutdown first
-	if err := cmd.Process.Signal(os.Interrupt); err != nil {
-		return e.forceKill(cmd)
+	if err := signalProcess(cmd, syscall.SIGTERM); err != nil {
+		e.forceKill(cmd)
+		return TerminationKilled, <-done
 	}
 
 	// Wait for the process to exit gracefully
 	timer := time.NewTimer(killTimeout)
 	defer timer.Stop()
 
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Wait()
-	}()
-
 	select {
 	case err := <-done:
-		return err
+		return TerminationTerminated, err
 	case <-timer.C:
-		return e.forceKill(cmd)
+		e.forceKill(cmd)
+		return TerminationKilled, <-done
 	}
 }
 
-// forceKill forcefully terminates a process
+// forceKill forcefully terminates a process and its process group
 func (e *Executor) forceKill(cmd *exec.Cmd) error {
+	return signalProcess(cmd, syscall.SIGKILL)
+}
+
+// killGroup kills the processes left in the group of a process that has
+// exited, reaps those that were reparented to us and returns any that are
+// still alive after survivorTimeout
+func (e *Executor) killGroup(cmd *exec.Cmd) []int {
 	if cmd.Process == nil {
 		return nil
 	}
-	return cmd.Process.Kill()
+	pgid := cmd.Process.Pid
+	if len(groupMembers(pgid)) == 0 {
+		return nil
+	}
+	e.forceKill(cmd)
+
+	deadline := time.Now().Add(survivorTimeout)
+	for {
+		reapGroup(pgid)
+		survivors := groupMembers(pgid)
+		if len(survivors) == 0 || time.Now().After(deadline) {
+			return survivors
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// EnableSubreaper makes this process adopt the processes orphaned by its
+// jobs, so they can be reaped here instead of by init. It is only
+// supported on Linux. Either way, anything left in a job's process group
+// when it ends is killed.
+func (e *Executor) EnableSubreaper() error {
+	if err := enableSubreaper(); err != nil {
+		return fmt.Errorf("failed to enable subreaper: %v", err)
+	}
+	return nil
+}
+
+// SetCgroupRoot sets the cgroup v2 directory under which processes with
+// memory, CPU or process limits each get a cgroup of their own. The
+// directory must be delegated to this process, and must not itself contain
+// processes. Without one, only rlimits are applied.
+func (e *Executor) SetCgroupRoot(path string) error {
+	if err := enableCgroupRoot(path); err != nil {
+		return fmt.Errorf("failed to set cgroup root: %v", err)
+	}
+	e.mu.Lock()
+	e.cgroupRoot = path
+	e.mu.Unlock()
+	return nil
+}
+
+// RemoveWorkDirs removes the scratch directories kept by executions that
+// have finished and were last modified before cutoff, returning how many
+// it removed
+func (e *Executor) RemoveWorkDirs(cutoff time.Time) int {
+	entries, err := os.ReadDir(e.workDir)
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		execID := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(execID, execPrefix) {
+			continue
+		}
+		e.mu.RLock()
+		_, running := e.processes[execID]
+		e.mu.RUnlock()
+		info, err := entry.Info()
+		if running || err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if os.RemoveAll(filepath.Join(e.workDir, execID)) == nil {
+			removed++
+		}
+	}
+	return removed
 }
 
 // ListProcesses returns information about all running processes
@@ -236,20 +675,13 @@ func (e *Executor) Cleanup() {
 	}
 }
 
-// LimitedWriter wraps an io.Writer with a byte limit
-type LimitedWriter struct {
-	W io.Writer // Underlying writer
-	N int64     // Max bytes remaining
+// ignoreErrors wraps an io.Writer so that its failures don't stop the
+// output reaching any other writer
+type ignoreErrors struct {
+	w io.Writer
 }
 
-func (l *LimitedWriter) Write(p []byte) (n int, err error) {
-	if l.N <= 0 {
-		return 0, io.ErrShortWrite
-	}
-	if int64(len(p)) > l.N {
-		p = p[:l.N]
-	}
-	n, err = l.W.Write(p)
-	l.N -= int64(n)
-	return
+func (i ignoreErrors) Write(p []byte) (int, error) {
+	i.w.Write(p)
+	return len(p), nil
 }
@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// selfExe is the running binary, which is re-executed to act as a helper
+// that sets a process up before it runs
+const selfExe = "/proc/self/exe"
+
+// statusFD is the descriptor a helper reports setup failures on, the one
+// after progressFD
+const statusFD = progressFD + 1
+
+// maxStatus is the longest setup failure that is read
+const maxStatus = 4096
+
+// isHelper reports whether cmd starts a helper rather than the process
+// it was created for
+func isHelper(cmd *exec.Cmd) bool {
+	return cmd.Path == selfExe
+}
+
+// statusPipe reads the setup failure a helper reports, if any. A helper
+// that fails writes why and exits; one that succeeds makes sure the
+// process it runs does not inherit the pipe, so that nothing is read. The
+// exit status alone could not tell the two apart.
+type statusPipe struct {
+	r, w *os.File
+}
+
+// watchStatus passes a helper the write end of a pipe for its setup
+// failures
+func watchStatus(cmd *exec.Cmd) (*statusPipe, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create status pipe: %v", err)
+	}
+	// Extra file i becomes descriptor 3+i; one left nil is closed
+	files := make([]*os.File, statusFD-2)
+	copy(files, cmd.ExtraFiles)
+	files[statusFD-3] = w
+	cmd.ExtraFiles = files
+	return &statusPipe{r: r, w: w}, nil
+}
+
+// start lets go of the write end once the helper has its own copy
+func (p *statusPipe) start() {
+	p.w.Close()
+}
+
+// failure returns the setup failure the helper reported, once it has
+// exited
+func (p *statusPipe) failure() string {
+	defer p.r.Close()
+	p.r.SetReadDeadline(time.Now().Add(waitDelay))
+	report, _ := io.ReadAll(io.LimitReader(p.r, maxStatus))
+	return string(report)
+}
+
+// close closes both ends, for a helper that never started
+func (p *statusPipe) close() {
+	p.w.Close()
+	p.r.Close()
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// helperFailureCode is the exit code of a helper that could not set up
+// its process. It is not relied on, as the process could exit with it too.
+const helperFailureCode = 125
+
+// MaybeRunHelper runs this process as one of the executor's helpers if it
+// was started as one, and then never returns. Sandboxes and rlimits are
+// set up by re-executing the running binary, so a program that executes
+// processes with either must call this before anything else in main.
+func MaybeRunHelper() {
+	if len(os.Args) != 2 {
+		return
+	}
+	switch os.Args[0] {
+	case rlimitExecName:
+		runRlimitExec(os.Args[1])
+	case sandboxInitName:
+		runSandboxInit(os.Args[1])
+	}
+}
+
+// helperFail reports why a helper could not set up its process on the
+// status pipe, or on stderr without one, and exits
+func helperFail(format string, args ...interface{}) {
+	report := fmt.Sprintf(format, args...)
+	if _, err := io.WriteString(os.NewFile(statusFD, "status"), report); err != nil {
+		fmt.Fprintln(os.Stderr, report)
+	}
+	os.Exit(helperFailureCode)
+}
@@ -0,0 +1,6 @@
+//go:build !linux
+
+package executor
+
+// MaybeRunHelper does nothing, as helpers are only used on Linux
+func MaybeRunHelper() {}
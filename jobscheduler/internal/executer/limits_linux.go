@@ -0,0 +1,230 @@
+//go:build linux
+
+package executor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Period over which a CPU quota is measured, in microseconds
+const cpuPeriod = 100000
+
+// rlimitExecName is the argv[0] that makes MaybeRunHelper set rlimits on
+// this process and exec the process they are meant for
+const rlimitExecName = "jobscheduler-rlimit-exec"
+
+// rlimitSpec tells the rlimit helper what to set and then run
+type rlimitSpec struct {
+	Limits map[int]uint64 // Value for both the soft and hard limit of each resource
+	Path   string         // Process to exec
+	Args   []string       // Its arguments, including argv[0]
+}
+
+// resourceControl enforces the limits of one execution, with rlimits and,
+// when the executor has a cgroup root, a cgroup of its own
+type resourceControl struct {
+	limits Limits
+	cgroup string   // Directory of the execution's cgroup, if any
+	dir    *os.File // Open cgroup directory, which the process starts in
+}
+
+// newResourceControl prepares the limits for cmd. Memory, CPU and process
+// limits are set on a new cgroup under root if there is one; without one,
+// memory falls back to an rlimit, and a CPU quota or process limit is
+// refused. RLIMIT_NPROC would count every process of the user, and does
+// not apply to root at all.
+func newResourceControl(root, execID string, limits Limits, cmd *exec.Cmd) (*resourceControl, error) {
+	rc := &resourceControl{limits: limits}
+	needsCgroup := limits.MemoryMax > 0 || limits.CPUQuota > 0 || limits.MaxProcesses > 0
+	if needsCgroup && root == "" && limits.CPUQuota > 0 {
+		return rc, fmt.Errorf("a CPU quota requires a cgroup root")
+	}
+	if needsCgroup && root == "" && limits.MaxProcesses > 0 {
+		return rc, fmt.Errorf("a process limit requires a cgroup root")
+	}
+	if needsCgroup && root != "" {
+		if err := rc.joinCgroup(root, execID, cmd); err != nil {
+			return rc, err
+		}
+	}
+	return rc, rc.setRlimits(cmd)
+}
+
+// joinCgroup creates a cgroup for the execution under root with the
+// memory, CPU and process limits, which cmd starts in
+func (rc *resourceControl) joinCgroup(root, execID string, cmd *exec.Cmd) error {
+	limits := rc.limits
+
+	path := filepath.Join(root, execID)
+	if err := os.Mkdir(path, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup: %v", err)
+	}
+	rc.cgroup = path
+
+	settings := make(map[string]string)
+	if limits.MemoryMax > 0 {
+		settings["memory.max"] = strconv.FormatInt(limits.MemoryMax, 10)
+	}
+	if limits.CPUQuota > 0 {
+		quota := int(limits.CPUQuota * cpuPeriod)
+		settings["cpu.max"] = fmt.Sprintf("%d %d", max(quota, 1000), cpuPeriod)
+	}
+	if limits.MaxProcesses > 0 {
+		settings["pids.max"] = strconv.Itoa(limits.MaxProcesses)
+	}
+	for name, value := range settings {
+		if err := os.WriteFile(filepath.Join(path, name), []byte(value), 0644); err != nil {
+			return fmt.Errorf("failed to set %s: %v", name, err)
+		}
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open cgroup: %v", err)
+	}
+	rc.dir = dir
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+	return nil
+}
+
+// setRlimits makes cmd set its own rlimits before it execs, by starting it
+// through this binary. Setting them from outside once it has started would
+// race with the process itself.
+func (rc *resourceControl) setRlimits(cmd *exec.Cmd) error {
+	limits := make(map[int]uint64)
+	if rc.limits.MaxOpenFiles > 0 {
+		limits[syscall.RLIMIT_NOFILE] = uint64(rc.limits.MaxOpenFiles)
+	}
+	if rc.limits.MaxFileSize > 0 {
+		limits[syscall.RLIMIT_FSIZE] = uint64(rc.limits.MaxFileSize)
+	}
+	if rc.cgroup == "" && rc.limits.MemoryMax > 0 {
+		// A weaker stand-in: the address space rather than memory in use
+		limits[syscall.RLIMIT_AS] = uint64(rc.limits.MemoryMax)
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	encoded, err := json.Marshal(rlimitSpec{Limits: limits, Path: cmd.Path, Args: cmd.Args})
+	if err != nil {
+		return fmt.Errorf("failed to encode rlimits: %v", err)
+	}
+	cmd.Path = selfExe
+	cmd.Args = []string{rlimitExecName, string(encoded)}
+	return nil
+}
+
+// runRlimitExec sets the rlimits described by encoded and execs the
+// process they are for, which keeps them. The status pipe is only passed
+// on if that process is another helper.
+func runRlimitExec(encoded string) {
+	var spec rlimitSpec
+	if err := json.Unmarshal([]byte(encoded), &spec); err != nil {
+		helperFail("rlimit: invalid rlimits: %v", err)
+	}
+	for resource, value := range spec.Limits {
+		limit := syscall.Rlimit{Cur: value, Max: value}
+		if err := syscall.Setrlimit(resource, &limit); err != nil {
+			helperFail("rlimit: failed to set rlimit %d: %v", resource, err)
+		}
+	}
+	if spec.Path != selfExe {
+		syscall.CloseOnExec(statusFD)
+	}
+	err := syscall.Exec(spec.Path, spec.Args, os.Environ())
+	helperFail("rlimit: failed to exec %s: %v", spec.Path, err)
+}
+
+// failure works out which limit, if any, made a process fail
+func (rc *resourceControl) failure(state *os.ProcessState) FailureReason {
+	if state != nil && rc.limits.MaxFileSize > 0 {
+		// A shell reports a child killed by a signal as 128 plus its number
+		status, ok := state.Sys().(syscall.WaitStatus)
+		if ok && ((status.Signaled() && status.Signal() == syscall.SIGXFSZ) || status.ExitStatus() == 128+int(syscall.SIGXFSZ)) {
+			return FailureFileSizeLimit
+		}
+	}
+	if rc.cgroup == "" {
+		return ""
+	}
+	if rc.limits.MemoryMax > 0 && cgroupEvent(rc.cgroup, "memory.events", "oom_kill") > 0 {
+		return FailureOOMKilled
+	}
+	if rc.limits.MaxProcesses > 0 && cgroupEvent(rc.cgroup, "pids.events", "max") > 0 {
+		return FailureProcessLimit
+	}
+	return ""
+}
+
+// close kills anything left in the execution's cgroup and removes it
+func (rc *resourceControl) close() {
+	if rc.dir != nil {
+		rc.dir.Close()
+	}
+	if rc.cgroup == "" {
+		return
+	}
+
+	// cgroup.kill only exists on Linux 5.14 and later
+	os.WriteFile(filepath.Join(rc.cgroup, "cgroup.kill"), []byte("1"), 0644)
+	deadline := time.Now().Add(survivorTimeout)
+	for os.Remove(rc.cgroup) != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// cgroupEvent reads a counter from a cgroup events file such as
+// memory.events
+func cgroupEvent(cgroup, file, key string) int64 {
+	f, err := os.Open(filepath.Join(cgroup, file))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			n, _ := strconv.ParseInt(fields[1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// enableCgroupRoot checks that path is a cgroup v2 directory and enables
+// the controllers its children need
+func enableCgroupRoot(path string) error {
+	controllers, err := os.ReadFile(filepath.Join(path, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("%s is not a cgroup v2 directory: %v", path, err)
+	}
+
+	var enable []string
+	for _, controller := range strings.Fields(string(controllers)) {
+		switch controller {
+		case "cpu", "memory", "pids":
+			enable = append(enable, "+"+controller)
+		}
+	}
+	if len(enable) == 0 {
+		return fmt.Errorf("%s has none of the cpu, memory and pids controllers", path)
+	}
+	control := strings.Join(enable, " ")
+	if err := os.WriteFile(filepath.Join(path, "cgroup.subtree_control"), []byte(control), 0644); err != nil {
+		return fmt.Errorf("failed to enable controllers in %s: %v", path, err)
+	}
+	return nil
+}
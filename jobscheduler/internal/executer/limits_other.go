@@ -0,0 +1,32 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// resourceControl would enforce the limits of one execution; limits are
+// only supported on Linux
+type resourceControl struct{}
+
+// newResourceControl refuses any limits on this platform
+func newResourceControl(root, execID string, limits Limits, cmd *exec.Cmd) (*resourceControl, error) {
+	if !limits.IsZero() {
+		return &resourceControl{}, fmt.Errorf("resource limits are not supported on this platform")
+	}
+	return &resourceControl{}, nil
+}
+
+func (rc *resourceControl) failure(state *os.ProcessState) FailureReason {
+	return ""
+}
+
+func (rc *resourceControl) close() {}
+
+// enableCgroupRoot always fails, as cgroups are Linux only
+func enableCgroupRoot(path string) error {
+	return fmt.Errorf("cgroups are not supported on this platform")
+}
@@ -0,0 +1,126 @@
+package executor
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// outputBuffer captures a stream of output. It keeps the head and tail of
+// the stream in memory, up to limit bytes between them, and once the
+// stream outgrows them writes the whole of it to a gzipped file. Writes
+// never fail, so the process is never cut off; a failure to write the
+// file is reported by Close.
+type outputBuffer struct {
+	limit int64
+	head  []byte
+	tail  []byte
+	size  int64
+
+	path string // Where to write the whole stream; nowhere if empty
+	file *os.File
+	gz   *gzip.Writer
+	err  error
+}
+
+func newOutputBuffer(limit int64, path string) *outputBuffer {
+	return &outputBuffer{limit: limit, path: path}
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.size += int64(n)
+
+	// Everything written so far is still in memory at the moment the
+	// stream first outgrows it, so the file starts with that
+	if b.limit > 0 && b.size > b.limit && b.file == nil && b.path != "" && b.err == nil {
+		b.spill()
+	}
+	if b.gz != nil && b.err == nil {
+		if _, err := b.gz.Write(p); err != nil {
+			b.err = fmt.Errorf("failed to write %s: %v", b.path, err)
+		}
+	}
+
+	if b.limit <= 0 {
+		b.head = append(b.head, p...)
+		return n, nil
+	}
+	if room := b.limit/2 - int64(len(b.head)); room > 0 {
+		taken := min(room, int64(len(p)))
+		b.head = append(b.head, p[:taken]...)
+		p = p[taken:]
+	}
+	b.tail = append(b.tail, p...)
+	if keep := b.limit - b.limit/2; int64(len(b.tail)) > keep {
+		b.tail = b.tail[int64(len(b.tail))-keep:]
+	}
+	return n, nil
+}
+
+// spill starts writing the stream to its file
+func (b *outputBuffer) spill() {
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		b.err = fmt.Errorf("failed to create output directory: %v", err)
+		return
+	}
+	file, err := os.Create(b.path)
+	if err != nil {
+		b.err = fmt.Errorf("failed to create output file: %v", err)
+		return
+	}
+	b.file = file
+	b.gz = gzip.NewWriter(file)
+	for _, kept := range [][]byte{b.head, b.tail} {
+		if _, err := b.gz.Write(kept); err != nil {
+			b.err = fmt.Errorf("failed to write %s: %v", b.path, err)
+			return
+		}
+	}
+}
+
+// captured returns the output kept in memory, and how many bytes were
+// omitted from the middle of it. Where bytes were omitted is marked, in
+// place of the start of the tail so that the output stays within limit.
+func (b *outputBuffer) captured() (string, int64) {
+	omitted := b.size - int64(len(b.head)) - int64(len(b.tail))
+	if omitted == 0 {
+		return string(b.head) + string(b.tail), 0
+	}
+
+	// Making room for the marker omits more, which may lengthen it
+	marker := omittedMarker(omitted)
+	for {
+		next := omittedMarker(omitted + int64(len(marker)))
+		settled := len(next) == len(marker)
+		marker = next
+		if settled {
+			break
+		}
+	}
+	if len(marker) > len(b.tail) {
+		return string(b.head) + string(b.tail), omitted
+	}
+	return string(b.head) + marker + string(b.tail[len(marker):]), omitted + int64(len(marker))
+}
+
+// omittedMarker marks where n bytes were omitted from output
+func omittedMarker(n int64) string {
+	return fmt.Sprintf("\n... %d bytes omitted ...\n", n)
+}
+
+// Close finishes the file, if the stream was written to one
+func (b *outputBuffer) Close() error {
+	if b.file == nil {
+		return b.err
+	}
+	if err := b.gz.Close(); err != nil && b.err == nil {
+		b.err = fmt.Errorf("failed to write %s: %v", b.path, err)
+	}
+	if err := b.file.Close(); err != nil && b.err == nil {
+		b.err = fmt.Errorf("failed to write %s: %v", b.path, err)
+	}
+	b.file, b.gz = nil, nil
+	return b.err
+}
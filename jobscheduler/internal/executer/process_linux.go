@@ -0,0 +1,88 @@
+//go:build linux
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// prctl option that makes orphaned descendants reparent to this process
+const prSetChildSubreaper = 36
+
+// configureProcess starts the command in its own process group, so that it
+// and every process it spawns can be signalled together
+func configureProcess(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcess sends sig to the command's whole process group
+func signalProcess(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// groupMembers returns the live processes in a process group. Zombies are
+// left out, as they no longer consume anything but a process table entry.
+func groupMembers(pgid int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue // Exited while we were looking
+		}
+
+		// The command name is parenthesised and may contain spaces
+		end := strings.LastIndexByte(string(stat), ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[end+1:]))
+		if len(fields) < 3 || fields[0] == "Z" || fields[0] == "X" {
+			continue
+		}
+		if group, err := strconv.Atoi(fields[2]); err == nil && group == pgid {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// reapGroup collects the exit status of any of our children left in a
+// process group, which only happens to orphans once we are a subreaper
+func reapGroup(pgid int) {
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-pgid, &status, syscall.WNOHANG, nil)
+		if err != nil || pid <= 0 {
+			return
+		}
+	}
+}
+
+// enableSubreaper marks this process as a child subreaper, so processes
+// orphaned by a job are reparented to it rather than to init
+func enableSubreaper() error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
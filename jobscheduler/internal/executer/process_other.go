@@ -0,0 +1,36 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// configureProcess does nothing; process groups are only used on Linux
+func configureProcess(cmd *exec.Cmd) {}
+
+// signalProcess sends sig to the command's process only
+func signalProcess(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if sig == syscall.SIGKILL {
+		return cmd.Process.Kill()
+	}
+	return cmd.Process.Signal(sig)
+}
+
+// groupMembers cannot list process groups on this platform
+func groupMembers(pgid int) []int {
+	return nil
+}
+
+// reapGroup does nothing, as enableSubreaper is not supported
+func reapGroup(pgid int) {}
+
+// enableSubreaper is only supported on Linux
+func enableSubreaper() error {
+	return fmt.Errorf("subreaper is not supported on this platform")
+}
@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProgressFDEnv names the environment variable that tells a process which
+// file descriptor to write progress reports to. Each report is a line
+// holding a percentage, optionally followed by a message, or a message
+// alone, which keeps the last percentage:
+//
+//	echo "40 Copying files" >&$JOB_PROGRESS_FD
+const ProgressFDEnv = "JOB_PROGRESS_FD"
+
+// progressFD is the descriptor reports are written to, the first after
+// stderr
+const progressFD = 3
+
+// maxProgressLine is the longest report that is read. Once a process
+// writes a longer one, the rest of its reports are ignored.
+const maxProgressLine = 4096
+
+// Progress is how far a process has said it has got
+type Progress struct {
+	Percent float64 // 0 to 100
+	Message string
+}
+
+// progressPipe reads a process's progress reports
+type progressPipe struct {
+	r, w *os.File
+	done chan struct{}
+}
+
+// watchProgress passes a process the write end of a pipe for its
+// progress reports
+func watchProgress(cmd *exec.Cmd) (*progressPipe, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create progress pipe: %v", err)
+	}
+	cmd.ExtraFiles = []*os.File{w} // The first extra file is progressFD
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", ProgressFDEnv, progressFD))
+	return &progressPipe{r: r, w: w, done: make(chan struct{})}, nil
+}
+
+// start passes reports on to report once the process has started with
+// its own copy of the write end
+func (p *progressPipe) start(report func(Progress)) {
+	p.w.Close()
+	go func() {
+		defer close(p.done)
+		var current Progress
+		scanner := bufio.NewScanner(p.r)
+		scanner.Buffer(make([]byte, 0, 256), maxProgressLine)
+		for scanner.Scan() {
+			if parseProgress(scanner.Text(), &current) {
+				report(current)
+			}
+		}
+		io.Copy(io.Discard, p.r) // Keep the pipe drained if a report was too long
+	}()
+}
+
+// stop waits briefly for the reports the process wrote before it exited,
+// then stops reading, even if processes it left behind hold the pipe open
+func (p *progressPipe) stop() {
+	p.r.SetReadDeadline(time.Now().Add(waitDelay))
+	<-p.done
+	p.r.Close()
+}
+
+// close releases the pipe of a process that failed to start
+func (p *progressPipe) close() {
+	p.w.Close()
+	p.r.Close()
+}
+
+// parseProgress applies a report line to current, returning false if the
+// line says nothing
+func parseProgress(line string, current *Progress) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	first, rest, _ := strings.Cut(line, " ")
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(first, "%"), 64)
+	if err != nil || math.IsNaN(percent) || math.IsInf(percent, 0) {
+		current.Message = line
+		return true
+	}
+	current.Percent = min(max(percent, 0), 100)
+	current.Message = strings.TrimSpace(rest)
+	return true
+}
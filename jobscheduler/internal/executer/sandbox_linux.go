@@ -0,0 +1,240 @@
+//go:build linux
+
+package executor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// sandboxInitName is the argv[0] that makes MaybeRunHelper act as the init
+// process of a sandbox instead of returning to main
+const sandboxInitName = "jobscheduler-sandbox-init"
+
+// prctl option that stops execve from granting privileges
+const prSetNoNewPrivs = 38
+
+// sandboxSpec tells the sandbox init process what to set up and run
+type sandboxSpec struct {
+	Root    string   // Empty directory to build the new root on
+	WorkDir string   // Executor working directory, hidden inside the sandbox
+	Scratch string   // Writable per-execution directory
+	Dir     string   // Working directory of the application
+	Path    string   // Application to run
+	Args    []string // Its arguments, without argv[0]
+}
+
+// configureSandbox turns cmd into a sandbox init process that runs the
+// command it was created for, in the directory already set on cmd. Of the
+// executor's working directory, only the execution's scratch directory is
+// visible and writable. The returned function removes the sandbox root.
+func configureSandbox(cmd *exec.Cmd, sandbox *Sandbox, workDir, execID, scratch string) (func(), error) {
+	spec := sandboxSpec{
+		Root:    filepath.Join(workDir, "."+execID+".root"),
+		WorkDir: workDir,
+		Scratch: scratch,
+		Dir:     cmd.Dir,
+		Path:    cmd.Path,
+		Args:    cmd.Args[1:],
+	}
+	cleanup := func() {
+		os.Remove(spec.Root)
+	}
+	if err := os.Mkdir(spec.Root, 0700); err != nil {
+		return func() {}, fmt.Errorf("failed to create sandbox root: %v", err)
+	}
+
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		cleanup()
+		return func() {}, fmt.Errorf("failed to encode sandbox: %v", err)
+	}
+	cmd.Path = selfExe
+	cmd.Args = []string{sandboxInitName, string(encoded)}
+
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !sandbox.Network {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	return cleanup, nil
+}
+
+// runSandboxInit sets up the sandbox described by encoded and runs the
+// application in it, exiting with its status. It runs as PID 1 of the new
+// PID namespace, so everything else in the sandbox dies when it exits.
+func runSandboxInit(encoded string) {
+	// Keep the privileges set below on the thread that starts the
+	// application, and the status pipe from the application itself
+	runtime.LockOSThread()
+	syscall.CloseOnExec(statusFD)
+
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(encoded), &spec); err != nil {
+		helperFail("sandbox: invalid sandbox: %v", err)
+	}
+	if err := buildRoot(spec); err != nil {
+		helperFail("sandbox: %v", err)
+	}
+	if err := os.Chdir(spec.Dir); err != nil {
+		helperFail("sandbox: failed to enter working directory: %v", err)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		helperFail("sandbox: failed to drop privileges: %v", errno)
+	}
+
+	// The application is in our process group, so signals sent to the
+	// group reach it directly; we only need to survive them
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+	// A nested user namespace locks the mounts, so that the application
+	// cannot make them writable again or uncover what they hide
+	cmd := exec.Command(spec.Path, spec.Args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), "HOME="+spec.Scratch, "TMPDIR="+spec.Scratch)
+	if os.Getenv(ProgressFDEnv) != "" {
+		cmd.ExtraFiles = []*os.File{os.NewFile(progressFD, "progress")}
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:                 syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: 0, Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: 0, Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+	if err := cmd.Start(); err != nil {
+		helperFail("sandbox: %v", err)
+	}
+
+	// Reap everything reparented to us until the application exits
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, 0, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			helperFail("sandbox: failed to wait for process: %v", err)
+		}
+		if pid != cmd.Process.Pid {
+			continue
+		}
+		if status.Signaled() {
+			os.Exit(128 + int(status.Signal()))
+		}
+		os.Exit(status.ExitStatus())
+	}
+}
+
+// buildRoot makes a read-only copy of the filesystem at spec.Root, with the
+// executor's working directory hidden except for the scratch directory and
+// a /proc for the new PID namespace, and makes it the root
+func buildRoot(spec sandboxSpec) error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %v", err)
+	}
+	if err := syscall.Mount("/", spec.Root, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind root: %v", err)
+	}
+	if err := remountReadOnly(spec.Root); err != nil {
+		return err
+	}
+
+	hidden := filepath.Join(spec.Root, spec.WorkDir)
+	if err := syscall.Mount("tmpfs", hidden, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755,size=64k"); err != nil {
+		return fmt.Errorf("failed to hide working directory: %v", err)
+	}
+	scratch := filepath.Join(spec.Root, spec.Scratch)
+	if err := os.MkdirAll(scratch, 0755); err != nil {
+		return fmt.Errorf("failed to create scratch mount point: %v", err)
+	}
+	if err := syscall.Mount(spec.Scratch, scratch, "", syscall.MS_BIND|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("failed to bind scratch directory: %v", err)
+	}
+	if err := syscall.Mount("", hidden, "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("failed to make working directory read-only: %v", err)
+	}
+	if err := syscall.Mount("proc", filepath.Join(spec.Root, "proc"), "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("failed to mount /proc: %v", err)
+	}
+
+	// Stack the old root on the new one, then detach it
+	if err := os.Chdir(spec.Root); err != nil {
+		return fmt.Errorf("failed to enter sandbox root: %v", err)
+	}
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("failed to pivot root: %v", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach old root: %v", err)
+	}
+	return os.Chdir("/")
+}
+
+// remountReadOnly makes root and every mount beneath it read-only, keeping
+// the flags that the user namespace does not allow us to clear
+func remountReadOnly(root string) error {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return fmt.Errorf("failed to list mounts: %v", err)
+	}
+	defer f.Close()
+
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		// Mount points escape spaces and other characters as octal
+		point := unescapeMountPoint(fields[4])
+		if point == root || strings.HasPrefix(point, root+"/") {
+			mounts = append(mounts, point)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to list mounts: %v", err)
+	}
+
+	for _, point := range mounts {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(point, &st); err != nil {
+			continue // Hidden by a later mount
+		}
+		const kept = syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_NOATIME |
+			syscall.MS_NODIRATIME | syscall.MS_RELATIME
+		flags := uintptr(st.Flags)&kept | syscall.MS_REMOUNT | syscall.MS_BIND | syscall.MS_RDONLY
+		if err := syscall.Mount("", point, "", flags, ""); err != nil {
+			return fmt.Errorf("failed to make %s read-only: %v", strings.TrimPrefix(point, root), err)
+		}
+	}
+	return nil
+}
+
+// unescapeMountPoint decodes the octal escapes in a mountinfo path
+func unescapeMountPoint(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			var c byte
+			if _, err := fmt.Sscanf(s[i+1:i+4], "%03o", &c); err == nil {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
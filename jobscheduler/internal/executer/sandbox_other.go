@@ -0,0 +1,13 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// configureSandbox always fails, as sandboxes need Linux namespaces
+func configureSandbox(cmd *exec.Cmd, sandbox *Sandbox, workDir, execID, scratch string) (func(), error) {
+	return func() {}, fmt.Errorf("sandboxing is not supported on this platform")
+}
@@ -0,0 +1,85 @@
+package jobscheduler
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on Linux")
+	}
+	scheduler := newTestScheduler(t)
+
+	limitedJob := func(id, script string, limits ResourceLimits) JobPayload {
+		job := shellJob(id, "limits-channel", script)
+		job.Application.Limits = limits
+		return job
+	}
+
+	t.Run("OpenFiles", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(limitedJob("open-files", "ulimit -n", ResourceLimits{MaxOpenFiles: 64})))
+
+		waitForStatus(t, scheduler, "open-files", JobStatusComplete)
+		result, err := scheduler.GetJobResult("open-files")
+		require.NoError(t, err)
+		assert.Equal(t, "64\n", result.Output)
+	})
+
+	t.Run("MemoryWithoutCgroup", func(t *testing.T) {
+		job := limitedJob("memory", "ulimit -v", ResourceLimits{MemoryMax: 512 << 20})
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		waitForStatus(t, scheduler, "memory", JobStatusComplete)
+		result, err := scheduler.GetJobResult("memory")
+		require.NoError(t, err)
+		assert.Equal(t, "524288\n", result.Output)
+	})
+
+	t.Run("FileSize", func(t *testing.T) {
+		job := limitedJob("file-size", "head -c 10000 /dev/zero > big.out", ResourceLimits{MaxFileSize: 1024})
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		waitForStatus(t, scheduler, "file-size", JobStatusFailed)
+		result, err := scheduler.GetJobResult("file-size")
+		require.NoError(t, err)
+		assert.Equal(t, FailureFileSizeLimit, result.FailureReason)
+		assert.Contains(t, result.Error, string(FailureFileSizeLimit))
+	})
+
+	t.Run("OrdinaryFailure", func(t *testing.T) {
+		job := limitedJob("plain-failure", "exit 2", ResourceLimits{MaxFileSize: 1024})
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		waitForStatus(t, scheduler, "plain-failure", JobStatusFailed)
+		result, err := scheduler.GetJobResult("plain-failure")
+		require.NoError(t, err)
+		assert.Empty(t, result.FailureReason)
+	})
+
+	t.Run("CPUQuotaNeedsCgroup", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(limitedJob("cpu-quota", "true", ResourceLimits{CPUQuota: 0.5})))
+
+		waitForStatus(t, scheduler, "cpu-quota", JobStatusFailed)
+		job, err := scheduler.GetJobStatus("cpu-quota")
+		require.NoError(t, err)
+		assert.Contains(t, job.Error, "cgroup")
+	})
+
+	t.Run("ProcessesNeedCgroup", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(limitedJob("processes", "true", ResourceLimits{MaxProcesses: 32})))
+
+		waitForStatus(t, scheduler, "processes", JobStatusFailed)
+		job, err := scheduler.GetJobStatus("processes")
+		require.NoError(t, err)
+		assert.Contains(t, job.Error, "cgroup")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		err := scheduler.SubmitJob(limitedJob("negative", "true", ResourceLimits{MemoryMax: -1}))
+		assert.Error(t, err)
+	})
+}
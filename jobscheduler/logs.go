@@ -0,0 +1,321 @@
+package jobscheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxLogLineLength is the longest line kept in a job's log. Longer lines
+// are split.
+const maxLogLineLength = 16 * 1024
+
+// Defaults for Config.LogLines and Config.LogRetention
+const (
+	defaultLogLines     = 1000
+	defaultLogRetention = time.Hour
+)
+
+// LogStream names the output stream a log line was written to
+type LogStream string
+
+const (
+	LogStdout LogStream = "stdout"
+	LogStderr LogStream = "stderr"
+)
+
+// LogLine is a line of a job's output
+type LogLine struct {
+	Seq    int64     `json:"seq"` // Position in the job's log, counting from 1
+	Time   time.Time `json:"time"`
+	Stream LogStream `json:"stream"`
+	Text   string    `json:"text"`
+}
+
+// logLines returns how many lines of each job's output are kept
+func (c Config) logLines() int {
+	if c.LogLines > 0 {
+		return c.LogLines
+	}
+	return defaultLogLines
+}
+
+// logRetention returns how long a finished job's log is kept
+func (c Config) logRetention() time.Duration {
+	if c.LogRetention > 0 {
+		return c.LogRetention
+	}
+	return defaultLogRetention
+}
+
+// jobLog keeps the most recent lines of a job's output, across all of its
+// attempts, and wakes anyone following it when it changes
+type jobLog struct {
+	mu       sync.Mutex
+	lines    []LogLine // Ring buffer, oldest line at head once it is full
+	head     int
+	max      int
+	next     int64         // Seq of the next line
+	changed  chan struct{} // Closed and replaced whenever a line is added
+	closed   bool          // Set once the job has finished
+	closedAt time.Time
+}
+
+func newJobLog(max int) *jobLog {
+	return &jobLog{max: max, next: 1, changed: make(chan struct{})}
+}
+
+// append adds a line, dropping the oldest if the log is full
+func (l *jobLog) append(stream LogStream, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line := LogLine{Seq: l.next, Time: time.Now(), Stream: stream, Text: text}
+	l.next++
+	if len(l.lines) < l.max {
+		l.lines = append(l.lines, line)
+	} else {
+		l.lines[l.head] = line
+		l.head = (l.head + 1) % l.max
+	}
+	if !l.closed {
+		close(l.changed)
+		l.changed = make(chan struct{})
+	}
+}
+
+// reopen lets a finished job's log grow again when the job runs again
+func (l *jobLog) reopen() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		l.closed = false
+		l.changed = make(chan struct{})
+	}
+}
+
+// close marks the log as complete, waking its followers
+func (l *jobLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+	l.closed = true
+	l.closedAt = time.Now()
+	close(l.changed)
+}
+
+// since returns the lines after seq that are still kept, whether the log
+// is complete, and a channel that is closed when it next changes
+func (l *jobLog) since(seq int64) ([]LogLine, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var lines []LogLine
+	for i := range l.lines {
+		line := l.lines[(l.head+i)%len(l.lines)]
+		if line.Seq > seq {
+			lines = append(lines, line)
+		}
+	}
+	return lines, l.closed, l.changed
+}
+
+// writer returns a writer that adds what is written to the log a line at
+// a time. Closing it adds any unterminated last line.
+func (l *jobLog) writer(stream LogStream) *logWriter {
+	return &logWriter{log: l, stream: stream}
+}
+
+// logWriter splits a stream of output into log lines
+type logWriter struct {
+	mu     sync.Mutex
+	log    *jobLog
+	stream LogStream
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, b := range p {
+		if b == '\n' {
+			w.flushLocked()
+			continue
+		}
+		w.buf = append(w.buf, b)
+		if len(w.buf) == maxLogLineLength {
+			w.flushLocked()
+		}
+	}
+	return len(p), nil
+}
+
+// Close adds any unterminated last line
+func (w *logWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.flushLocked()
+	}
+	return nil
+}
+
+// flushLocked adds the buffered line. The caller must hold w.mu.
+func (w *logWriter) flushLocked() {
+	w.log.append(w.stream, string(w.buf))
+	w.buf = w.buf[:0]
+}
+
+// logStore holds the logs of running jobs, and of finished jobs until
+// their retention expires
+type logStore struct {
+	mu        sync.Mutex
+	logs      map[string]*jobLog
+	maxLines  int
+	retention time.Duration
+}
+
+func newLogStore(maxLines int, retention time.Duration) *logStore {
+	return &logStore{
+		logs:      make(map[string]*jobLog),
+		maxLines:  maxLines,
+		retention: retention,
+	}
+}
+
+// open returns a job's log, creating it if it has none or reopening it if
+// the job has run before. Expired logs are dropped along the way.
+func (s *logStore) open(jobID string) *jobLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.retention)
+	for id, l := range s.logs {
+		l.mu.Lock()
+		expired := l.closed && l.closedAt.Before(cutoff)
+		l.mu.Unlock()
+		if expired && id != jobID {
+			delete(s.logs, id)
+		}
+	}
+
+	l, exists := s.logs[jobID]
+	if !exists {
+		l = newJobLog(s.maxLines)
+		s.logs[jobID] = l
+	}
+	l.reopen()
+	return l
+}
+
+// get returns a job's log, if it has one
+func (s *logStore) get(jobID string) (*jobLog, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, exists := s.logs[jobID]
+	return l, exists
+}
+
+// close marks a finished job's log as complete
+func (s *logStore) close(jobID string) {
+	if l, exists := s.get(jobID); exists {
+		l.close()
+	}
+}
+
+// remove drops a job's log
+func (s *logStore) remove(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, exists := s.logs[jobID]; exists {
+		l.close()
+		delete(s.logs, jobID)
+	}
+}
+
+// jobLog returns the log of a job, which is empty if the job has not
+// written any output yet
+func (s *Scheduler) jobLog(jobID string) (*jobLog, error) {
+	job, exists := s.registry.get(jobID)
+	if !exists {
+		return nil, fmt.Errorf("job %s: %w", jobID, ErrJobNotFound)
+	}
+	if l, exists := s.logs.get(jobID); exists {
+		return l, nil
+	}
+	if job.Status.IsTerminal() {
+		// It never wrote anything, or its log has expired
+		l := newJobLog(0)
+		l.close()
+		return l, nil
+	}
+
+	// Wait for its output in a log of its own. The job may have finished
+	// in the meantime, in which case nothing else will close it.
+	l := s.logs.open(jobID)
+	if job, exists := s.registry.get(jobID); !exists || job.Status.IsTerminal() {
+		l.close()
+	}
+	return l, nil
+}
+
+// JobLogs returns the lines of a job's output after seq that are still
+// kept, and whether the job has finished writing them. Only the most
+// recent Config.LogLines lines of a job are kept, so seq may skip ahead.
+func (s *Scheduler) JobLogs(jobID string, seq int64) ([]LogLine, bool, error) {
+	l, err := s.jobLog(jobID)
+	if err != nil {
+		return nil, false, err
+	}
+	lines, done, _ := l.since(seq)
+	return lines, done, nil
+}
+
+// FollowJobLogs streams the lines of a job's output after seq as they are
+// written. The channel is closed once the job has finished, or when ctx is
+// done or the scheduler shuts down. A follower that falls behind by more
+// than Config.LogLines lines misses the ones in between.
+func (s *Scheduler) FollowJobLogs(ctx context.Context, jobID string, seq int64) (<-chan LogLine, error) {
+	l, err := s.jobLog(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan LogLine)
+	go func() {
+		defer close(out)
+		for {
+			lines, done, changed := l.since(seq)
+			for _, line := range lines {
+				select {
+				case out <- line:
+					seq = line.Seq
+				case <-ctx.Done():
+					return
+				case <-s.ctx.Done():
+					return
+				}
+			}
+			if done {
+				return
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,136 @@
+package jobscheduler
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobLogs(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+	cfg.WorkDir = tmpDir
+	cfg.LogLines = 3
+
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	defer scheduler.Shutdown()
+
+	texts := func(lines []LogLine) []string {
+		var texts []string
+		for _, line := range lines {
+			texts = append(texts, string(line.Stream)+":"+line.Text)
+		}
+		return texts
+	}
+
+	t.Run("Follow", func(t *testing.T) {
+		// Hold the job in the queue until the follower is waiting
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "blocker",
+			Channel:     "logs-channel",
+			Application: &ApplicationConfig{Name: "sleep", Path: "sleep", Args: []string{"0.2"}},
+		}))
+		require.NoError(t, scheduler.SubmitJob(shellJob("follow", "logs-channel", "echo one; sleep 0.1; echo two >&2; printf three")))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		lines, err := scheduler.FollowJobLogs(ctx, "follow", 0)
+		require.NoError(t, err)
+
+		var followed []LogLine
+		for line := range lines {
+			followed = append(followed, line)
+		}
+		require.NoError(t, ctx.Err(), "log was never closed")
+		assert.Equal(t, []string{"stdout:one", "stderr:two", "stdout:three"}, texts(followed))
+		assert.Equal(t, []int64{1, 2, 3}, []int64{followed[0].Seq, followed[1].Seq, followed[2].Seq})
+
+		// The log is kept once the job has finished
+		kept, done, err := scheduler.JobLogs("follow", 1)
+		require.NoError(t, err)
+		assert.True(t, done)
+		assert.Equal(t, []string{"stderr:two", "stdout:three"}, texts(kept))
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(shellJob("bounded", "logs-channel", "for i in 1 2 3 4 5; do echo $i; done")))
+		waitForStatus(t, scheduler, "bounded", JobStatusComplete)
+
+		lines, _, err := scheduler.JobLogs("bounded", 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"stdout:3", "stdout:4", "stdout:5"}, texts(lines))
+		assert.Equal(t, int64(3), lines[0].Seq)
+	})
+
+	t.Run("LongLines", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(shellJob("long", "logs-channel", "head -c 20000 /dev/zero | tr '\\0' x")))
+		waitForStatus(t, scheduler, "long", JobStatusComplete)
+
+		lines, _, err := scheduler.JobLogs("long", 0)
+		require.NoError(t, err)
+		require.Len(t, lines, 2)
+		assert.Len(t, lines[0].Text, maxLogLineLength)
+		assert.Equal(t, strings.Repeat("x", 20000-maxLogLineLength), lines[1].Text)
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		require.NoError(t, scheduler.RegisterHandler("logged", func(ctx context.Context, job JobPayload) (JobResult, error) {
+			return JobResult{Output: "handled\n"}, nil
+		}))
+		require.NoError(t, scheduler.SubmitJob(JobPayload{ID: "handler", Channel: "logs-channel", Type: "logged"}))
+		waitForStatus(t, scheduler, "handler", JobStatusComplete)
+
+		lines, done, err := scheduler.JobLogs("handler", 0)
+		require.NoError(t, err)
+		assert.True(t, done)
+		assert.Equal(t, []string{"stdout:handled"}, texts(lines))
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "cancelled",
+			Channel:     "logs-channel",
+			Application: &ApplicationConfig{Name: "sleep", Path: "sleep", Args: []string{"10"}},
+			Delay:       time.Hour,
+		}))
+		lines, err := scheduler.FollowJobLogs(context.Background(), "cancelled", 0)
+		require.NoError(t, err)
+		require.NoError(t, scheduler.CancelJob("cancelled"))
+
+		select {
+		case _, open := <-lines:
+			assert.False(t, open)
+		case <-time.After(5 * time.Second):
+			t.Fatal("log of cancelled job was never closed")
+		}
+	})
+
+	t.Run("UnknownJob", func(t *testing.T) {
+		_, _, err := scheduler.JobLogs("missing", 0)
+		assert.ErrorIs(t, err, ErrJobNotFound)
+		_, err = scheduler.FollowJobLogs(context.Background(), "missing", 0)
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+}
+
+func TestLogRetention(t *testing.T) {
+	store := newLogStore(10, time.Millisecond)
+	store.open("finished").append(LogStdout, "done")
+	store.close("finished")
+	store.open("running")
+
+	time.Sleep(5 * time.Millisecond)
+	store.open("next")
+
+	_, exists := store.get("finished")
+	assert.False(t, exists)
+	_, exists = store.get("running")
+	assert.True(t, exists)
+}
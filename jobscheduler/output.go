@@ -0,0 +1,92 @@
+package jobscheduler
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrOutputNotFound is returned when the whole output of a job that
+// outgrew Config.MaxOutputSize has expired
+var ErrOutputNotFound = errors.New("output not found")
+
+// outputDir returns the directory the whole output of jobs is kept in
+func (c Config) outputDir() string {
+	if c.OutputDir != "" {
+		return c.OutputDir
+	}
+	return filepath.Join(c.WorkDir, "output")
+}
+
+// outputFile returns the file the whole of one of a job's output streams
+// is written to, gzipped
+func outputFile(dir, jobID string, stream LogStream) string {
+	return filepath.Join(artifactPath(dir, jobID), string(stream)+".gz")
+}
+
+// gzipFile reads a gzipped file, closing both when it is closed
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// OpenOutput opens the whole of a finished job's stdout or stderr for
+// reading. When the output outgrew Config.MaxOutputSize, it comes from the
+// file it was written to, which may since have expired.
+func (s *Scheduler) OpenOutput(jobID string, stream LogStream) (io.ReadCloser, error) {
+	result, err := s.registry.result(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return openOutput(s.config.outputDir(), result, stream)
+}
+
+// openOutput opens the whole of one of a result's output streams
+func openOutput(dir string, result JobResult, stream LogStream) (io.ReadCloser, error) {
+	var kept string
+	var omitted int64
+	switch stream {
+	case LogStdout:
+		kept, omitted = result.Output, result.OutputOmitted
+	case LogStderr:
+		kept, omitted = result.Stderr, result.StderrOmitted
+	default:
+		return nil, fmt.Errorf("unknown output stream: %s", stream)
+	}
+	if omitted == 0 {
+		return io.NopCloser(strings.NewReader(kept)), nil
+	}
+
+	f, err := os.Open(outputFile(dir, result.JobID, stream))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s of job %s has expired: %w", stream, result.JobID, ErrOutputNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read %s of job %s: %v", stream, result.JobID, err)
+	}
+	return gzipFile{Reader: gz, file: f}, nil
+}
+
+// removeOutput deletes the files a job's output was written to
+func (s *Scheduler) removeOutput(jobID string) {
+	os.RemoveAll(artifactPath(s.config.outputDir(), jobID))
+}
+
+// sweepOutput removes the output files of jobs that finished before cutoff
+func (s *Scheduler) sweepOutput(cutoff time.Time) {
+	sweepDir(s.config.outputDir(), cutoff)
+}
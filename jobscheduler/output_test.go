@@ -0,0 +1,93 @@
+package jobscheduler
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLargeOutput(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	run := func(t *testing.T, id, script string, inputs ...JobInput) *JobResult {
+		t.Helper()
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          id,
+			Channel:     "output-channel",
+			Application: &ApplicationConfig{Name: "sh", Path: "sh", Args: []string{"-c", script}, Inputs: inputs},
+		}))
+		waitForStatus(t, scheduler, id, JobStatusComplete)
+		result, err := scheduler.GetJobResult(id)
+		require.NoError(t, err)
+		return result
+	}
+
+	readOutput := func(t *testing.T, jobID string, stream LogStream) string {
+		t.Helper()
+		output, err := scheduler.OpenOutput(jobID, stream)
+		require.NoError(t, err)
+		defer output.Close()
+		data, err := io.ReadAll(output)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	var expected strings.Builder
+	for i := 1; i <= 20000; i++ {
+		fmt.Fprintf(&expected, "%d\n", i)
+	}
+
+	t.Run("HeadAndTail", func(t *testing.T) {
+		result := run(t, "large", "seq 1 20000; echo small >&2")
+		assert.LessOrEqual(t, len(result.Output), 1024)
+		assert.True(t, strings.HasPrefix(result.Output, "1\n2\n3\n"), result.Output)
+		assert.True(t, strings.HasSuffix(result.Output, "19999\n20000\n"), result.Output)
+		marker := fmt.Sprintf("\n... %d bytes omitted ...\n", result.OutputOmitted)
+		assert.Contains(t, result.Output, marker)
+		assert.Equal(t, int64(expected.Len()-len(result.Output)+len(marker)), result.OutputOmitted)
+
+		// The whole of it is kept on disk, and small output only in memory
+		assert.Equal(t, expected.String(), readOutput(t, "large", LogStdout))
+		assert.Equal(t, "small\n", readOutput(t, "large", LogStderr))
+		assert.NoFileExists(t, outputFile(scheduler.config.outputDir(), "large", LogStderr))
+	})
+
+	t.Run("AsInput", func(t *testing.T) {
+		result := run(t, "line-count", "wc -l < lines.txt", JobInput{JobID: "large", File: "lines.txt"})
+		assert.Equal(t, "20000", strings.TrimSpace(result.Output))
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		scheduler.sweepOutput(time.Now().Add(time.Hour))
+		_, err := scheduler.OpenOutput("large", LogStdout)
+		assert.ErrorIs(t, err, ErrOutputNotFound)
+
+		// Output that was kept whole in memory does not expire
+		assert.Equal(t, "small\n", readOutput(t, "large", LogStderr))
+	})
+
+	t.Run("UnknownJob", func(t *testing.T) {
+		_, err := scheduler.OpenOutput("missing", LogStdout)
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+
+	t.Run("PurgedWithJob", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "large-failure",
+			Channel:     "output-channel",
+			Application: &ApplicationConfig{Name: "sh", Path: "sh", Args: []string{"-c", "seq 1 20000; exit 1"}},
+		}))
+		waitForStatus(t, scheduler, "large-failure", JobStatusFailed)
+		dir := filepath.Dir(outputFile(scheduler.config.outputDir(), "large-failure", LogStdout))
+		assert.DirExists(t, dir)
+
+		require.NoError(t, scheduler.PurgeDeadLetter("large-failure"))
+		assert.NoDirExists(t, dir)
+	})
+}
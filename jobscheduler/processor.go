@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -16,135 +18,356 @@ import (
 type ProcessorConfig struct {
 	Channel       *Channel
 	Executor      *executor.Executor
-	ProcessLog    *os.File
 	MaxOutputSize int64
+	Registry      *jobRegistry
+	Handlers      *handlerRegistry
+	Applications  *applicationRegistry
+	Logs          *logStore
+
+	// Where scratch directories are removed, and artifacts and the whole
+	// of large output are kept
+	WorkDirCleanup WorkDirCleanup
+	ArtifactDir    string
+	OutputDir      string
+
+	// Sandbox applications of untrusted jobs even if they do not ask for
+	// it, and whether a sandbox forced on a job may share the network
+	SandboxUntrusted bool
+	SandboxNetwork   bool
+
+	// Retry is offered every unsuccessful attempt before it is finalised,
+	// and returns true if it scheduled the job to run again
+	Retry func(job JobPayload, result JobResult) bool
+
+	// Finished is called once a job has reached its final status
+	Finished func(job JobPayload)
+
+	// Record is called with every event in the processing of a job
+	Record func(event JobEvent)
 }
 
 // Processor handles the processing of jobs for a specific channel
 type Processor struct {
 	config     ProcessorConfig
-	workerPool chan struct{}
 	activeJobs sync.Map
+	wg         sync.WaitGroup
 }
 
 // NewProcessor creates a new processor instance
 func NewProcessor(cfg ProcessorConfig) *Processor {
 	return &Processor{
-		config:     cfg,
-		workerPool: make(chan struct{}, cfg.Channel.Workers),
+		config: cfg,
 	}
 }
 
 // Start begins processing jobs from the channel
 func (p *Processor) Start(ctx context.Context) {
 	log.Printf("Starting processor for channel: %s with %d workers",
-		p.config.Channel.Name, p.config.Channel.Workers)
+		p.config.Channel.Name, p.config.Channel.settings().Workers)
 
 	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("Stopping processor for channel: %s", p.config.Channel.Name)
+		// Wait for available worker before choosing a job, so that the
+		// highest priority job at that moment is the one that runs
+		workers := p.config.Channel.workers
+		if !workers.acquire(ctx) {
+			p.stop()
 			return
-		case job := <-p.config.Channel.Jobs:
-			// Wait for available worker
-			p.workerPool <- struct{}{}
-
-			// Process job in goroutine
-			go func(job JobPayload) {
-				defer func() { <-p.workerPool }()
-				p.processJob(ctx, job)
-			}(job)
 		}
+
+		job, ok := p.config.Channel.queue.pop(ctx)
+		if !ok {
+			workers.release()
+			p.stop()
+			return
+		}
+
+		// Process job in goroutine
+		p.wg.Add(1)
+		go func(job JobPayload) {
+			defer p.wg.Done()
+			defer workers.release()
+			p.processJob(ctx, job)
+		}(job)
 	}
 }
 
+// stop waits for in-flight jobs to record their final state
+func (p *Processor) stop() {
+	log.Printf("Stopping processor for channel: %s", p.config.Channel.Name)
+	p.wg.Wait()
+}
+
 // processJob handles the execution of a single job
 func (p *Processor) processJob(ctx context.Context, job JobPayload) {
-	// Store job in active jobs
-	p.activeJobs.Store(job.ID, job)
-	defer p.activeJobs.Delete(job.ID)
-
-	// Create job context with timeout
-	jobCtx, cancel := context.WithTimeout(ctx, p.config.Channel.Timeout)
+	// Create job context with the application's or the channel's timeout
+	timeout := p.config.Channel.settings().Timeout
+	if job.Application != nil && job.Application.Timeout > 0 {
+		timeout = job.Application.Timeout
+	}
+	jobCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	// Claim the job, skipping it if it was cancelled while queued
+	startTime := time.Now()
+	if !p.config.Registry.markRunning(job.ID, startTime, cancel) {
+		job.Status = JobStatusCancelled
+		p.logJobEvent(EventSkipped, job, nil, "cancelled before start")
+		return
+	}
+
 	// Update job status
 	job.Status = JobStatusRunning
-	job.StartTime = time.Now()
+	job.StartTime = startTime
+
+	// Store job in active jobs
+	p.activeJobs.Store(job.ID, job)
+	defer p.activeJobs.Delete(job.ID)
 
 	// Log job start
-	p.logJobEvent(job, "STARTED")
+	p.logJobEvent(EventStarted, job, nil, "")
 
-	var _ *executor.ExecutionResult
+	var execResult *executor.ExecutionResult
 	var err error
 
 	if job.Application != nil {
 		// Execute external application
-		_, err = p.executeApplication(jobCtx, job)
+		execResult, err = p.executeApplication(jobCtx, job)
 	} else {
-		// Process regular job
-		_, err = p.processRegularJob(jobCtx, job)
+		// Run the registered in-process handler
+		execResult, err = p.processHandlerJob(jobCtx, job)
 	}
 
 	// Update job status based on result
 	job.EndTime = time.Now()
-	if err != nil {
-		if err == context.DeadlineExceeded {
-			job.Status = JobStatusTimedOut
-			job.Error = fmt.Sprintf("job timed out after %v", p.config.Channel.Timeout)
-		} else {
-			job.Status = JobStatusFailed
-			job.Error = err.Error()
-		}
-	} else {
+	switch {
+	case err == nil:
 		job.Status = JobStatusComplete
+		job.Error = ""
+	case jobCtx.Err() == context.DeadlineExceeded:
+		job.Status = JobStatusTimedOut
+		job.Error = fmt.Sprintf("job timed out after %v", timeout)
+	case ctx.Err() != nil:
+		job.Status = JobStatusInterrupted
+		job.Error = "interrupted by scheduler shutdown"
+	default:
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
 	}
 
+	// Keep the exit code and captured output alongside the final state
+	result := JobResult{
+		JobID:     job.ID,
+		Status:    job.Status,
+		Error:     job.Error,
+		StartTime: job.StartTime,
+		EndTime:   job.EndTime,
+	}
+	if execResult != nil {
+		result.ExitCode = execResult.ExitCode
+		result.Output = execResult.Stdout
+		result.Stderr = execResult.Stderr
+		result.OutputOmitted = execResult.StdoutOmitted
+		result.StderrOmitted = execResult.StderrOmitted
+		result.Termination = Termination(execResult.Termination)
+		result.Survivors = execResult.Survivors
+		result.FailureReason = FailureReason(execResult.FailureReason)
+		result.WorkDir = execResult.WorkDir
+		for _, artifact := range execResult.Artifacts {
+			result.Artifacts = append(result.Artifacts, Artifact(artifact))
+		}
+		if len(result.Survivors) > 0 {
+			p.logJobEvent(EventSurvivors, job, &result, fmt.Sprintf("processes survived termination: %v", result.Survivors))
+		}
+	} else if err != nil {
+		result.ExitCode = -1
+	}
+
+	// Give unsuccessful attempts a chance to run again
+	if job.Status != JobStatusComplete && p.config.Retry != nil && p.config.Retry(job, result) {
+		p.logJobEvent(EventRetrying, job, &result, "")
+		return
+	}
+
+	// Record the final state, which becomes cancelled if CancelJob stopped it
+	job = p.config.Registry.finish(job, result)
+
 	// Log job completion
-	p.logJobEvent(job, fmt.Sprintf("COMPLETED - Status: %s", job.Status))
+	p.logJobEvent(EventFinished, job, &result, "")
+
+	if p.config.Finished != nil {
+		p.config.Finished(job)
+	}
 }
 
 // executeApplication handles execution of external applications
 func (p *Processor) executeApplication(ctx context.Context, job JobPayload) (*executor.ExecutionResult, error) {
+	// Settle what actually runs, which a registered application decides
+	app, err := p.config.Applications.resolve(*job.Application)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create executor config
 	cfg := executor.Config{
-		Path:        job.Application.Path,
-		Args:        job.Application.Args,
-		WorkingDir:  job.Application.WorkingDir,
-		Env:         job.Application.Env,
+		Path:        app.Path,
+		Args:        app.Args,
+		WorkingDir:  app.WorkingDir,
+		Env:         app.Env,
 		OutputLimit: p.config.MaxOutputSize,
+		KillTimeout: p.config.Channel.settings().KillTimeout,
+		Limits:      executor.Limits(app.Limits),
+		Cleanup:     executor.CleanupPolicy(p.config.WorkDirCleanup),
+	}
+	if app.KillTimeout > 0 {
+		cfg.KillTimeout = app.KillTimeout
+	}
+
+	// Isolate the application if it has to be, or if it asks for it. A job
+	// cannot loosen a sandbox forced on it unless the operator allows it.
+	forced := p.config.Channel.settings().Sandbox || (job.Untrusted && p.config.SandboxUntrusted)
+	switch {
+	case forced:
+		network := app.Sandbox != nil && app.Sandbox.Network && p.config.SandboxNetwork
+		cfg.Sandbox = &executor.Sandbox{Network: network}
+	case app.Sandbox != nil:
+		cfg.Sandbox = &executor.Sandbox{Network: app.Sandbox.Network}
 	}
 
 	// Set up stdin if payload should be passed
-	if job.Application.PassPayload {
+	if app.PassPayload {
 		cfg.Stdin = bytes.NewReader(job.Body)
 	}
 
+	// Feed in the output of upstream jobs, streamed from wherever it is kept
+	for _, in := range app.Inputs {
+		input, err := p.openInput(in)
+		if err != nil {
+			return nil, err
+		}
+		defer input.Close()
+		if in.File == "" {
+			cfg.Stdin = input
+			continue
+		}
+		if cfg.Files == nil {
+			cfg.Files = make(map[string]io.Reader)
+		}
+		cfg.Files[in.File] = input
+	}
+
+	// Keep the artifacts of this attempt only
+	if len(app.Artifacts) > 0 {
+		cfg.Artifacts = app.Artifacts
+		cfg.ArtifactDir = artifactPath(p.config.ArtifactDir, job.ID)
+		if err := os.RemoveAll(cfg.ArtifactDir); err != nil {
+			return nil, fmt.Errorf("failed to remove earlier artifacts: %v", err)
+		}
+	}
+
+	// Write the whole of large output into the job's output directory,
+	// replacing the previous attempt's
+	outputDir := artifactPath(p.config.OutputDir, job.ID)
+	if err := os.RemoveAll(outputDir); err != nil {
+		return nil, fmt.Errorf("failed to remove earlier output: %v", err)
+	}
+	cfg.StdoutFile = outputFile(p.config.OutputDir, job.ID, LogStdout)
+	cfg.StderrFile = outputFile(p.config.OutputDir, job.ID, LogStderr)
+
+	// Keep the latest progress it reports
+	cfg.Progress = func(progress executor.Progress) {
+		p.config.Registry.setProgress(job.ID, Progress{
+			Percent:   progress.Percent,
+			Message:   progress.Message,
+			UpdatedAt: time.Now(),
+		})
+	}
+
+	// Stream its output into the job's log as it is written
+	jobLog := p.config.Logs.open(job.ID)
+	stdout, stderr := jobLog.writer(LogStdout), jobLog.writer(LogStderr)
+	defer stdout.Close()
+	defer stderr.Close()
+	cfg.Stdout, cfg.Stderr = stdout, stderr
+
 	// Execute the application
 	return p.config.Executor.Execute(ctx, cfg)
 }
 
-// processRegularJob handles jobs that don't require external application execution
-func (p *Processor) processRegularJob(ctx context.Context, job JobPayload) (*executor.ExecutionResult, error) {
-	// Simulate processing for regular jobs
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-time.After(100 * time.Millisecond):
-		// Create a dummy result for regular jobs
-		return &executor.ExecutionResult{
-			ExitCode:  0,
-			StartTime: job.StartTime,
-			EndTime:   time.Now(),
-		}, nil
+// openInput opens the output an input refers to, all of it even if only
+// its head and tail were kept in memory
+func (p *Processor) openInput(in JobInput) (io.ReadCloser, error) {
+	result, err := p.config.Registry.result(in.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input from job %s: %v", in.JobID, err)
+	}
+	stream := LogStdout
+	switch in.Source {
+	case InputStderr:
+		stream = LogStderr
+	case InputArtifact:
+		for _, artifact := range result.Artifacts {
+			if artifact.Name == in.Artifact {
+				path := filepath.Join(artifactPath(p.config.ArtifactDir, in.JobID), filepath.FromSlash(artifact.Name))
+				f, err := os.Open(path)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read artifact %s of job %s: %v", in.Artifact, in.JobID, err)
+				}
+				return f, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to read input from job %s: artifact %s: %w", in.JobID, in.Artifact, ErrArtifactNotFound)
+	}
+
+	output, err := openOutput(p.config.OutputDir, result, stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input from job %s: %w", in.JobID, err)
 	}
+	return output, nil
 }
 
-// logJobEvent logs a job event to the process log
-func (p *Processor) logJobEvent(job JobPayload, event string) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
-	fmt.Fprintf(p.config.ProcessLog, "%s - %s - Channel: %s, JobID: %s\n",
-		timestamp, event, p.config.Channel.Name, job.ID)
+// processHandlerJob handles jobs without an application by running the
+// handler registered for their type or channel
+func (p *Processor) processHandlerJob(ctx context.Context, job JobPayload) (*executor.ExecutionResult, error) {
+	handler, exists := p.config.Handlers.lookup(job)
+	if !exists {
+		if job.Type != "" {
+			return nil, fmt.Errorf("job type %s: %w", job.Type, ErrNoHandler)
+		}
+		return nil, fmt.Errorf("channel %s: %w", job.Channel, ErrNoHandler)
+	}
+
+	result, err := runHandler(ctx, handler, job)
+	if err != nil && result.ExitCode == 0 {
+		result.ExitCode = -1
+	}
+
+	// Handlers return their output all at once, so it is logged at the end
+	jobLog := p.config.Logs.open(job.ID)
+	stdout, stderr := jobLog.writer(LogStdout), jobLog.writer(LogStderr)
+	io.WriteString(stdout, result.Output)
+	io.WriteString(stderr, result.Stderr)
+	stdout.Close()
+	stderr.Close()
+	return &executor.ExecutionResult{
+		ExitCode:  result.ExitCode,
+		Stdout:    result.Output,
+		Stderr:    result.Stderr,
+		StartTime: job.StartTime,
+		EndTime:   time.Now(),
+	}, err
+}
+
+// logJobEvent records an event in a job's processing, along with the
+// outcome of the attempt once it has ended
+func (p *Processor) logJobEvent(event EventType, job JobPayload, result *JobResult, message string) {
+	entry := newJobEvent(event, job)
+	entry.Message = message
+	if result != nil {
+		exitCode := result.ExitCode
+		entry.ExitCode = &exitCode
+		entry.Duration = result.EndTime.Sub(result.StartTime).Seconds()
+	}
+	p.config.Record(entry)
 }
 
 // GetActiveJobs returns a list of currently active jobs
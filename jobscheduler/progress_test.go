@@ -0,0 +1,79 @@
+package jobscheduler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	scheduler := newTestScheduler(t)
+	gate := filepath.Join(t.TempDir(), "continue")
+
+	progressOf := func(t *testing.T, jobID string) *Progress {
+		t.Helper()
+		job, err := scheduler.GetJobStatus(jobID)
+		require.NoError(t, err)
+		return job.Progress
+	}
+
+	t.Run("Reports", func(t *testing.T) {
+		script := `echo "25 Extracting" >&$JOB_PROGRESS_FD
+while [ ! -e ` + gate + ` ]; do sleep 0.01; done
+echo 150 >&3
+echo "Loading" >&3
+echo "not a report"`
+		require.NoError(t, scheduler.SubmitJob(shellJob("reports", "progress-channel", script)))
+
+		require.Eventually(t, func() bool {
+			progress := progressOf(t, "reports")
+			return progress != nil && progress.Percent == 25
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, "Extracting", progressOf(t, "reports").Message)
+		assert.Equal(t, JobStatusRunning, mustStatus(t, scheduler, "reports"))
+
+		// A message alone keeps the last percentage, which is capped at 100
+		require.NoError(t, os.WriteFile(gate, nil, 0644))
+		waitForStatus(t, scheduler, "reports", JobStatusComplete)
+		progress := progressOf(t, "reports")
+		require.NotNil(t, progress)
+		assert.Equal(t, float64(100), progress.Percent)
+		assert.Equal(t, "Loading", progress.Message)
+		assert.False(t, progress.UpdatedAt.IsZero())
+
+		result, err := scheduler.GetJobResult("reports")
+		require.NoError(t, err)
+		assert.Equal(t, "not a report\n", result.Output)
+	})
+
+	t.Run("NoReports", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(shellJob("silent", "progress-channel", "true")))
+		waitForStatus(t, scheduler, "silent", JobStatusComplete)
+		assert.Nil(t, progressOf(t, "silent"))
+	})
+
+	t.Run("Sandboxed", func(t *testing.T) {
+		requireUserNamespaces(t)
+		job := shellJob("sandboxed-progress", "progress-channel", `echo "60 Inside" >&$JOB_PROGRESS_FD`)
+		job.Application.Sandbox = &SandboxConfig{}
+		job.Application.Limits = ResourceLimits{MaxOpenFiles: 64}
+		require.NoError(t, scheduler.SubmitJob(job))
+		waitForStatus(t, scheduler, job.ID, JobStatusComplete)
+
+		progress := progressOf(t, job.ID)
+		require.NotNil(t, progress)
+		assert.Equal(t, Progress{Percent: 60, Message: "Inside", UpdatedAt: progress.UpdatedAt}, *progress)
+	})
+}
+
+// mustStatus returns the current status of a job
+func mustStatus(t *testing.T, scheduler *Scheduler, jobID string) JobStatus {
+	t.Helper()
+	job, err := scheduler.GetJobStatus(jobID)
+	require.NoError(t, err)
+	return job.Status
+}
@@ -0,0 +1,179 @@
+package jobscheduler
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// jobQueue is a bounded priority queue of pending jobs for one channel.
+//
+// Higher priority jobs are dequeued first. So that a steady stream of
+// urgent work cannot starve everything else, each priority level is worth a
+// fixed amount of waiting time, the aging interval: a job ranks as if it had
+// been enqueued priority*aging earlier than it was. A low-priority job that
+// has waited long enough therefore overtakes newly submitted urgent jobs.
+// With no aging interval, ordering is strictly by priority.
+type jobQueue struct {
+	mu       sync.Mutex
+	items    queueHeap
+	index    map[string]*queueItem
+	capacity int
+	aging    time.Duration
+	seq      uint64
+	notify   chan struct{} // Signalled when a job is pushed
+}
+
+// queueItem is a job waiting in a jobQueue
+type queueItem struct {
+	job      JobPayload
+	rank     int64  // Effective enqueue time in nanoseconds, lower runs first
+	priority int    // Breaks rank ties, higher runs first
+	seq      uint64 // Breaks remaining ties in FIFO order
+	index    int    // Position in the heap
+}
+
+// newJobQueue creates an empty queue holding at most capacity jobs
+func newJobQueue(capacity int, aging time.Duration) *jobQueue {
+	return &jobQueue{
+		index:    make(map[string]*queueItem),
+		capacity: capacity,
+		aging:    aging,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// checkRoom fails if the queue is at capacity
+func (q *jobQueue) checkRoom(channel string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.checkRoomLocked(channel)
+}
+
+func (q *jobQueue) checkRoomLocked(channel string) error {
+	if len(q.items) >= q.capacity {
+		return fmt.Errorf("channel %s: %w (%d jobs waiting)", channel, ErrChannelFull, len(q.items))
+	}
+	return nil
+}
+
+// push adds a job to the queue. Unless force is set, it fails when the queue
+// is at capacity; jobs the scheduler has already accepted are forced in.
+func (q *jobQueue) push(job JobPayload, force bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !force {
+		if err := q.checkRoomLocked(job.Channel); err != nil {
+			return err
+		}
+	}
+
+	q.seq++
+	item := &queueItem{
+		job:      job,
+		priority: job.Priority,
+		seq:      q.seq,
+	}
+	if q.aging > 0 {
+		item.rank = time.Now().UnixNano() - int64(job.Priority)*int64(q.aging)
+	}
+	heap.Push(&q.items, item)
+	q.index[job.ID] = item
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pop removes and returns the highest ranked job, waiting until one is
+// available. It returns false if ctx is done first.
+func (q *jobQueue) pop(ctx context.Context) (JobPayload, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			item := heap.Pop(&q.items).(*queueItem)
+			delete(q.index, item.job.ID)
+			q.mu.Unlock()
+			return item.job, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return JobPayload{}, false
+		}
+	}
+}
+
+// remove drops a queued job, reporting whether it was found
+func (q *jobQueue) remove(jobID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, exists := q.index[jobID]
+	if !exists {
+		return false
+	}
+	heap.Remove(&q.items, item.index)
+	delete(q.index, jobID)
+	return true
+}
+
+// setCapacity changes how many jobs the queue accepts. Jobs already queued
+// beyond a smaller capacity stay queued.
+func (q *jobQueue) setCapacity(capacity int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.capacity = capacity
+}
+
+// len returns the number of queued jobs
+func (q *jobQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+// queueHeap implements heap.Interface ordered by rank
+type queueHeap []*queueItem
+
+func (h queueHeap) Len() int { return len(h) }
+
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h queueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *queueHeap) Push(x interface{}) {
+	item := x.(*queueItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *queueHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
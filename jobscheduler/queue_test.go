@@ -0,0 +1,112 @@
+package jobscheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobQueue(t *testing.T) {
+	popIDs := func(t *testing.T, q *jobQueue, n int) []string {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		var ids []string
+		for i := 0; i < n; i++ {
+			job, ok := q.pop(ctx)
+			require.True(t, ok)
+			ids = append(ids, job.ID)
+		}
+		return ids
+	}
+
+	t.Run("StrictPriority", func(t *testing.T) {
+		q := newJobQueue(10, 0)
+		require.NoError(t, q.push(JobPayload{ID: "low", Priority: 1}, false))
+		require.NoError(t, q.push(JobPayload{ID: "high", Priority: 9}, false))
+		require.NoError(t, q.push(JobPayload{ID: "low-2", Priority: 1}, false))
+		require.NoError(t, q.push(JobPayload{ID: "mid", Priority: 5}, false))
+
+		assert.Equal(t, []string{"high", "mid", "low", "low-2"}, popIDs(t, q, 4))
+	})
+
+	t.Run("Aging", func(t *testing.T) {
+		q := newJobQueue(10, time.Millisecond)
+		require.NoError(t, q.push(JobPayload{ID: "old-low", Priority: 0}, false))
+		time.Sleep(20 * time.Millisecond)
+		require.NoError(t, q.push(JobPayload{ID: "new-high", Priority: 10}, false))
+
+		// 20ms of waiting outweighs 10 priority levels at 1ms each
+		assert.Equal(t, []string{"old-low", "new-high"}, popIDs(t, q, 2))
+	})
+
+	t.Run("Capacity", func(t *testing.T) {
+		q := newJobQueue(2, 0)
+		for i := 0; i < 2; i++ {
+			require.NoError(t, q.push(JobPayload{ID: fmt.Sprintf("job-%d", i), Channel: "full"}, false))
+		}
+		assert.Error(t, q.push(JobPayload{ID: "rejected", Channel: "full"}, false))
+		assert.NoError(t, q.push(JobPayload{ID: "forced", Channel: "full"}, true))
+		assert.Equal(t, 3, q.len())
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		q := newJobQueue(10, 0)
+		require.NoError(t, q.push(JobPayload{ID: "a", Priority: 2}, false))
+		require.NoError(t, q.push(JobPayload{ID: "b", Priority: 3}, false))
+		require.NoError(t, q.push(JobPayload{ID: "c", Priority: 1}, false))
+
+		assert.True(t, q.remove("b"))
+		assert.False(t, q.remove("b"))
+		assert.Equal(t, []string{"a", "c"}, popIDs(t, q, 2))
+	})
+
+	t.Run("PopStopsOnCancel", func(t *testing.T) {
+		q := newJobQueue(10, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, ok := q.pop(ctx)
+		assert.False(t, ok)
+	})
+}
+
+func TestJobPriority(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	job := func(id string, priority int, args ...string) JobPayload {
+		return JobPayload{
+			ID:       id,
+			Channel:  "priority-channel",
+			Priority: priority,
+			Application: &ApplicationConfig{
+				Name: "sleep",
+				Path: "sleep",
+				Args: args,
+			},
+		}
+	}
+
+	// Hold the only worker so the remaining jobs queue up behind it
+	require.NoError(t, scheduler.SubmitJob(job("blocker", 0, "0.5")))
+	waitForStatus(t, scheduler, "blocker", JobStatusRunning)
+
+	require.NoError(t, scheduler.SubmitJob(job("low", 0, "0")))
+	require.NoError(t, scheduler.SubmitJob(job("high", 10, "0")))
+
+	waitForStatus(t, scheduler, "low", JobStatusComplete)
+	waitForStatus(t, scheduler, "high", JobStatusComplete)
+
+	low, err := scheduler.GetJobResult("low")
+	require.NoError(t, err)
+	high, err := scheduler.GetJobResult("high")
+	require.NoError(t, err)
+	assert.True(t, high.StartTime.Before(low.StartTime), "high priority job should start first")
+
+	err = scheduler.SubmitJob(job("too-high", MaxPriority+1, "0"))
+	assert.Error(t, err)
+}
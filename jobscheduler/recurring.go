@@ -0,0 +1,439 @@
+package jobscheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// OverlapPolicy controls what a recurring schedule does when a run falls due
+// while the job from its previous run is still queued or running
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the new run. It is the default.
+	OverlapSkip OverlapPolicy = "skip"
+
+	// OverlapQueue holds the new run until the previous job finishes
+	OverlapQueue OverlapPolicy = "queue"
+
+	// OverlapCancelPrevious cancels the previous job and starts the new run
+	OverlapCancelPrevious OverlapPolicy = "cancel_previous"
+)
+
+// CatchUpPolicy controls which runs a recurring schedule makes up for when
+// the scheduler was not running at the time they fell due
+type CatchUpPolicy string
+
+const (
+	// CatchUpNone skips missed runs and waits for the next one. It is the
+	// default.
+	CatchUpNone CatchUpPolicy = "none"
+
+	// CatchUpOnce makes a single run in place of all the missed ones
+	CatchUpOnce CatchUpPolicy = "once"
+
+	// CatchUpAll makes every missed run, up to maxScheduleBacklog, one after
+	// another
+	CatchUpAll CatchUpPolicy = "all"
+)
+
+// Most runs a schedule will catch up on or hold back at once
+const maxScheduleBacklog = 100
+
+var (
+	// ErrScheduleNotFound is returned for an unknown schedule ID
+	ErrScheduleNotFound = errors.New("schedule not found")
+
+	// ErrScheduleExists is returned when creating a schedule whose ID is taken
+	ErrScheduleExists = errors.New("schedule already exists")
+)
+
+// Schedule is a recurring job definition. On every activation of its cron
+// expression a fresh job is created from Template, with an ID made of the
+// schedule ID and the activation time.
+type Schedule struct {
+	ID         string        `json:"id"`
+	Expression string        `json:"expression"`         // 5-field cron expression, or @every/@hourly style shorthand
+	Timezone   string        `json:"timezone,omitempty"` // IANA time zone the expression is evaluated in, UTC if empty
+	Template   JobPayload    `json:"template"`           // Job to run; its ID is ignored
+	Overlap    OverlapPolicy `json:"overlap,omitempty"`
+	CatchUp    CatchUpPolicy `json:"catch_up,omitempty"`
+	Paused     bool          `json:"paused,omitempty"`
+
+	// Maintained by the scheduler
+	CreatedAt time.Time `json:"created_at"`
+	NextRun   time.Time `json:"next_run,omitempty"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastJobID string    `json:"last_job_id,omitempty"`
+	Runs      int64     `json:"runs"`           // Jobs created
+	Skipped   int64     `json:"skipped"`        // Runs dropped by the overlap policy
+	Held      int       `json:"held,omitempty"` // Runs waiting for the previous job to finish
+}
+
+// Validate checks if the schedule definition is valid
+func (sc *Schedule) Validate() error {
+	if sc.ID == "" {
+		return fmt.Errorf("schedule ID cannot be empty")
+	}
+	if _, err := ParseCron(sc.Expression); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(sc.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %v", err)
+	}
+	switch sc.Overlap {
+	case "", OverlapSkip, OverlapQueue, OverlapCancelPrevious:
+	default:
+		return fmt.Errorf("unknown overlap policy: %s", sc.Overlap)
+	}
+	switch sc.CatchUp {
+	case "", CatchUpNone, CatchUpOnce, CatchUpAll:
+	default:
+		return fmt.Errorf("unknown catch-up policy: %s", sc.CatchUp)
+	}
+
+	template := sc.Template
+	template.ID = sc.ID
+	if err := template.Validate(); err != nil {
+		return fmt.Errorf("invalid job template: %v", err)
+	}
+	return nil
+}
+
+// scheduleEntry is a schedule with its parsed expression
+type scheduleEntry struct {
+	def  Schedule
+	cron CronSchedule
+	loc  *time.Location
+}
+
+// newScheduleEntry parses a validated schedule
+func newScheduleEntry(def Schedule) (*scheduleEntry, error) {
+	cron, err := ParseCron(def.Expression)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(def.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %v", err)
+	}
+	if def.Overlap == "" {
+		def.Overlap = OverlapSkip
+	}
+	if def.CatchUp == "" {
+		def.CatchUp = CatchUpNone
+	}
+	return &scheduleEntry{def: def, cron: cron, loc: loc}, nil
+}
+
+// next returns the first activation after t
+func (e *scheduleEntry) next(t time.Time) time.Time {
+	return e.cron.Next(t.In(e.loc))
+}
+
+// persistSchedule writes a schedule through to the store, logging failures
+// since the in-memory schedule remains authoritative
+func (s *Scheduler) persistSchedule(entry *scheduleEntry) {
+	if err := s.scheduleStore.SaveSchedule(entry.def); err != nil {
+		log.Printf("Failed to persist schedule %s: %v", entry.def.ID, err)
+	}
+}
+
+// recoverSchedules reloads persisted schedules, applying each one's catch-up
+// policy to runs missed while the scheduler was stopped
+func (s *Scheduler) recoverSchedules() error {
+	defs, err := s.scheduleStore.LoadSchedules()
+	if err != nil {
+		return err
+	}
+
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	now := time.Now()
+	for _, def := range defs {
+		entry, err := newScheduleEntry(def)
+		if err != nil {
+			log.Printf("Dropping invalid schedule %s: %v", def.ID, err)
+			continue
+		}
+		s.schedules[def.ID] = entry
+
+		if entry.def.Paused {
+			continue
+		}
+		if entry.def.NextRun.IsZero() || (entry.def.NextRun.Before(now) && entry.def.CatchUp == CatchUpNone) {
+			entry.def.NextRun = entry.next(now)
+			s.persistSchedule(entry)
+		}
+		s.ticks.add(def.ID, entry.def.NextRun)
+
+		// A run held back for a job that finished while we were down
+		s.releaseHeldRunLocked(entry)
+	}
+	return nil
+}
+
+// CreateSchedule adds a recurring schedule and returns it with its first run
+// time filled in
+func (s *Scheduler) CreateSchedule(def Schedule) (*Schedule, error) {
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %v", err)
+	}
+	if err := s.applications.check(def.Template); err != nil {
+		return nil, err
+	}
+
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	if _, exists := s.schedules[def.ID]; exists {
+		return nil, fmt.Errorf("schedule %s: %w", def.ID, ErrScheduleExists)
+	}
+
+	def.CreatedAt = time.Now()
+	def.NextRun, def.LastRun, def.LastJobID = time.Time{}, time.Time{}, ""
+	def.Runs, def.Skipped, def.Held = 0, 0, 0
+	entry, err := newScheduleEntry(def)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.def.Paused {
+		entry.def.NextRun = entry.next(entry.def.CreatedAt)
+	}
+
+	if err := s.scheduleStore.SaveSchedule(entry.def); err != nil {
+		return nil, fmt.Errorf("failed to persist schedule %s: %v", def.ID, err)
+	}
+	s.schedules[def.ID] = entry
+	if !entry.def.Paused {
+		s.ticks.add(def.ID, entry.def.NextRun)
+	}
+
+	result := entry.def
+	return &result, nil
+}
+
+// UpdateSchedule replaces the definition of an existing schedule, keeping its
+// run history. The next run is recalculated from the current time.
+func (s *Scheduler) UpdateSchedule(def Schedule) (*Schedule, error) {
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %v", err)
+	}
+	if err := s.applications.check(def.Template); err != nil {
+		return nil, err
+	}
+
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	existing, exists := s.schedules[def.ID]
+	if !exists {
+		return nil, fmt.Errorf("schedule %s: %w", def.ID, ErrScheduleNotFound)
+	}
+
+	def.CreatedAt = existing.def.CreatedAt
+	def.LastRun = existing.def.LastRun
+	def.LastJobID = existing.def.LastJobID
+	def.Runs = existing.def.Runs
+	def.Skipped = existing.def.Skipped
+	def.Held = existing.def.Held
+	def.NextRun = time.Time{}
+	entry, err := newScheduleEntry(def)
+	if err != nil {
+		return nil, err
+	}
+	s.ticks.remove(def.ID)
+	if !entry.def.Paused {
+		entry.def.NextRun = entry.next(time.Now())
+		s.ticks.add(def.ID, entry.def.NextRun)
+	}
+	s.schedules[def.ID] = entry
+	s.persistSchedule(entry)
+
+	result := entry.def
+	return &result, nil
+}
+
+// DeleteSchedule removes a schedule. Jobs it already created are unaffected.
+func (s *Scheduler) DeleteSchedule(id string) error {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	if _, exists := s.schedules[id]; !exists {
+		return fmt.Errorf("schedule %s: %w", id, ErrScheduleNotFound)
+	}
+	s.ticks.remove(id)
+	delete(s.schedules, id)
+	if err := s.scheduleStore.DeleteSchedule(id); err != nil {
+		log.Printf("Failed to delete schedule %s from store: %v", id, err)
+	}
+	return nil
+}
+
+// GetSchedule returns a schedule and its run history
+func (s *Scheduler) GetSchedule(id string) (*Schedule, error) {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	entry, exists := s.schedules[id]
+	if !exists {
+		return nil, fmt.Errorf("schedule %s: %w", id, ErrScheduleNotFound)
+	}
+	result := entry.def
+	return &result, nil
+}
+
+// ListSchedules returns every schedule ordered by creation time
+func (s *Scheduler) ListSchedules() []Schedule {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	schedules := make([]Schedule, 0, len(s.schedules))
+	for _, entry := range s.schedules {
+		schedules = append(schedules, entry.def)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].CreatedAt.Equal(schedules[j].CreatedAt) {
+			return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules
+}
+
+// fireSchedule is called when a schedule's next run falls due. It makes the
+// due runs allowed by the catch-up policy and arms the following one.
+func (s *Scheduler) fireSchedule(id string) {
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	entry, exists := s.schedules[id]
+	if !exists || entry.def.Paused {
+		return
+	}
+
+	now := time.Now()
+	var due []time.Time
+	for t := entry.def.NextRun; !t.IsZero() && !t.After(now); t = entry.next(t) {
+		due = append(due, t)
+		if len(due) == maxScheduleBacklog {
+			break
+		}
+	}
+	if entry.def.CatchUp != CatchUpAll && len(due) > 1 {
+		due = due[len(due)-1:]
+	}
+
+	if len(due) > 0 {
+		s.triggerLocked(entry, due[0])
+
+		// Further missed runs wait for the one before them
+		for range due[1:] {
+			if entry.def.Held < maxScheduleBacklog {
+				entry.def.Held++
+			} else {
+				entry.def.Skipped++
+			}
+		}
+		s.releaseHeldRunLocked(entry)
+	}
+
+	entry.def.NextRun = entry.next(now)
+	if !entry.def.NextRun.IsZero() {
+		s.ticks.add(id, entry.def.NextRun)
+	}
+	s.persistSchedule(entry)
+}
+
+// triggerLocked makes a single run of a schedule, subject to its overlap
+// policy. The caller must hold schedMu.
+func (s *Scheduler) triggerLocked(entry *scheduleEntry, tick time.Time) {
+	if prev := entry.def.LastJobID; prev != "" {
+		if job, exists := s.registry.get(prev); exists && !job.Status.IsTerminal() {
+			switch entry.def.Overlap {
+			case OverlapQueue:
+				if entry.def.Held < maxScheduleBacklog {
+					entry.def.Held++
+					return
+				}
+				entry.def.Skipped++
+				return
+			case OverlapCancelPrevious:
+				err := s.cancelJob(prev, "")
+				switch {
+				case err == nil:
+					// A run that never started is finished now. jobFinished
+					// would take schedMu again, and the run about to be
+					// submitted replaces this one anyway.
+					if job, _ := s.registry.get(prev); job.Status.IsTerminal() {
+						s.logs.close(prev)
+						if s.ctx.Err() == nil {
+							s.releaseDependents(prev)
+						}
+					}
+				case !errors.Is(err, ErrJobFinished):
+					log.Printf("Schedule %s: failed to cancel previous job %s: %v", entry.def.ID, prev, err)
+				}
+			default:
+				entry.def.Skipped++
+				return
+			}
+		}
+	}
+
+	s.submitRunLocked(entry, tick)
+}
+
+// submitRunLocked creates and submits the job for one run of a schedule
+func (s *Scheduler) submitRunLocked(entry *scheduleEntry, tick time.Time) {
+	job := entry.def.Template
+	job.ID = fmt.Sprintf("%s-%d", entry.def.ID, tick.UnixNano())
+	job.ScheduleID = entry.def.ID
+	job.RunAt = time.Time{}
+	job.Delay = 0
+
+	if err := s.SubmitJob(job); err != nil {
+		log.Printf("Schedule %s: failed to submit job %s: %v", entry.def.ID, job.ID, err)
+		entry.def.Skipped++
+		return
+	}
+	entry.def.LastRun = tick
+	entry.def.LastJobID = job.ID
+	entry.def.Runs++
+}
+
+// releaseHeldRunLocked starts a run held back by OverlapQueue once the
+// previous job has finished
+func (s *Scheduler) releaseHeldRunLocked(entry *scheduleEntry) {
+	if entry.def.Held == 0 {
+		return
+	}
+	if job, exists := s.registry.get(entry.def.LastJobID); exists && !job.Status.IsTerminal() {
+		return
+	}
+
+	entry.def.Held--
+	s.submitRunLocked(entry, time.Now())
+	s.persistSchedule(entry)
+}
+
+// scheduleRunFinished releases a held run once the job from a schedule's
+// latest run has finished
+func (s *Scheduler) scheduleRunFinished(job JobPayload) {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+
+	entry, exists := s.schedules[job.ScheduleID]
+	if !exists || entry.def.LastJobID != job.ID {
+		return
+	}
+	s.releaseHeldRunLocked(entry)
+}
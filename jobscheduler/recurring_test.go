@@ -0,0 +1,240 @@
+package jobscheduler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedules(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	sleepTemplate := func(channel, seconds string) JobPayload {
+		return JobPayload{
+			Channel: channel,
+			Workers: 2,
+			Application: &ApplicationConfig{
+				Name: "sleep",
+				Path: "sleep",
+				Args: []string{seconds},
+			},
+		}
+	}
+
+	waitForSchedule := func(t *testing.T, id string, condition func(Schedule) bool) Schedule {
+		t.Helper()
+		var schedule Schedule
+		require.Eventually(t, func() bool {
+			s, err := scheduler.GetSchedule(id)
+			require.NoError(t, err)
+			schedule = *s
+			return condition(schedule)
+		}, 10*time.Second, 20*time.Millisecond, "schedule %s never reached the expected state", id)
+		return schedule
+	}
+
+	t.Run("CRUD", func(t *testing.T) {
+		created, err := scheduler.CreateSchedule(Schedule{
+			ID:         "nightly",
+			Expression: "0 2 * * *",
+			Template:   sleepTemplate("crud-channel", "0"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, OverlapSkip, created.Overlap)
+		assert.Equal(t, CatchUpNone, created.CatchUp)
+		assert.Equal(t, 2, created.NextRun.UTC().Hour())
+
+		_, err = scheduler.CreateSchedule(Schedule{ID: "nightly", Expression: "@daily", Template: sleepTemplate("crud-channel", "0")})
+		assert.ErrorIs(t, err, ErrScheduleExists)
+
+		_, err = scheduler.CreateSchedule(Schedule{ID: "bad", Expression: "not cron", Template: sleepTemplate("crud-channel", "0")})
+		assert.Error(t, err)
+
+		updated, err := scheduler.UpdateSchedule(Schedule{
+			ID:         "nightly",
+			Expression: "@hourly",
+			Template:   sleepTemplate("crud-channel", "0"),
+			Paused:     true,
+		})
+		require.NoError(t, err)
+		assert.True(t, updated.NextRun.IsZero())
+		assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+
+		ids := []string{}
+		for _, schedule := range scheduler.ListSchedules() {
+			ids = append(ids, schedule.ID)
+		}
+		assert.Contains(t, ids, "nightly")
+
+		require.NoError(t, scheduler.DeleteSchedule("nightly"))
+		_, err = scheduler.GetSchedule("nightly")
+		assert.ErrorIs(t, err, ErrScheduleNotFound)
+		assert.ErrorIs(t, scheduler.DeleteSchedule("nightly"), ErrScheduleNotFound)
+	})
+
+	t.Run("CreatesJobs", func(t *testing.T) {
+		_, err := scheduler.CreateSchedule(Schedule{
+			ID:         "every-second",
+			Expression: "@every 1s",
+			Template:   sleepTemplate("every-channel", "0"),
+		})
+		require.NoError(t, err)
+
+		schedule := waitForSchedule(t, "every-second", func(s Schedule) bool { return s.Runs >= 2 })
+		require.NoError(t, scheduler.DeleteSchedule("every-second"))
+
+		job, err := scheduler.GetJobStatus(schedule.LastJobID)
+		require.NoError(t, err)
+		assert.Equal(t, "every-second", job.ScheduleID)
+		assert.Equal(t, "every-channel", job.Channel)
+	})
+
+	t.Run("OverlapSkip", func(t *testing.T) {
+		_, err := scheduler.CreateSchedule(Schedule{
+			ID:         "skip",
+			Expression: "@every 1s",
+			Template:   sleepTemplate("skip-channel", "3"),
+			Overlap:    OverlapSkip,
+		})
+		require.NoError(t, err)
+
+		schedule := waitForSchedule(t, "skip", func(s Schedule) bool { return s.Skipped >= 1 })
+		require.NoError(t, scheduler.DeleteSchedule("skip"))
+		assert.EqualValues(t, 1, schedule.Runs)
+	})
+
+	t.Run("OverlapQueue", func(t *testing.T) {
+		_, err := scheduler.CreateSchedule(Schedule{
+			ID:         "queue",
+			Expression: "@every 1s",
+			Template:   sleepTemplate("queue-channel", "1.5"),
+			Overlap:    OverlapQueue,
+		})
+		require.NoError(t, err)
+
+		waitForSchedule(t, "queue", func(s Schedule) bool { return s.Held >= 1 })
+		schedule := waitForSchedule(t, "queue", func(s Schedule) bool { return s.Runs >= 2 })
+		require.NoError(t, scheduler.DeleteSchedule("queue"))
+
+		// The held run only started once the first job had finished
+		jobs, err := scheduler.ListJobs("queue-channel", "")
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, len(jobs), 2)
+		first, err := scheduler.GetJobResult(jobs[0].ID)
+		require.NoError(t, err)
+		second, err := scheduler.GetJobStatus(jobs[1].ID)
+		require.NoError(t, err)
+		assert.Equal(t, schedule.ID, second.ScheduleID)
+		assert.False(t, second.StartTime.Before(first.EndTime))
+	})
+
+	t.Run("OverlapCancelPrevious", func(t *testing.T) {
+		_, err := scheduler.CreateSchedule(Schedule{
+			ID:         "cancel",
+			Expression: "@every 1s",
+			Template:   sleepTemplate("cancel-prev-channel", "5"),
+			Overlap:    OverlapCancelPrevious,
+		})
+		require.NoError(t, err)
+
+		first := waitForSchedule(t, "cancel", func(s Schedule) bool { return s.Runs == 1 }).LastJobID
+		waitForSchedule(t, "cancel", func(s Schedule) bool { return s.Runs >= 2 })
+		require.NoError(t, scheduler.DeleteSchedule("cancel"))
+		waitForStatus(t, scheduler, first, JobStatusCancelled)
+	})
+
+	t.Run("OverlapCancelQueued", func(t *testing.T) {
+		// Runs queue behind a job holding the channel's only worker
+		holder := sleepTemplate("cancel-queued-channel", "30")
+		holder.ID = "cancel-holder"
+		holder.Workers = 1
+		require.NoError(t, scheduler.SubmitJob(holder))
+		waitForStatus(t, scheduler, "cancel-holder", JobStatusRunning)
+
+		template := sleepTemplate("cancel-queued-channel", "0")
+		template.Workers = 1
+		_, err := scheduler.CreateSchedule(Schedule{
+			ID:         "cancel-queued",
+			Expression: "@every 1s",
+			Template:   template,
+			Overlap:    OverlapCancelPrevious,
+		})
+		require.NoError(t, err)
+
+		// A job waiting on a run that is cancelled before it starts is
+		// cancelled with it rather than blocked for good
+		first := waitForSchedule(t, "cancel-queued", func(s Schedule) bool { return s.Runs == 1 }).LastJobID
+		dependent := sleepTemplate("cancel-queued-dependents", "0")
+		dependent.ID = "after-cancelled-run"
+		dependent.DependsOn = []string{first}
+		require.NoError(t, scheduler.SubmitJob(dependent))
+		waitForSchedule(t, "cancel-queued", func(s Schedule) bool { return s.Runs >= 2 })
+		require.NoError(t, scheduler.DeleteSchedule("cancel-queued"))
+		waitForStatus(t, scheduler, first, JobStatusCancelled)
+		waitForStatus(t, scheduler, "after-cancelled-run", JobStatusCancelled)
+		require.NoError(t, scheduler.CancelJob("cancel-holder"))
+	})
+}
+
+func TestScheduleCatchUp(t *testing.T) {
+	tests := []struct {
+		policy CatchUpPolicy
+		runs   int
+	}{
+		{CatchUpNone, 0},
+		{CatchUpOnce, 1},
+		{CatchUpAll, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			tmpDir := t.TempDir()
+			storePath := filepath.Join(tmpDir, "jobs.wal")
+
+			// Simulate a scheduler that was down for three hourly runs
+			store, err := NewFileStore(storePath)
+			require.NoError(t, err)
+			require.NoError(t, store.SaveSchedule(Schedule{
+				ID:         "hourly",
+				Expression: "@every 1h",
+				Template: JobPayload{
+					Channel:     "catch-up",
+					Application: &ApplicationConfig{Name: "true", Path: "true"},
+				},
+				CatchUp:   tt.policy,
+				CreatedAt: time.Now().Add(-4 * time.Hour),
+				NextRun:   time.Now().Add(-3*time.Hour + time.Minute),
+			}))
+			require.NoError(t, store.Close())
+
+			cfg := DefaultConfig()
+			cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+			cfg.WorkDir = tmpDir
+			cfg.StorePath = storePath
+			scheduler, err := NewScheduler(cfg)
+			require.NoError(t, err)
+			defer scheduler.Shutdown()
+
+			if tt.runs > 0 {
+				require.Eventually(t, func() bool {
+					jobs, err := scheduler.ListJobs("catch-up", string(JobStatusComplete))
+					return err == nil && len(jobs) == tt.runs
+				}, 5*time.Second, 20*time.Millisecond)
+			} else {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			jobs, err := scheduler.ListJobs("catch-up", "")
+			require.NoError(t, err)
+			assert.Len(t, jobs, tt.runs)
+
+			schedule, err := scheduler.GetSchedule("hourly")
+			require.NoError(t, err)
+			assert.True(t, schedule.NextRun.After(time.Now()))
+			assert.EqualValues(t, tt.runs, schedule.Runs)
+		})
+	}
+}
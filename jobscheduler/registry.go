@@ -0,0 +1,453 @@
+package jobscheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrJobNotFound is returned when the scheduler has no record of a job ID
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobExists is returned when a job is submitted under an ID already in use
+	ErrJobExists = errors.New("job already exists")
+
+	// ErrInvalidJob is returned when a submitted job fails validation
+	ErrInvalidJob = errors.New("invalid job payload")
+
+	// ErrJobFinished is returned when an operation needs a job that has not yet finished
+	ErrJobFinished = errors.New("job already finished")
+
+	// ErrJobNotFinished is returned when a job has no result because it is still pending or running
+	ErrJobNotFinished = errors.New("job not finished")
+
+	// ErrJobNotDeadLettered is returned when a dead-letter operation names a job that is not dead-lettered
+	ErrJobNotDeadLettered = errors.New("job is not dead-lettered")
+
+	// ErrDependencyNotFound is returned when a job depends on an unknown job ID
+	ErrDependencyNotFound = errors.New("dependency not found")
+)
+
+// jobEntry holds a tracked job together with its runtime state
+type jobEntry struct {
+	job      JobPayload
+	result   *JobResult         // Set once the job reaches a terminal status
+	attempts []JobAttempt       // Every finished run of the job
+	events   []JobEvent         // Everything that happened to the job
+	cancel   context.CancelFunc // Set while the job is running
+
+	deadLetteredAt  time.Time // Set while the job is dead-lettered
+	cancelRequested bool
+	counted         bool // Counted among its channel's unfinished jobs
+}
+
+// jobRegistry tracks every submitted job from pending through to a terminal
+// status, writing every change through to a JobStore
+type jobRegistry struct {
+	mu         sync.RWMutex
+	jobs       map[string]*jobEntry
+	order      []string            // Job IDs in submission order
+	dependents map[string][]string // Job IDs that depend on each job
+	open       map[string]int      // Jobs not yet in a terminal status, by channel
+	store      JobStore
+}
+
+// newJobRegistry creates an empty job registry backed by store
+func newJobRegistry(store JobStore) *jobRegistry {
+	return &jobRegistry{
+		jobs:       make(map[string]*jobEntry),
+		dependents: make(map[string][]string),
+		open:       make(map[string]int),
+		store:      store,
+	}
+}
+
+// record returns the persisted form of an entry
+func (e *jobEntry) record() JobRecord {
+	return JobRecord{
+		Job:            e.job,
+		Result:         e.result,
+		Attempts:       e.attempts,
+		Events:         e.events,
+		DeadLetteredAt: e.deadLetteredAt,
+	}
+}
+
+// recordAttempt appends the outcome of a finished run to the job's history
+func (e *jobEntry) recordAttempt(result JobResult) {
+	e.attempts = append(e.attempts, JobAttempt{
+		Attempt:       len(e.attempts) + 1,
+		Status:        result.Status,
+		ExitCode:      result.ExitCode,
+		Error:         result.Error,
+		Termination:   result.Termination,
+		FailureReason: result.FailureReason,
+		StartTime:     result.StartTime,
+		EndTime:       result.EndTime,
+	})
+}
+
+// persist writes an entry to the store, and brings its channel's count of
+// unfinished jobs up to date. A failed write is logged rather than returned
+// because the in-memory state has already moved on.
+func (r *jobRegistry) persist(entry *jobEntry) {
+	r.countLocked(entry, !entry.job.Status.IsTerminal())
+	if err := r.store.Save(entry.record()); err != nil {
+		log.Printf("Failed to persist job %s: %v", entry.job.ID, err)
+	}
+}
+
+// countLocked counts an entry among its channel's unfinished jobs, or stops
+// counting it. The caller must hold r.mu.
+func (r *jobRegistry) countLocked(entry *jobEntry, unfinished bool) {
+	if entry.counted == unfinished {
+		return
+	}
+	entry.counted = unfinished
+	channel := entry.job.Channel
+	if unfinished {
+		r.open[channel]++
+	} else if r.open[channel]--; r.open[channel] == 0 {
+		delete(r.open, channel)
+	}
+}
+
+// add registers a new job, rejecting duplicate IDs. A job whose
+// dependencies have not all completed is added as blocked, and one with a
+// dependency that can no longer complete is added as cancelled; the job is
+// returned with its resulting status.
+func (r *jobRegistry) add(job JobPayload) (JobPayload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.jobs[job.ID]; exists {
+		return job, fmt.Errorf("job %s: %w", job.ID, ErrJobExists)
+	}
+	for _, parentID := range job.DependsOn {
+		if _, exists := r.jobs[parentID]; !exists {
+			return job, fmt.Errorf("job %s depends on %s: %w", job.ID, parentID, ErrDependencyNotFound)
+		}
+	}
+
+	entry := &jobEntry{job: job}
+	if ready, reason := r.dependencyStateLocked(job); reason != "" {
+		entry.cancelLocked(reason)
+	} else if !ready {
+		entry.job.Status = JobStatusBlocked
+	}
+
+	if err := r.store.Save(entry.record()); err != nil {
+		return job, fmt.Errorf("failed to persist job %s: %v", job.ID, err)
+	}
+	r.jobs[job.ID] = entry
+	r.order = append(r.order, job.ID)
+	r.indexDependentsLocked(entry.job)
+	r.countLocked(entry, !entry.job.Status.IsTerminal())
+	return entry.job, nil
+}
+
+// restore loads a persisted record without writing it back to the store
+func (r *jobRegistry) restore(record JobRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if old, exists := r.jobs[record.Job.ID]; exists {
+		r.countLocked(old, false)
+	} else {
+		r.order = append(r.order, record.Job.ID)
+		r.indexDependentsLocked(record.Job)
+	}
+	entry := &jobEntry{
+		job:            record.Job,
+		result:         record.Result,
+		attempts:       record.Attempts,
+		events:         record.Events,
+		deadLetteredAt: record.DeadLetteredAt,
+	}
+	r.jobs[record.Job.ID] = entry
+	r.countLocked(entry, !entry.job.Status.IsTerminal())
+}
+
+// update replaces the stored state of a job that is not running
+func (r *jobRegistry) update(job JobPayload, result *JobResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[job.ID]
+	if !exists {
+		return
+	}
+	entry.job = job
+	entry.result = result
+	r.persist(entry)
+}
+
+// remove drops a job that never made it into a channel
+func (r *jobRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeLocked(id)
+}
+
+// removeLocked drops a job and its stored record. The caller must hold r.mu.
+func (r *jobRegistry) removeLocked(id string) {
+	entry, exists := r.jobs[id]
+	if !exists {
+		return
+	}
+	r.countLocked(entry, false)
+	delete(r.jobs, id)
+	delete(r.dependents, id)
+	for _, parentID := range entry.job.DependsOn {
+		if dependents := removeID(r.dependents[parentID], id); len(dependents) > 0 {
+			r.dependents[parentID] = dependents
+		} else {
+			delete(r.dependents, parentID)
+		}
+	}
+	r.order = removeID(r.order, id)
+	if err := r.store.Delete(id); err != nil {
+		log.Printf("Failed to delete job %s from store: %v", id, err)
+	}
+}
+
+// evict forgets finished jobs that ended before cutoff, if it is not zero,
+// and the oldest finished jobs beyond the most recent max, if it is not
+// zero, and returns their IDs. Dead-lettered jobs are kept, as are jobs
+// that unfinished jobs depend on.
+func (r *jobRegistry) evict(cutoff time.Time, max int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var finished []string
+	for _, id := range r.order {
+		entry := r.jobs[id]
+		if entry.job.Status.IsTerminal() && entry.deadLetteredAt.IsZero() && !r.awaitedLocked(id) {
+			finished = append(finished, id)
+		}
+	}
+
+	var evicted []string
+	for i, id := range finished {
+		expired := !cutoff.IsZero() && r.jobs[id].job.EndTime.Before(cutoff)
+		if expired || (max > 0 && len(finished)-i > max) {
+			evicted = append(evicted, id)
+		}
+	}
+	for _, id := range evicted {
+		r.removeLocked(id)
+	}
+	return evicted
+}
+
+// awaitedLocked reports whether any unfinished job depends on a job. The
+// caller must hold r.mu.
+func (r *jobRegistry) awaitedLocked(id string) bool {
+	for _, dependentID := range r.dependents[id] {
+		if dependent, exists := r.jobs[dependentID]; exists && !dependent.job.Status.IsTerminal() {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns a copy of the job with the given ID
+func (r *jobRegistry) get(id string) (JobPayload, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.jobs[id]
+	if !exists {
+		return JobPayload{}, false
+	}
+	return entry.job, true
+}
+
+// list returns jobs in submission order, optionally filtered by channel and status
+func (r *jobRegistry) list(channel string, status JobStatus) []JobPayload {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jobs := make([]JobPayload, 0, len(r.order))
+	for _, id := range r.order {
+		job := r.jobs[id].job
+		if channel != "" && job.Channel != channel {
+			continue
+		}
+		if status != "" && job.Status != status {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// unfinished counts the jobs in a channel that have not reached a terminal
+// status
+func (r *jobRegistry) unfinished(channel string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.open[channel]
+}
+
+// markRunning moves a pending job to running and records how to cancel it.
+// It returns false if the job was cancelled while it was still queued.
+func (r *jobRegistry) markRunning(id string, startTime time.Time, cancel context.CancelFunc) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[id]
+	if !exists || entry.job.Status != JobStatusPending {
+		return false
+	}
+	entry.job.Status = JobStatusRunning
+	entry.job.StartTime = startTime
+	entry.job.Progress = nil
+	entry.cancel = cancel
+	r.persist(entry)
+	return true
+}
+
+// setProgress records the latest progress report of a running job. It is
+// not persisted on its own, since reports can come thick and fast.
+func (r *jobRegistry) setProgress(id string, progress Progress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, exists := r.jobs[id]; exists && entry.job.Status == JobStatusRunning {
+		entry.job.Progress = &progress
+	}
+}
+
+// result returns a copy of the result of a finished job
+func (r *jobRegistry) result(id string) (JobResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.jobs[id]
+	if !exists {
+		return JobResult{}, fmt.Errorf("job %s: %w", id, ErrJobNotFound)
+	}
+	if entry.result == nil {
+		return JobResult{}, fmt.Errorf("job %s is %s: %w", id, entry.job.Status, ErrJobNotFinished)
+	}
+	return *entry.result, nil
+}
+
+// finish records the final state and result of a job that has stopped
+// running and returns the job, reporting a job that was stopped by
+// CancelJob as cancelled. Jobs that end failed or timed out are moved to
+// the dead-letter list.
+func (r *jobRegistry) finish(job JobPayload, result JobResult) JobPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[job.ID]
+	if !exists {
+		return job
+	}
+	if entry.cancelRequested && job.Status != JobStatusComplete {
+		job.Status = JobStatusCancelled
+		job.Error = "job cancelled"
+	}
+	result.Status = job.Status
+	result.Error = job.Error
+	job.Progress = entry.job.Progress
+	entry.job = job
+	entry.result = &result
+	entry.cancel = nil
+	entry.cancelRequested = false
+	entry.recordAttempt(result)
+	if job.Status == JobStatusFailed || job.Status == JobStatusTimedOut {
+		entry.deadLetteredAt = job.EndTime
+	}
+	r.persist(entry)
+	return job
+}
+
+// retry moves a job that has just failed into the retrying status. It
+// returns false if the job was cancelled while it was running.
+func (r *jobRegistry) retry(job JobPayload, result JobResult) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[job.ID]
+	if !exists || entry.cancelRequested {
+		return false
+	}
+
+	entry.recordAttempt(result)
+
+	// The attempt keeps its error; the job starts afresh
+	entry.job = job
+	entry.job.Error = ""
+	entry.job.EndTime = time.Time{}
+	entry.cancel = nil
+	r.persist(entry)
+	return true
+}
+
+// requeue moves a retrying or scheduled job to pending once it is due. It
+// returns false if the job was cancelled in the meantime.
+func (r *jobRegistry) requeue(id string) (JobPayload, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[id]
+	if !exists {
+		return JobPayload{}, false
+	}
+	if entry.job.Status != JobStatusRetrying && entry.job.Status != JobStatusScheduled {
+		return JobPayload{}, false
+	}
+	entry.job.Status = JobStatusPending
+	entry.job.Error = ""
+	entry.job.EndTime = time.Time{}
+	r.persist(entry)
+	return entry.job, true
+}
+
+// cancel cancels a queued job outright, or signals a running job to stop
+func (r *jobRegistry) cancel(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.jobs[id]
+	if !exists {
+		return fmt.Errorf("job %s: %w", id, ErrJobNotFound)
+	}
+
+	switch entry.job.Status {
+	case JobStatusPending, JobStatusScheduled, JobStatusBlocked, JobStatusRetrying:
+		entry.cancelLocked("job cancelled")
+		r.persist(entry)
+		return nil
+	case JobStatusRunning:
+		entry.cancelRequested = true
+		if entry.cancel != nil {
+			entry.cancel()
+		}
+		return nil
+	default:
+		return fmt.Errorf("job %s is %s: %w", id, entry.job.Status, ErrJobFinished)
+	}
+}
+
+// cancelLocked moves a job that is not running straight to cancelled
+func (e *jobEntry) cancelLocked(reason string) {
+	e.job.Status = JobStatusCancelled
+	e.job.Error = reason
+	e.job.EndTime = time.Now()
+	e.result = &JobResult{
+		JobID:   e.job.ID,
+		Status:  e.job.Status,
+		Error:   e.job.Error,
+		EndTime: e.job.EndTime,
+	}
+}
@@ -0,0 +1,83 @@
+package jobscheduler
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls automatic retries of failed and timed-out jobs
+type RetryPolicy struct {
+	// Maximum number of retries after the first attempt (0 disables retries)
+	MaxRetries int `json:"max_retries"`
+
+	// Delay before the first retry
+	InitialDelay time.Duration `json:"initial_delay,omitempty"`
+
+	// Upper bound for the delay between retries (0 for no limit)
+	MaxDelay time.Duration `json:"max_delay,omitempty"`
+
+	// Multiplier applied to the delay after each retry (values below 1 keep the delay constant)
+	BackoffFactor float64 `json:"backoff_factor,omitempty"`
+
+	// Fraction of each delay, between 0 and 1, that is randomly shaved off
+	// so that jobs failing together do not retry in lockstep
+	Jitter float64 `json:"jitter,omitempty"`
+
+	// Exit codes that indicate a permanent failure and are never retried
+	NonRetryableExitCodes []int `json:"non_retryable_exit_codes,omitempty"`
+}
+
+// Validate checks if the retry policy is valid
+func (r *RetryPolicy) Validate() error {
+	if r.MaxRetries < 0 {
+		return fmt.Errorf("max retries cannot be negative")
+	}
+	if r.InitialDelay < 0 || r.MaxDelay < 0 {
+		return fmt.Errorf("retry delays cannot be negative")
+	}
+	if r.BackoffFactor < 0 {
+		return fmt.Errorf("backoff factor cannot be negative")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// shouldRetry reports whether a finished attempt qualifies for another try
+func (r *RetryPolicy) shouldRetry(job JobPayload, result JobResult) bool {
+	if job.Status != JobStatusFailed && job.Status != JobStatusTimedOut {
+		return false
+	}
+	if job.RetryCount >= r.MaxRetries {
+		return false
+	}
+	if job.Status == JobStatusFailed {
+		for _, code := range r.NonRetryableExitCodes {
+			if result.ExitCode == code {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// delay returns how long to wait before the given retry, counting from zero
+func (r *RetryPolicy) delay(retry int) time.Duration {
+	factor := r.BackoffFactor
+	if factor < 1 {
+		factor = 1
+	}
+
+	delay := float64(r.InitialDelay) * math.Pow(factor, float64(retry))
+	if r.MaxDelay > 0 && delay > float64(r.MaxDelay) {
+		delay = float64(r.MaxDelay)
+	}
+	if delay > math.MaxInt64 {
+		delay = math.MaxInt64
+	}
+	delay -= delay * r.Jitter * rand.Float64()
+	return time.Duration(delay)
+}
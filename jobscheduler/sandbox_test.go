@@ -0,0 +1,195 @@
+package jobscheduler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requireUserNamespaces skips a test where sandboxes cannot be created
+func requireUserNamespaces(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("sandboxing is only supported on Linux")
+	}
+	cmd := exec.Command("true")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+	if err := cmd.Run(); err != nil {
+		t.Skipf("user namespaces are not available: %v", err)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	requireUserNamespaces(t)
+	scheduler := newTestScheduler(t)
+
+	sandboxedJob := func(id, script string) JobPayload {
+		job := shellJob(id, "sandbox-channel", script)
+		job.Application.Sandbox = &SandboxConfig{}
+		return job
+	}
+
+	run := func(t *testing.T, job JobPayload, status JobStatus) *JobResult {
+		t.Helper()
+		require.NoError(t, scheduler.SubmitJob(job))
+		waitForStatus(t, scheduler, job.ID, status)
+		result, err := scheduler.GetJobResult(job.ID)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("Scratch", func(t *testing.T) {
+		result := run(t, sandboxedJob("scratch", `echo hi > out.txt && cat out.txt && [ "$HOME" = "$PWD" ]`), JobStatusComplete)
+		assert.Equal(t, "hi\n", result.Output)
+	})
+
+	t.Run("ReadOnlyRoot", func(t *testing.T) {
+		result := run(t, sandboxedJob("read-only", "touch /etc/sandbox-test"), JobStatusFailed)
+		assert.Contains(t, result.Stderr, "Read-only file system")
+		_, err := os.Stat("/etc/sandbox-test")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("CannotRemount", func(t *testing.T) {
+		run(t, sandboxedJob("remount", "mount -o remount,rw / || mount -o remount,rw,bind / || exit 1; touch /etc/sandbox-test"), JobStatusFailed)
+		_, err := os.Stat("/etc/sandbox-test")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("HidesOtherJobs", func(t *testing.T) {
+		result := run(t, sandboxedJob("hidden", "ls .. | wc -l"), JobStatusComplete)
+		assert.Equal(t, "1\n", result.Output)
+	})
+
+	t.Run("OwnProcesses", func(t *testing.T) {
+		// PID 1 is the sandbox's init process rather than the host's
+		result := run(t, sandboxedJob("pid-namespace", "head -c 25 /proc/1/cmdline"), JobStatusComplete)
+		assert.Equal(t, "jobscheduler-sandbox-init", result.Output)
+	})
+
+	t.Run("NoNetwork", func(t *testing.T) {
+		// Only the loopback interface, after the two header lines
+		result := run(t, sandboxedJob("no-network", "tail -n +3 /proc/net/dev | wc -l"), JobStatusComplete)
+		assert.Equal(t, "1\n", result.Output)
+	})
+
+	t.Run("Inputs", func(t *testing.T) {
+		run(t, sandboxedJob("sandbox-upstream", "echo upstream"), JobStatusComplete)
+		job := sandboxedJob("sandbox-downstream", `cat data.txt; basename "$PWD"`)
+		job.Application.WorkingDir = "work"
+		job.Application.Inputs = []JobInput{{JobID: "sandbox-upstream", File: "data.txt"}}
+		result := run(t, job, JobStatusComplete)
+		assert.Equal(t, "upstream\nwork\n", result.Output)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		job := sandboxedJob("sandbox-timeout", "trap 'exit 3' TERM; sleep 30 & wait")
+		job.Application.Timeout = 100 * time.Millisecond
+		job.Application.KillTimeout = 5 * time.Second
+		result := run(t, job, JobStatusTimedOut)
+		assert.Equal(t, TerminationTerminated, result.Termination)
+		assert.Less(t, result.EndTime.Sub(result.StartTime), time.Second)
+		assert.Empty(t, result.Survivors)
+	})
+
+	t.Run("SetupFailure", func(t *testing.T) {
+		// An application the sandbox could not start never ran, while one
+		// that exits with the status of a failed sandbox simply failed
+		job := sandboxedJob("missing", "true")
+		job.Application.Path = "/no/such/application"
+		result := run(t, job, JobStatusFailed)
+		assert.Equal(t, -1, result.ExitCode)
+		assert.Contains(t, result.Error, "sandbox: ")
+
+		result = run(t, sandboxedJob("exit-125", "exit 125"), JobStatusFailed)
+		assert.Equal(t, 125, result.ExitCode)
+		assert.NotContains(t, result.Error, "sandbox")
+	})
+
+	t.Run("Cleanup", func(t *testing.T) {
+		run(t, sandboxedJob("cleanup", "touch left-behind"), JobStatusComplete)
+		entries, err := os.ReadDir(scheduler.config.WorkDir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			assert.False(t, entry.IsDir(), "left %s behind", filepath.Join(scheduler.config.WorkDir, entry.Name()))
+		}
+	})
+
+	t.Run("InvalidWorkingDir", func(t *testing.T) {
+		job := sandboxedJob("absolute-dir", "true")
+		job.Application.WorkingDir = "/etc"
+		assert.Error(t, scheduler.SubmitJob(job))
+	})
+}
+
+func TestForcedSandbox(t *testing.T) {
+	requireUserNamespaces(t)
+
+	tmpDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+	cfg.WorkDir = tmpDir
+	cfg.SandboxUntrusted = true
+	cfg.Channels = []ChannelConfig{{Name: "isolated", Sandbox: true}}
+
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	defer scheduler.Shutdown()
+
+	initJob := func(id, channel string, untrusted bool) JobPayload {
+		job := shellJob(id, channel, "head -c 25 /proc/1/cmdline")
+		job.Untrusted = untrusted
+		return job
+	}
+
+	for _, tc := range []struct {
+		job       JobPayload
+		sandboxed bool
+	}{
+		{initJob("trusted", "open", false), false},
+		{initJob("untrusted", "open", true), true},
+		{initJob("channel", "isolated", false), true},
+	} {
+		require.NoError(t, scheduler.SubmitJob(tc.job))
+		waitForStatus(t, scheduler, tc.job.ID, JobStatusComplete)
+		result, err := scheduler.GetJobResult(tc.job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.sandboxed, result.Output == "jobscheduler-sandbox-init", "job %s", tc.job.ID)
+	}
+
+	// A job cannot ask its way out of the network isolation of a sandbox
+	// forced on it, unless the operator allows it
+	networkJob := func(id string) JobPayload {
+		job := shellJob(id, "isolated", "tail -n +3 /proc/net/dev | wc -l")
+		job.Application.Sandbox = &SandboxConfig{Network: true}
+		return job
+	}
+
+	interfaces := func(t *testing.T, scheduler *Scheduler, id string) string {
+		t.Helper()
+		require.NoError(t, scheduler.SubmitJob(networkJob(id)))
+		waitForStatus(t, scheduler, id, JobStatusComplete)
+		result, err := scheduler.GetJobResult(id)
+		require.NoError(t, err)
+		return result.Output
+	}
+	assert.Equal(t, "1\n", interfaces(t, scheduler, "forced-network"))
+
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "allowed.log")
+	cfg.SandboxNetwork = true
+	allowed, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	defer allowed.Shutdown()
+	assert.NotEqual(t, "1\n", interfaces(t, allowed, "allowed-network"))
+}
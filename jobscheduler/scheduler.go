@@ -13,24 +13,37 @@ import (
 
 // Scheduler manages the job scheduling and processing
 type Scheduler struct {
-	config     Config
-	executor   *executor.Executor
-	channels   map[string]*Channel
-	stats      map[string]*ChannelStats
-	mu         sync.RWMutex
-	processLog *os.File
-	ctx        context.Context
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
-}
-
-// Channel represents a processing channel
-type Channel struct {
-	Name      string
-	Jobs      chan JobPayload
-	Workers   int
-	Timeout   time.Duration
-	processor *Processor
+	config       Config
+	executor     *executor.Executor
+	channels     map[string]*Channel
+	stats        *statsRecorder
+	registry     *jobRegistry
+	handlers     *handlerRegistry
+	applications *applicationRegistry
+	logs         *logStore
+	delays       *delayQueue
+	ticks        *delayQueue
+	mu           sync.RWMutex
+	processLog   *eventLog
+	subscribers  *subscriberList
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	workflowMu   sync.Mutex // Serialises workflow submissions
+
+	// Recurring schedules, guarded by schedMu
+	schedules     map[string]*scheduleEntry
+	schedMu       sync.Mutex
+	scheduleStore ScheduleStore
+}
+
+// MaybeRunHelper runs this process as one of the helpers that set up
+// sandboxed or resource-limited applications if it was started as one, in
+// which case it never returns. Those helpers work by re-executing the
+// running binary, so a program that runs such applications must call this
+// first thing in main.
+func MaybeRunHelper() {
+	executor.MaybeRunHelper()
 }
 
 // NewScheduler creates and returns a new Scheduler instance
@@ -45,7 +58,7 @@ func NewScheduler(cfg Config) (*Scheduler, error) {
 	}
 
 	// Open process log
-	processLog, err := os.OpenFile(cfg.ProcessingLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	processLog, err := openEventLog(cfg.ProcessingLogPath, cfg.ProcessingLog)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open process log: %v", err)
 	}
@@ -56,27 +69,210 @@ func NewScheduler(cfg Config) (*Scheduler, error) {
 		processLog.Close()
 		return nil, fmt.Errorf("failed to create executor: %v", err)
 	}
+	if cfg.Subreaper {
+		if err := exec.EnableSubreaper(); err != nil {
+			processLog.Close()
+			return nil, err
+		}
+	}
+	if cfg.CgroupRoot != "" {
+		if err := exec.SetCgroupRoot(cfg.CgroupRoot); err != nil {
+			processLog.Close()
+			return nil, err
+		}
+	}
+
+	// Open job store
+	store := cfg.Store
+	if store == nil {
+		if cfg.StorePath != "" {
+			store, err = NewFileStore(cfg.StorePath)
+			if err != nil {
+				processLog.Close()
+				return nil, fmt.Errorf("failed to open job store: %v", err)
+			}
+		} else {
+			store = NewMemoryStore()
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	s := &Scheduler{
-		config:     cfg,
-		executor:   exec,
-		channels:   make(map[string]*Channel),
-		stats:      make(map[string]*ChannelStats),
-		processLog: processLog,
-		ctx:        ctx,
-		cancel:     cancel,
+		config:       cfg,
+		executor:     exec,
+		channels:     make(map[string]*Channel),
+		stats:        newStatsRecorder(time.Now()),
+		registry:     newJobRegistry(store),
+		handlers:     newHandlerRegistry(),
+		applications: newApplicationRegistry(cfg.RestrictApplications),
+		logs:         newLogStore(cfg.logLines(), cfg.logRetention()),
+		delays:       newDelayQueue(),
+		ticks:        newDelayQueue(),
+		schedules:    make(map[string]*scheduleEntry),
+		processLog:   processLog,
+		subscribers:  &subscriberList{},
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	// Schedules are kept alongside jobs when the store supports it
+	if scheduleStore, ok := store.(ScheduleStore); ok {
+		s.scheduleStore = scheduleStore
+	} else {
+		s.scheduleStore = NewMemoryStore()
+	}
+
+	// Declared applications and channels exist before any job arrives
+	for _, app := range cfg.Applications {
+		s.applications.put(app, false)
+	}
+	s.mu.Lock()
+	for _, channel := range cfg.Channels {
+		s.createChannelLocked(channel, true)
+	}
+	s.mu.Unlock()
+
+	// Replay persisted jobs and schedules from a previous run
+	if err := s.recoverJobs(); err != nil {
+		cancel()
+		store.Close()
+		processLog.Close()
+		return nil, fmt.Errorf("failed to recover jobs: %v", err)
+	}
+	if err := s.recoverSchedules(); err != nil {
+		cancel()
+		store.Close()
+		processLog.Close()
+		return nil, fmt.Errorf("failed to recover schedules: %v", err)
+	}
+
+	// Release scheduled jobs and retries as they fall due
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.delays.run(ctx, s.releaseJob)
+	}()
+
+	// Run recurring schedules
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.ticks.run(ctx, s.fireSchedule)
+	}()
+
+	// Expire old artifacts, output and finished jobs
+	if cfg.ArtifactRetention > 0 || cfg.OutputRetention > 0 || cfg.JobRetention > 0 || cfg.MaxFinishedJobs > 0 {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.runRetention()
+		}()
 	}
 
 	return s, nil
 }
 
+// recoverJobs reloads persisted jobs and re-enqueues unfinished ones
+// according to each job's recovery policy. Jobs that were running when the
+// scheduler stopped are marked as interrupted.
+func (s *Scheduler) recoverJobs() error {
+	records, err := s.registry.store.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		s.registry.restore(record)
+
+		job := record.Job
+		switch job.Status {
+		case JobStatusPending, JobStatusScheduled, JobStatusBlocked, JobStatusRetrying:
+			if job.Recovery == RecoveryDiscard {
+				job.Status = JobStatusCancelled
+				job.Error = "discarded on scheduler restart"
+				job.EndTime = time.Now()
+				s.registry.update(job, &JobResult{
+					JobID:   job.ID,
+					Status:  job.Status,
+					Error:   job.Error,
+					EndTime: job.EndTime,
+				})
+				s.recordEvent(newJobEvent(EventFinished, job))
+				continue
+			}
+			if job.Status == JobStatusScheduled {
+				// Still waiting for its run time
+				s.mu.Lock()
+				s.scheduleJob(job)
+				s.mu.Unlock()
+				continue
+			}
+			if job.Status == JobStatusBlocked {
+				// Still waiting for its dependencies, resolved below
+				s.mu.Lock()
+				s.getOrCreateChannel(job)
+				s.mu.Unlock()
+				continue
+			}
+		case JobStatusRunning, JobStatusInterrupted:
+			if job.Recovery != "" && job.Recovery != RecoveryRequeue {
+				if job.Status == JobStatusRunning {
+					job.Status = JobStatusInterrupted
+					job.Error = "interrupted by scheduler restart"
+					job.EndTime = time.Now()
+					s.registry.update(job, &JobResult{
+						JobID:     job.ID,
+						Status:    job.Status,
+						ExitCode:  -1,
+						Error:     job.Error,
+						StartTime: job.StartTime,
+						EndTime:   job.EndTime,
+					})
+					s.recordEvent(newJobEvent(EventFinished, job))
+				}
+				continue
+			}
+			job.Error = ""
+			job.EndTime = time.Time{}
+		default:
+			continue
+		}
+
+		job.Status = JobStatusPending
+		s.registry.update(job, nil)
+
+		s.mu.Lock()
+		s.enqueueWaiting(job)
+		s.mu.Unlock()
+	}
+
+	// Dependencies may have finished, or been discarded, while we were down
+	released, cancelled := s.registry.resolveBlocked()
+	s.startReleased(released)
+	s.finishCancelled(cancelled)
+
+	return nil
+}
+
+// enqueueWaiting submits a job the scheduler has already accepted, such as
+// a recovered job or a retry. These are never rejected, even if they take
+// the channel past its buffer size.
+func (s *Scheduler) enqueueWaiting(job JobPayload) {
+	channel, _ := s.getOrCreateChannel(job)
+	s.recordEvent(newJobEvent(EventQueued, job))
+	channel.queue.push(job, true)
+}
+
 // SubmitJob submits a new job for processing
 func (s *Scheduler) SubmitJob(job JobPayload) error {
 	if err := job.Validate(); err != nil {
-		return fmt.Errorf("invalid job payload: %v", err)
+		return fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	if err := s.applications.check(job); err != nil {
+		return err
 	}
+	job = job.withInputDependencies()
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -87,91 +283,221 @@ func (s *Scheduler) SubmitJob(job JobPayload) error {
 		return err
 	}
 
+	// Bound the jobs a channel holds, whether queued or waiting to be
+	if limit := channel.settings().MaxQueueSize; s.registry.unfinished(job.Channel) >= limit {
+		return fmt.Errorf("channel %s: %w (%d unfinished jobs)", job.Channel, ErrChannelFull, limit)
+	}
+
 	// Initialize job status
 	job.Status = JobStatusPending
 	job.StartTime = time.Now()
+	if job.Delay > 0 {
+		job.RunAt = job.StartTime.Add(job.Delay)
+	}
+	if job.RunAt.After(job.StartTime) {
+		job.Status = JobStatusScheduled
+	}
 
-	// Submit to channel
-	select {
-	case channel.Jobs <- job:
-		// Update statistics
-		s.updateStatsForNewJob(job.Channel)
-		return nil
-	default:
-		return fmt.Errorf("channel %s is full", job.Channel)
+	// Track the job before it becomes visible to the processor
+	job, err = s.registry.add(job)
+	if err != nil {
+		return err
+	}
+
+	// Turn away a job the buffer has no room for before it is recorded.
+	// Only workers take jobs off the queue while we hold the lock, so the
+	// room is still there when the job is pushed.
+	if job.Status == JobStatusPending {
+		if err := channel.queue.checkRoom(job.Channel); err != nil {
+			s.registry.remove(job.ID)
+			return err
+		}
+	}
+	submitted := newJobEvent(EventSubmitted, job)
+	submitted.Actor = job.SubmittedBy
+	s.recordEvent(submitted)
+
+	// Blocked jobs wait for their dependencies, and jobs whose dependencies
+	// already failed are cancelled on arrival
+	switch job.Status {
+	case JobStatusScheduled:
+		s.delays.add(job.ID, job.RunAt)
+	case JobStatusCancelled:
+		s.recordEvent(newJobEvent(EventFinished, job))
+	case JobStatusPending:
+		// Submit to channel, recording that it is queued before a worker
+		// can record that it started
+		s.recordEvent(newJobEvent(EventQueued, job))
+		if err := channel.queue.push(job, false); err != nil {
+			s.registry.remove(job.ID)
+			return err
+		}
+	}
+	return nil
+}
+
+// GetJobStatus returns the current state of a job
+func (s *Scheduler) GetJobStatus(jobID string) (*JobPayload, error) {
+	job, exists := s.registry.get(jobID)
+	if !exists {
+		return nil, fmt.Errorf("job %s: %w", jobID, ErrJobNotFound)
+	}
+	return &job, nil
+}
+
+// GetJobResult returns the exit code and captured output of a finished job
+func (s *Scheduler) GetJobResult(jobID string) (*JobResult, error) {
+	result, err := s.registry.result(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListJobs returns all known jobs in submission order. An empty channel or
+// status matches every job.
+func (s *Scheduler) ListJobs(channel, status string) ([]JobPayload, error) {
+	jobStatus := JobStatus(status)
+	if status != "" && !jobStatus.Valid() {
+		return nil, fmt.Errorf("unknown job status: %s", status)
+	}
+	return s.registry.list(channel, jobStatus), nil
+}
+
+// CancelJob cancels a queued job, or stops a running job. A running job is
+// reported as cancelled once its process has exited.
+func (s *Scheduler) CancelJob(jobID string) error {
+	return s.CancelJobAs(jobID, "")
+}
+
+// CancelJobAs cancels a job like CancelJob, recording who cancelled it in
+// the job's history
+func (s *Scheduler) CancelJobAs(jobID, actor string) error {
+	if err := s.cancelJob(jobID, actor); err != nil {
+		return err
+	}
+
+	// A job that never started is finished as soon as it is cancelled
+	if job, exists := s.registry.get(jobID); exists && job.Status.IsTerminal() {
+		s.jobFinished(job)
+	}
+	return nil
+}
+
+// cancelJob cancels a job without notifying its schedule
+func (s *Scheduler) cancelJob(jobID, actor string) error {
+	if err := s.registry.cancel(jobID); err != nil {
+		return err
+	}
+	job, _ := s.registry.get(jobID)
+	cancelled := newJobEvent(EventCancelled, job)
+	cancelled.Actor = actor
+	s.recordEvent(cancelled)
+	if job.Status.IsTerminal() {
+		s.recordEvent(newJobEvent(EventFinished, job))
+	}
+
+	// Free the queue slot of a job that had not started yet
+	s.delays.remove(jobID)
+	s.mu.RLock()
+	channel, exists := s.channels[job.Channel]
+	s.mu.RUnlock()
+	if exists {
+		channel.queue.remove(jobID)
+	}
+	return nil
+}
+
+// jobFinished is called whenever a job reaches its final status
+func (s *Scheduler) jobFinished(job JobPayload) {
+	s.logs.close(job.ID)
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	s.releaseDependents(job.ID)
+	if job.ScheduleID != "" {
+		s.scheduleRunFinished(job)
 	}
 }
 
-// getOrCreateChannel creates a new channel if it doesn't exist
+// getOrCreateChannel returns a job's channel, creating it if it doesn't
+// exist. A channel created this way takes its workers and timeout from the
+// job that created it; later jobs' values are ignored.
 func (s *Scheduler) getOrCreateChannel(job JobPayload) (*Channel, error) {
 	channel, exists := s.channels[job.Channel]
 	if !exists {
-		// Create new channel
 		workers := job.Workers
-		if workers <= 0 {
-			workers = s.config.DefaultWorkers
+		if workers < 0 {
+			workers = 0
 		}
-
 		timeout := job.Timeout
-		if timeout <= 0 {
-			timeout = s.config.DefaultTimeout
+		if timeout < 0 {
+			timeout = 0
 		}
-
-		channel = &Channel{
+		channel = s.createChannelLocked(ChannelConfig{
 			Name:    job.Channel,
-			Jobs:    make(chan JobPayload, s.config.ChannelBufferSize),
 			Workers: workers,
 			Timeout: timeout,
-		}
+		}, false)
+	}
 
-		// Initialize channel processor
-		processor := NewProcessor(ProcessorConfig{
-			Channel:       channel,
-			Executor:      s.executor,
-			ProcessLog:    s.processLog,
-			MaxOutputSize: s.config.MaxOutputSize,
-		})
+	return channel, nil
+}
+
+// retryPolicyFor returns the retry policy that applies to a job
+func (s *Scheduler) retryPolicyFor(job JobPayload) RetryPolicy {
+	if job.RetryPolicy != nil {
+		return *job.RetryPolicy
+	}
+	if policy, ok := s.config.ChannelRetryPolicies[job.Channel]; ok {
+		return policy
+	}
+	return s.config.RetryPolicy
+}
 
-		channel.processor = processor
-		s.channels[job.Channel] = channel
-		s.stats[job.Channel] = &ChannelStats{}
+// retryJob schedules another attempt of an unsuccessful job if its retry
+// policy allows it, returning false if the job should be finalised instead
+func (s *Scheduler) retryJob(job JobPayload, result JobResult) bool {
+	policy := s.retryPolicyFor(job)
+	if !policy.shouldRetry(job, result) {
+		return false
+	}
 
-		// Start the processor
-		s.wg.Add(1)
-		go func() {
-			defer s.wg.Done()
-			processor.Start(s.ctx)
-		}()
+	delay := policy.delay(job.RetryCount)
+	job.RetryCount++
+	job.Status = JobStatusRetrying
+	if !s.registry.retry(job, result) {
+		return false
 	}
 
-	return channel, nil
+	s.delays.add(job.ID, time.Now().Add(delay))
+	return true
 }
 
-// updateStatsForNewJob updates channel statistics for a new job
-func (s *Scheduler) updateStatsForNewJob(channelName string) {
-	stats := s.stats[channelName]
-	stats.TotalJobs++
-	stats.LastJobTime = time.Now()
+// scheduleJob creates the channel of a job that is not due yet and holds
+// the job until its run time
+func (s *Scheduler) scheduleJob(job JobPayload) {
+	s.getOrCreateChannel(job)
+	s.delays.add(job.ID, job.RunAt)
 }
 
-// GetChannelStats returns statistics for all channels
-func (s *Scheduler) GetChannelStats() map[string]*ChannelStats {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// Create a copy of stats
-	statsCopy := make(map[string]*ChannelStats)
-	for name, stats := range s.stats {
-		statsCopy[name] = &ChannelStats{
-			Workers:     s.channels[name].Workers,
-			ActiveJobs:  stats.ActiveJobs,
-			TotalJobs:   stats.TotalJobs,
-			FailedJobs:  stats.FailedJobs,
-			LastJobTime: stats.LastJobTime,
-		}
+// releaseJob enqueues a scheduled or retrying job once it is due. Jobs still
+// waiting at shutdown stay scheduled or retrying, so that a persistent store
+// picks them up again after a restart.
+func (s *Scheduler) releaseJob(jobID string) {
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	job, ok := s.registry.requeue(jobID)
+	if !ok {
+		return
 	}
 
-	return statsCopy
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueueWaiting(job)
 }
 
 // Shutdown gracefully shuts down the scheduler
@@ -202,6 +528,11 @@ func (s *Scheduler) Shutdown() error {
 	// Cleanup executor
 	s.executor.Cleanup()
 
+	// Close job store
+	if err := s.registry.store.Close(); err != nil {
+		log.Printf("Failed to close job store: %v", err)
+	}
+
 	// Close process log
 	if err := s.processLog.Close(); err != nil {
 		return fmt.Errorf("failed to close process log: %v", err)
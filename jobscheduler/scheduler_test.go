@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +15,12 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	// Sandboxed and limited jobs re-execute the test binary
+	MaybeRunHelper()
+	os.Exit(m.Run())
+}
+
 func TestScheduler(t *testing.T) {
 	// Create temporary directory for tests
 	tmpDir, err := os.MkdirTemp("", "scheduler-test-*")
@@ -130,7 +138,7 @@ func TestScheduler(t *testing.T) {
 
 		// Submit should fail
 		err := scheduler.SubmitJob(job)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidJob)
 	})
 
 	t.Run("Shutdown", func(t *testing.T) {
@@ -160,3 +168,490 @@ func TestSchedulerConfig(t *testing.T) {
 		assert.Error(t, cfg.Validate())
 	})
 }
+
+// newTestScheduler creates a scheduler rooted in a temporary directory
+func newTestScheduler(t *testing.T) *Scheduler {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	cfg := Config{
+		ProcessingLogPath: filepath.Join(tmpDir, "processing.log"),
+		DefaultWorkers:    1,
+		DefaultTimeout:    5 * time.Second,
+		MaxQueueSize:      100,
+		WorkDir:           tmpDir,
+		MaxOutputSize:     1024,
+		ShutdownTimeout:   5 * time.Second,
+		ChannelBufferSize: 10,
+	}
+
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { scheduler.Shutdown() })
+	return scheduler
+}
+
+// shellJob returns a job that runs script with sh in channel
+func shellJob(id, channel, script string) JobPayload {
+	return JobPayload{
+		ID:          id,
+		Channel:     channel,
+		Application: &ApplicationConfig{Name: "sh", Path: "sh", Args: []string{"-c", script}},
+	}
+}
+
+// sleepJob returns a job that sleeps for seconds in channel
+func sleepJob(id, channel, seconds string) JobPayload {
+	return JobPayload{
+		ID:          id,
+		Channel:     channel,
+		Application: &ApplicationConfig{Name: "sleep", Path: "sleep", Args: []string{seconds}},
+	}
+}
+
+// waitForStatus waits until a job reaches the expected status
+func waitForStatus(t *testing.T, scheduler *Scheduler, jobID string, status JobStatus) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		job, err := scheduler.GetJobStatus(jobID)
+		return err == nil && job.Status == status
+	}, 5*time.Second, 10*time.Millisecond, "job %s never reached status %s", jobID, status)
+}
+
+func TestJobRegistry(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	t.Run("GetJobStatus", func(t *testing.T) {
+		err := scheduler.SubmitJob(JobPayload{
+			ID:      "status-job",
+			Channel: "status-channel",
+			Application: &ApplicationConfig{
+				Name: "echo",
+				Path: "echo",
+				Args: []string{"done"},
+			},
+		})
+		require.NoError(t, err)
+
+		waitForStatus(t, scheduler, "status-job", JobStatusComplete)
+		job, err := scheduler.GetJobStatus("status-job")
+		require.NoError(t, err)
+		assert.Equal(t, "status-channel", job.Channel)
+		assert.False(t, job.EndTime.IsZero())
+
+		_, err = scheduler.GetJobStatus("missing-job")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+
+	t.Run("DuplicateJobID", func(t *testing.T) {
+		err := scheduler.SubmitJob(JobPayload{ID: "status-job", Channel: "status-channel"})
+		assert.ErrorIs(t, err, ErrJobExists)
+	})
+
+	t.Run("ListJobs", func(t *testing.T) {
+		jobs, err := scheduler.ListJobs("status-channel", "")
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "status-job", jobs[0].ID)
+
+		jobs, err = scheduler.ListJobs("", string(JobStatusRunning))
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		_, err = scheduler.ListJobs("", "bogus")
+		assert.Error(t, err)
+	})
+
+	t.Run("CancelQueuedJob", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(sleepJob("blocker", "cancel-channel", "1")))
+		require.NoError(t, scheduler.SubmitJob(sleepJob("queued", "cancel-channel", "1")))
+		waitForStatus(t, scheduler, "blocker", JobStatusRunning)
+
+		require.NoError(t, scheduler.CancelJob("queued"))
+		job, err := scheduler.GetJobStatus("queued")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCancelled, job.Status)
+
+		// The cancelled job must never start once the worker frees up
+		waitForStatus(t, scheduler, "blocker", JobStatusComplete)
+		time.Sleep(50 * time.Millisecond)
+		job, err = scheduler.GetJobStatus("queued")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCancelled, job.Status)
+	})
+
+	t.Run("CancelRunningJob", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(sleepJob("long-running", "running-channel", "5")))
+		waitForStatus(t, scheduler, "long-running", JobStatusRunning)
+
+		require.NoError(t, scheduler.CancelJob("long-running"))
+		waitForStatus(t, scheduler, "long-running", JobStatusCancelled)
+
+		err := scheduler.CancelJob("long-running")
+		assert.ErrorIs(t, err, ErrJobFinished)
+		assert.ErrorIs(t, scheduler.CancelJob("missing-job"), ErrJobNotFound)
+	})
+}
+
+func TestJobRetention(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	ids := func(jobs []JobPayload) []string {
+		var ids []string
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		return ids
+	}
+
+	for _, id := range []string{"first", "second", "third", "latest"} {
+		require.NoError(t, scheduler.SubmitJob(shellJob(id, "retention-channel", "true")))
+		waitForStatus(t, scheduler, id, JobStatusComplete)
+	}
+	require.NoError(t, scheduler.SubmitJob(shellJob("dead", "retention-channel", "exit 1")))
+	waitForStatus(t, scheduler, "dead", JobStatusFailed)
+	require.NoError(t, scheduler.SubmitJob(shellJob("parent", "retention-channel", "true")))
+	waitForStatus(t, scheduler, "parent", JobStatusComplete)
+	child := shellJob("child", "retention-channel", "true")
+	child.DependsOn = []string{"parent"}
+	child.Delay = time.Hour
+	require.NoError(t, scheduler.SubmitJob(child))
+
+	t.Run("MaxFinishedJobs", func(t *testing.T) {
+		// Neither dead letters nor jobs that others still wait on count
+		evicted := scheduler.registry.evict(time.Time{}, 1)
+		assert.Equal(t, []string{"first", "second", "third"}, evicted)
+
+		jobs, err := scheduler.ListJobs("", "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"latest", "dead", "parent", "child"}, ids(jobs))
+		records, err := scheduler.registry.store.Load()
+		require.NoError(t, err)
+		assert.Len(t, records, 4)
+		_, err = scheduler.JobEvents("first")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+
+	t.Run("JobRetention", func(t *testing.T) {
+		assert.Empty(t, scheduler.registry.evict(time.Now().Add(-time.Hour), 0))
+		assert.Equal(t, []string{"latest"}, scheduler.registry.evict(time.Now(), 0))
+
+		// A parent can go once its dependents have finished
+		require.NoError(t, scheduler.CancelJob("child"))
+		assert.Equal(t, []string{"parent", "child"}, scheduler.registry.evict(time.Now(), 0))
+		assert.Len(t, scheduler.ListDeadLetters(""), 1)
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.MaxFinishedJobs = -1
+		assert.Error(t, cfg.Validate())
+	})
+}
+
+func TestJobResult(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	t.Run("CapturesOutput", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(shellJob("echo-job", "result-channel", "echo hello")))
+
+		_, err := scheduler.GetJobResult("echo-job")
+		assert.Error(t, err)
+
+		waitForStatus(t, scheduler, "echo-job", JobStatusComplete)
+		result, err := scheduler.GetJobResult("echo-job")
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.ExitCode)
+		assert.Equal(t, "hello\n", result.Output)
+		assert.Equal(t, JobStatusComplete, result.Status)
+		assert.Equal(t, TerminationExited, result.Termination)
+	})
+
+	t.Run("CapturesFailure", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(shellJob("fail-job", "result-channel", "echo oops >&2; exit 3")))
+
+		waitForStatus(t, scheduler, "fail-job", JobStatusFailed)
+		result, err := scheduler.GetJobResult("fail-job")
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.ExitCode)
+		assert.Equal(t, "oops\n", result.Stderr)
+		assert.NotEmpty(t, result.Error)
+	})
+
+	t.Run("BoundsOutput", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(shellJob("big-job", "result-channel", "head -c 4096 /dev/zero")))
+
+		require.Eventually(t, func() bool {
+			_, err := scheduler.GetJobResult("big-job")
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+		result, err := scheduler.GetJobResult("big-job")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(result.Output), 1024)
+	})
+
+	t.Run("JobTimeout", func(t *testing.T) {
+		job := shellJob("job-timeout", "result-channel", "exec sleep 2")
+		job.Application.Timeout = 100 * time.Millisecond
+		job.Application.KillTimeout = time.Second
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		waitForStatus(t, scheduler, "job-timeout", JobStatusTimedOut)
+		result, err := scheduler.GetJobResult("job-timeout")
+		require.NoError(t, err)
+		assert.Equal(t, TerminationTerminated, result.Termination)
+		assert.Less(t, result.EndTime.Sub(result.StartTime), time.Second)
+	})
+
+	t.Run("GracefulTermination", func(t *testing.T) {
+		job := shellJob("graceful-job", "result-channel", "trap 'echo stopping; exit 3' TERM; while true; do sleep 0.05; done")
+		job.Application.Timeout = 100 * time.Millisecond
+		job.Application.KillTimeout = 5 * time.Second
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		waitForStatus(t, scheduler, "graceful-job", JobStatusTimedOut)
+		result, err := scheduler.GetJobResult("graceful-job")
+		require.NoError(t, err)
+		assert.Equal(t, TerminationTerminated, result.Termination)
+		assert.Equal(t, "stopping\n", result.Output)
+		assert.Equal(t, 3, result.ExitCode)
+	})
+
+	t.Run("KillAfterGracePeriod", func(t *testing.T) {
+		job := shellJob("stubborn-job", "result-channel", "trap '' TERM; while true; do sleep 0.05; done")
+		job.Application.Timeout = 100 * time.Millisecond
+		job.Application.KillTimeout = 300 * time.Millisecond
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		waitForStatus(t, scheduler, "stubborn-job", JobStatusTimedOut)
+		result, err := scheduler.GetJobResult("stubborn-job")
+		require.NoError(t, err)
+		assert.Equal(t, TerminationKilled, result.Termination)
+		assert.GreaterOrEqual(t, result.EndTime.Sub(result.StartTime), 400*time.Millisecond)
+	})
+
+	// alive reports whether the process whose pid was written to path is
+	// still running
+	alive := func(t *testing.T, path string) bool {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		stat, err := os.ReadFile(fmt.Sprintf("/proc/%s/stat", strings.TrimSpace(string(data))))
+		if err != nil {
+			return false
+		}
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		return fields[0] != "Z"
+	}
+
+	t.Run("KillsProcessGroup", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("process groups are only tracked on Linux")
+		}
+		pidFile := filepath.Join(t.TempDir(), "child.pid")
+		job := shellJob("group-timeout", "result-channel", fmt.Sprintf("sleep 30 & echo $! > %s; sleep 30", pidFile))
+		job.Application.Timeout = 100 * time.Millisecond
+		job.Application.KillTimeout = time.Second
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		waitForStatus(t, scheduler, "group-timeout", JobStatusTimedOut)
+		result, err := scheduler.GetJobResult("group-timeout")
+		require.NoError(t, err)
+		assert.Equal(t, TerminationTerminated, result.Termination)
+		assert.Empty(t, result.Survivors)
+		assert.Less(t, result.EndTime.Sub(result.StartTime), time.Second)
+		assert.False(t, alive(t, pidFile))
+	})
+
+	t.Run("KillsOrphans", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("process groups are only tracked on Linux")
+		}
+		pidFile := filepath.Join(t.TempDir(), "orphan.pid")
+		job := shellJob("orphan-job", "result-channel", fmt.Sprintf("sleep 30 & echo $! > %s; echo done", pidFile))
+		require.NoError(t, scheduler.SubmitJob(job))
+
+		waitForStatus(t, scheduler, "orphan-job", JobStatusComplete)
+		result, err := scheduler.GetJobResult("orphan-job")
+		require.NoError(t, err)
+		assert.Equal(t, "done\n", result.Output)
+		assert.Empty(t, result.Survivors)
+		assert.Less(t, result.EndTime.Sub(result.StartTime), time.Second)
+		assert.False(t, alive(t, pidFile))
+	})
+
+	t.Run("UnknownJob", func(t *testing.T) {
+		_, err := scheduler.GetJobResult("missing-job")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	retryJob := func(id, script string, policy *RetryPolicy) JobPayload {
+		job := shellJob(id, "retry-channel", script)
+		job.RetryPolicy = policy
+		return job
+	}
+
+	t.Run("Backoff", func(t *testing.T) {
+		policy := RetryPolicy{
+			InitialDelay:  100 * time.Millisecond,
+			MaxDelay:      300 * time.Millisecond,
+			BackoffFactor: 2,
+		}
+		assert.Equal(t, 100*time.Millisecond, policy.delay(0))
+		assert.Equal(t, 200*time.Millisecond, policy.delay(1))
+		assert.Equal(t, 300*time.Millisecond, policy.delay(5))
+
+		policy.Jitter = 0.5
+		for i := 0; i < 20; i++ {
+			delay := policy.delay(1)
+			assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+			assert.LessOrEqual(t, delay, 200*time.Millisecond)
+		}
+	})
+
+	t.Run("SucceedsAfterRetries", func(t *testing.T) {
+		counter := filepath.Join(t.TempDir(), "attempts")
+		script := fmt.Sprintf(`n=$(cat %[1]s 2>/dev/null || echo 0); n=$((n+1)); echo $n > %[1]s; [ $n -ge 3 ]`, counter)
+		policy := &RetryPolicy{MaxRetries: 3, InitialDelay: 10 * time.Millisecond, BackoffFactor: 2}
+		require.NoError(t, scheduler.SubmitJob(retryJob("flaky-job", script, policy)))
+
+		waitForStatus(t, scheduler, "flaky-job", JobStatusComplete)
+		job, err := scheduler.GetJobStatus("flaky-job")
+		require.NoError(t, err)
+		assert.Equal(t, 2, job.RetryCount)
+
+		// The failed attempts' errors do not outlive them
+		assert.Empty(t, job.Error)
+		result, err := scheduler.GetJobResult("flaky-job")
+		require.NoError(t, err)
+		assert.Empty(t, result.Error)
+		var last JobEvent
+		require.Eventually(t, func() bool {
+			history, err := scheduler.JobEvents("flaky-job")
+			require.NoError(t, err)
+			last = history[len(history)-1]
+			return last.Event == EventFinished
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Empty(t, last.Error)
+	})
+
+	t.Run("ExhaustsRetries", func(t *testing.T) {
+		policy := &RetryPolicy{MaxRetries: 2, InitialDelay: 10 * time.Millisecond}
+		require.NoError(t, scheduler.SubmitJob(retryJob("broken-job", "exit 1", policy)))
+
+		waitForStatus(t, scheduler, "broken-job", JobStatusFailed)
+		job, err := scheduler.GetJobStatus("broken-job")
+		require.NoError(t, err)
+		assert.Equal(t, 2, job.RetryCount)
+	})
+
+	t.Run("NonRetryableExitCode", func(t *testing.T) {
+		policy := &RetryPolicy{MaxRetries: 2, InitialDelay: 10 * time.Millisecond, NonRetryableExitCodes: []int{2}}
+		require.NoError(t, scheduler.SubmitJob(retryJob("fatal-job", "exit 2", policy)))
+
+		waitForStatus(t, scheduler, "fatal-job", JobStatusFailed)
+		job, err := scheduler.GetJobStatus("fatal-job")
+		require.NoError(t, err)
+		assert.Equal(t, 0, job.RetryCount)
+	})
+
+	t.Run("CancelWhileRetrying", func(t *testing.T) {
+		policy := &RetryPolicy{MaxRetries: 1, InitialDelay: time.Hour}
+		require.NoError(t, scheduler.SubmitJob(retryJob("waiting-job", "exit 1", policy)))
+
+		waitForStatus(t, scheduler, "waiting-job", JobStatusRetrying)
+		require.NoError(t, scheduler.CancelJob("waiting-job"))
+		waitForStatus(t, scheduler, "waiting-job", JobStatusCancelled)
+	})
+
+	t.Run("InvalidPolicy", func(t *testing.T) {
+		err := scheduler.SubmitJob(retryJob("invalid-job", "true", &RetryPolicy{MaxRetries: -1}))
+		assert.Error(t, err)
+	})
+}
+
+func TestDeadLetters(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	poisonJob := func(id string) JobPayload {
+		job := shellJob(id, "poison-channel", "echo bad input >&2; exit 4")
+		job.RetryPolicy = &RetryPolicy{MaxRetries: 1, InitialDelay: 10 * time.Millisecond}
+		return job
+	}
+
+	require.NoError(t, scheduler.SubmitJob(poisonJob("poison-1")))
+	require.NoError(t, scheduler.SubmitJob(poisonJob("poison-2")))
+	waitForStatus(t, scheduler, "poison-1", JobStatusFailed)
+	waitForStatus(t, scheduler, "poison-2", JobStatusFailed)
+
+	t.Run("ListAndInspect", func(t *testing.T) {
+		letters := scheduler.ListDeadLetters("poison-channel")
+		require.Len(t, letters, 2)
+		assert.Empty(t, scheduler.ListDeadLetters("other-channel"))
+
+		dl, err := scheduler.GetDeadLetter("poison-1")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusPending, dl.Job.Status)
+		assert.Equal(t, 0, dl.Job.RetryCount)
+		assert.Equal(t, JobStatusFailed, dl.Status)
+		assert.Equal(t, 4, dl.ExitCode)
+		require.Len(t, dl.Attempts, 2)
+		for i, attempt := range dl.Attempts {
+			assert.Equal(t, i+1, attempt.Attempt)
+			assert.Equal(t, 4, attempt.ExitCode)
+			assert.NotEmpty(t, attempt.Error)
+		}
+		assert.False(t, dl.DeadLetteredAt.IsZero())
+	})
+
+	t.Run("Requeue", func(t *testing.T) {
+		require.NoError(t, scheduler.RequeueDeadLetterAs("poison-1", "operator"))
+		_, err := scheduler.GetDeadLetter("poison-1")
+		assert.ErrorIs(t, err, ErrJobNotDeadLettered)
+
+		events, err := scheduler.JobEvents("poison-1")
+		require.NoError(t, err)
+		var requeued []JobEvent
+		for _, event := range events {
+			if event.Event == EventRequeued {
+				requeued = append(requeued, event)
+			}
+		}
+		require.Len(t, requeued, 1)
+		assert.Equal(t, "operator", requeued[0].Actor)
+
+		// The job fails again and returns with its history extended
+		require.Eventually(t, func() bool {
+			dl, err := scheduler.GetDeadLetter("poison-1")
+			return err == nil && len(dl.Attempts) == 4
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		require.NoError(t, scheduler.PurgeDeadLetter("poison-2"))
+		_, err := scheduler.GetJobStatus("poison-2")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+
+		purged, err := scheduler.PurgeDeadLetters("poison-channel")
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		assert.Empty(t, scheduler.ListDeadLetters(""))
+	})
+
+	t.Run("NotDeadLettered", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(JobPayload{
+			ID:          "healthy-job",
+			Channel:     "poison-channel",
+			Application: &ApplicationConfig{Name: "true", Path: "true"},
+		}))
+		waitForStatus(t, scheduler, "healthy-job", JobStatusComplete)
+
+		assert.ErrorIs(t, scheduler.RequeueDeadLetter("healthy-job"), ErrJobNotDeadLettered)
+		assert.ErrorIs(t, scheduler.PurgeDeadLetter("healthy-job"), ErrJobNotDeadLettered)
+		assert.ErrorIs(t, scheduler.RequeueDeadLetter("missing-job"), ErrJobNotFound)
+	})
+}
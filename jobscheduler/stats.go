@@ -0,0 +1,358 @@
+package jobscheduler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// throughputWindow is how far back a channel's throughput looks
+	throughputWindow = 5 * time.Minute
+
+	// durationSamples is how many of a channel's latest runs its run
+	// duration percentiles are taken from
+	durationSamples = 1000
+)
+
+// JobCounts counts jobs by how they ended
+type JobCounts struct {
+	TotalJobs       int64 `json:"total_jobs"` // Submitted
+	CompletedJobs   int64 `json:"completed_jobs"`
+	FailedJobs      int64 `json:"failed_jobs"`
+	TimedOutJobs    int64 `json:"timed_out_jobs"`
+	CancelledJobs   int64 `json:"cancelled_jobs"`
+	InterruptedJobs int64 `json:"interrupted_jobs"`
+	RetriedJobs     int64 `json:"retried_jobs"` // Failed attempts that were retried
+}
+
+// count counts a job that ended with a terminal status
+func (c *JobCounts) count(status JobStatus) {
+	switch status {
+	case JobStatusComplete:
+		c.CompletedJobs++
+	case JobStatusFailed:
+		c.FailedJobs++
+	case JobStatusTimedOut:
+		c.TimedOutJobs++
+	case JobStatusCancelled:
+		c.CancelledJobs++
+	case JobStatusInterrupted:
+		c.InterruptedJobs++
+	}
+}
+
+// add adds other's counts to c
+func (c *JobCounts) add(other JobCounts) {
+	c.TotalJobs += other.TotalJobs
+	c.CompletedJobs += other.CompletedJobs
+	c.FailedJobs += other.FailedJobs
+	c.TimedOutJobs += other.TimedOutJobs
+	c.CancelledJobs += other.CancelledJobs
+	c.InterruptedJobs += other.InterruptedJobs
+	c.RetriedJobs += other.RetriedJobs
+}
+
+// Percentiles of how long runs took, in seconds
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// ChannelStats represents statistics for a channel. Counts start when the
+// channel is created, so a restart starts them again.
+type ChannelStats struct {
+	JobCounts
+	Workers     int         `json:"workers"`
+	ActiveJobs  []string    `json:"active_jobs"`   // Running now
+	QueuedJobs  int         `json:"queued_jobs"`   // Waiting for a worker
+	LastJobTime time.Time   `json:"last_job_time"` // When a job was last submitted
+	Since       time.Time   `json:"since"`         // When the channel was created
+	Throughput  float64     `json:"throughput"`    // Jobs finished per minute over the last five minutes
+	RunDuration Percentiles `json:"run_duration"`  // Of the latest thousand runs, retried ones included
+}
+
+// OverallStats sums up the statistics of every channel
+type OverallStats struct {
+	JobCounts
+	ActiveJobs     int           `json:"active_jobs"`
+	QueuedJobs     int           `json:"queued_jobs"`
+	ActiveChannels int           `json:"active_channels"` // Channels with jobs queued or running
+	Throughput     float64       `json:"throughput"`      // Jobs finished per minute over the last five minutes
+	Uptime         time.Duration `json:"uptime"`
+	LastUpdate     time.Time     `json:"last_update"` // When a job last changed
+}
+
+// StatsSummary totals the jobs submitted within a time range
+type StatsSummary struct {
+	JobCounts
+	From           time.Time `json:"from,omitempty"`
+	To             time.Time `json:"to,omitempty"`
+	AverageRuntime float64   `json:"average_runtime"` // Seconds the last attempt of each finished job ran for, on average
+	ActiveChannels int       `json:"active_channels"` // Channels the jobs were submitted to
+}
+
+// channelStats accumulates the statistics of a channel from its jobs'
+// events
+type channelStats struct {
+	counts    JobCounts
+	since     time.Time
+	lastJob   time.Time
+	queued    map[string]bool
+	running   map[string]bool
+	finished  []time.Time // Within the throughput window, oldest first
+	durations []float64   // The latest runs, as a ring once full
+	next      int         // Where the ring's next run goes
+}
+
+func newChannelStats(since time.Time) *channelStats {
+	return &channelStats{
+		since:   since,
+		queued:  make(map[string]bool),
+		running: make(map[string]bool),
+	}
+}
+
+// ran notes how long a run took
+func (c *channelStats) ran(seconds float64) {
+	if len(c.durations) < durationSamples {
+		c.durations = append(c.durations, seconds)
+		return
+	}
+	c.durations[c.next] = seconds
+	c.next = (c.next + 1) % durationSamples
+}
+
+// trim forgets the jobs that finished before the throughput window
+func (c *channelStats) trim(now time.Time) {
+	cutoff := now.Add(-throughputWindow)
+	i := sort.Search(len(c.finished), func(i int) bool {
+		return c.finished[i].After(cutoff)
+	})
+	c.finished = c.finished[i:]
+}
+
+// throughput returns how many jobs finished per minute over the throughput
+// window, or since the channel was created if that is sooner, but no
+// sooner than a minute ago
+func (c *channelStats) throughput(now time.Time) float64 {
+	c.trim(now)
+
+	window := now.Sub(c.since)
+	if window > throughputWindow {
+		window = throughputWindow
+	}
+	if window < time.Minute {
+		window = time.Minute
+	}
+	return float64(len(c.finished)) / window.Minutes()
+}
+
+// percentiles returns the run duration percentiles
+func (c *channelStats) percentiles() Percentiles {
+	if len(c.durations) == 0 {
+		return Percentiles{}
+	}
+	sorted := append([]float64(nil), c.durations...)
+	sort.Float64s(sorted)
+	rank := func(p float64) float64 {
+		return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+	}
+	return Percentiles{P50: rank(0.50), P95: rank(0.95), P99: rank(0.99)}
+}
+
+// snapshot returns the channel's statistics, all but its workers
+func (c *channelStats) snapshot(now time.Time) ChannelStats {
+	active := make([]string, 0, len(c.running))
+	for id := range c.running {
+		active = append(active, id)
+	}
+	sort.Strings(active)
+	return ChannelStats{
+		JobCounts:   c.counts,
+		ActiveJobs:  active,
+		QueuedJobs:  len(c.queued),
+		LastJobTime: c.lastJob,
+		Since:       c.since,
+		Throughput:  c.throughput(now),
+		RunDuration: c.percentiles(),
+	}
+}
+
+// statsRecorder keeps the statistics of every channel up to date from the
+// events the scheduler records. Each event is applied under a single lock,
+// so statistics are never read half updated.
+type statsRecorder struct {
+	mu       sync.Mutex
+	started  time.Time
+	updated  time.Time
+	channels map[string]*channelStats
+}
+
+func newStatsRecorder(now time.Time) *statsRecorder {
+	return &statsRecorder{started: now, channels: make(map[string]*channelStats)}
+}
+
+// open starts counting for a new channel
+func (r *statsRecorder) open(name string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels[name] = newChannelStats(now)
+}
+
+// forget drops the statistics of a deleted channel
+func (r *statsRecorder) forget(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.channels, name)
+}
+
+// record applies an event to its channel's statistics. Events for a
+// channel that has been deleted, such as its last jobs being cancelled, are
+// not counted.
+func (r *statsRecorder) record(event JobEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.updated = event.Time
+	c, ok := r.channels[event.Channel]
+	if !ok {
+		return
+	}
+
+	switch event.Event {
+	case EventSubmitted:
+		c.counts.TotalJobs++
+		c.lastJob = event.Time
+	case EventQueued:
+		c.queued[event.JobID] = true
+	case EventStarted:
+		delete(c.queued, event.JobID)
+		c.running[event.JobID] = true
+	case EventSkipped:
+		delete(c.queued, event.JobID)
+	case EventRetrying:
+		c.counts.RetriedJobs++
+		if c.running[event.JobID] {
+			c.ran(event.Duration)
+			delete(c.running, event.JobID)
+		}
+	case EventFinished:
+		c.counts.count(event.Status)
+		c.finished = append(c.finished, event.Time)
+		c.trim(event.Time)
+		if c.running[event.JobID] {
+			c.ran(event.Duration)
+			delete(c.running, event.JobID)
+		}
+		delete(c.queued, event.JobID)
+	}
+}
+
+// snapshot returns the statistics of the named channels
+func (r *statsRecorder) snapshot(names []string, now time.Time) map[string]ChannelStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make(map[string]ChannelStats, len(names))
+	for _, name := range names {
+		if c, ok := r.channels[name]; ok {
+			stats[name] = c.snapshot(now)
+		} else {
+			stats[name] = newChannelStats(now).snapshot(now)
+		}
+	}
+	return stats
+}
+
+// overall sums up the statistics of every channel
+func (r *statsRecorder) overall(now time.Time) OverallStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	overall := OverallStats{Uptime: now.Sub(r.started), LastUpdate: r.updated}
+	for _, c := range r.channels {
+		overall.JobCounts.add(c.counts)
+		overall.ActiveJobs += len(c.running)
+		overall.QueuedJobs += len(c.queued)
+		overall.Throughput += c.throughput(now)
+		if len(c.running) > 0 || len(c.queued) > 0 {
+			overall.ActiveChannels++
+		}
+	}
+	return overall
+}
+
+// summary totals the jobs still kept that were submitted within a time
+// range, either end of which may be zero to leave it open
+func (r *jobRegistry) summary(from, to time.Time) StatsSummary {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	summary := StatsSummary{From: from, To: to}
+	channels := make(map[string]bool)
+	var runtime float64
+	var ran int
+	for _, id := range r.order {
+		entry := r.jobs[id]
+		if len(entry.events) == 0 || entry.events[0].Event != EventSubmitted {
+			continue
+		}
+		submitted := entry.events[0].Time
+		if (!from.IsZero() && submitted.Before(from)) || (!to.IsZero() && !submitted.Before(to)) {
+			continue
+		}
+
+		job := entry.job
+		channels[job.Channel] = true
+		summary.TotalJobs++
+		summary.RetriedJobs += int64(job.RetryCount)
+		summary.count(job.Status)
+		if last := entry.events[len(entry.events)-1]; last.Event == EventFinished && last.ExitCode != nil {
+			runtime += last.Duration
+			ran++
+		}
+	}
+	summary.ActiveChannels = len(channels)
+	if ran > 0 {
+		summary.AverageRuntime = runtime / float64(ran)
+	}
+	return summary
+}
+
+// GetChannelStats returns statistics for all channels
+func (s *Scheduler) GetChannelStats() map[string]*ChannelStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.channels))
+	for name := range s.channels {
+		names = append(names, name)
+	}
+	stats := make(map[string]*ChannelStats, len(names))
+	for name, stat := range s.stats.snapshot(names, time.Now()) {
+		stat := stat
+		stat.Workers = s.channels[name].settings().Workers
+		stats[name] = &stat
+	}
+	return stats
+}
+
+// GetOverallStats sums up the statistics of every channel
+func (s *Scheduler) GetOverallStats() OverallStats {
+	return s.stats.overall(time.Now())
+}
+
+// GetStatsSummary totals the jobs submitted from one time until another,
+// either of which may be zero to leave that end open. Only jobs that have
+// not been purged are counted.
+func (s *Scheduler) GetStatsSummary(from, to time.Time) (*StatsSummary, error) {
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return nil, fmt.Errorf("time range ends before it starts")
+	}
+	summary := s.registry.summary(from, to)
+	return &summary, nil
+}
@@ -0,0 +1,175 @@
+package jobscheduler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelStats(t *testing.T) {
+	scheduler := newTestScheduler(t)
+	gate := filepath.Join(t.TempDir(), "continue")
+
+	channelStats := func() *ChannelStats {
+		stats := scheduler.GetChannelStats()
+		require.Contains(t, stats, "stats-channel")
+		return stats["stats-channel"]
+	}
+
+	t.Run("Load", func(t *testing.T) {
+		require.NoError(t, scheduler.SubmitJob(shellJob("holder", "stats-channel", "while [ ! -e "+gate+" ]; do sleep 0.01; done")))
+		require.Eventually(t, func() bool {
+			return len(channelStats().ActiveJobs) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, scheduler.SubmitJob(shellJob("waiter", "stats-channel", "true")))
+
+		stats := channelStats()
+		assert.Equal(t, []string{"holder"}, stats.ActiveJobs)
+		assert.Equal(t, 1, stats.QueuedJobs)
+		assert.Equal(t, 1, stats.Workers)
+
+		overall := scheduler.GetOverallStats()
+		assert.Equal(t, 1, overall.ActiveJobs)
+		assert.Equal(t, 1, overall.QueuedJobs)
+		assert.Equal(t, 1, overall.ActiveChannels)
+
+		require.NoError(t, scheduler.CancelJob("waiter"))
+		require.NoError(t, os.WriteFile(gate, nil, 0644))
+		waitForStatus(t, scheduler, "holder", JobStatusComplete)
+	})
+
+	t.Run("Outcomes", func(t *testing.T) {
+		failing := shellJob("failing", "stats-channel", "exit 3")
+		failing.RetryPolicy = &RetryPolicy{MaxRetries: 1, InitialDelay: 10 * time.Millisecond}
+		require.NoError(t, scheduler.SubmitJob(failing))
+		waitForStatus(t, scheduler, "failing", JobStatusFailed)
+
+		slow := shellJob("slow", "stats-channel", "sleep 1")
+		slow.Application.Timeout = 100 * time.Millisecond
+		require.NoError(t, scheduler.SubmitJob(slow))
+		waitForStatus(t, scheduler, "slow", JobStatusTimedOut)
+
+		// Statistics are counted from the events recorded as jobs finish
+		var stats *ChannelStats
+		require.Eventually(t, func() bool {
+			stats = channelStats()
+			return stats.TimedOutJobs == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, JobCounts{
+			TotalJobs:     4,
+			CompletedJobs: 1,
+			FailedJobs:    1,
+			TimedOutJobs:  1,
+			CancelledJobs: 1,
+			RetriedJobs:   1,
+		}, stats.JobCounts)
+		assert.Empty(t, stats.ActiveJobs)
+		assert.NotNil(t, stats.ActiveJobs)
+		assert.Zero(t, stats.QueuedJobs)
+		assert.False(t, stats.LastJobTime.IsZero())
+
+		// Four jobs finished within the first minute
+		assert.Equal(t, float64(4), stats.Throughput)
+		assert.GreaterOrEqual(t, stats.RunDuration.P99, 0.1)
+		assert.LessOrEqual(t, stats.RunDuration.P50, stats.RunDuration.P95)
+
+		overall := scheduler.GetOverallStats()
+		assert.Equal(t, stats.JobCounts, overall.JobCounts)
+		assert.Zero(t, overall.ActiveChannels)
+		assert.Positive(t, overall.Uptime)
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		summary, err := scheduler.GetStatsSummary(time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), summary.TotalJobs)
+		assert.Equal(t, int64(1), summary.CompletedJobs)
+		assert.Equal(t, int64(1), summary.RetriedJobs)
+		assert.Equal(t, 1, summary.ActiveChannels)
+		assert.Positive(t, summary.AverageRuntime)
+
+		summary, err = scheduler.GetStatsSummary(time.Now(), time.Time{})
+		require.NoError(t, err)
+		assert.Zero(t, summary.TotalJobs)
+
+		_, err = scheduler.GetStatsSummary(time.Now(), time.Now().Add(-time.Hour))
+		assert.Error(t, err)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		require.NoError(t, scheduler.DeleteChannel("stats-channel"))
+		assert.NotContains(t, scheduler.GetChannelStats(), "stats-channel")
+		assert.Zero(t, scheduler.GetOverallStats().TotalJobs)
+	})
+}
+
+func TestRunStatistics(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Percentiles", func(t *testing.T) {
+		stats := newChannelStats(start)
+		assert.Equal(t, Percentiles{}, stats.percentiles())
+
+		// Only the latest runs count
+		for i := 0; i < durationSamples; i++ {
+			stats.ran(1000)
+		}
+		for i := 100; i >= 1; i-- {
+			stats.ran(float64(i))
+		}
+		assert.Len(t, stats.durations, durationSamples)
+		for i := 0; i < durationSamples-100; i++ {
+			stats.ran(float64(i%100 + 1))
+		}
+		assert.Equal(t, Percentiles{P50: 50, P95: 95, P99: 99}, stats.percentiles())
+	})
+
+	t.Run("Throughput", func(t *testing.T) {
+		stats := newChannelStats(start)
+		finish := func(at time.Duration) {
+			stats.finished = append(stats.finished, start.Add(at))
+		}
+
+		// A young channel's throughput is taken over at least a minute
+		finish(10 * time.Second)
+		assert.Equal(t, float64(1), stats.throughput(start.Add(30*time.Second)))
+		finish(time.Minute)
+		assert.InDelta(t, 2/1.5, stats.throughput(start.Add(90*time.Second)), 1e-9)
+
+		// Later, only the last five minutes count
+		for i := 2; i < 20; i++ {
+			finish(time.Duration(i) * time.Minute)
+		}
+		assert.Equal(t, float64(1), stats.throughput(start.Add(19*time.Minute+30*time.Second)))
+		assert.Len(t, stats.finished, 5)
+	})
+
+	t.Run("Record", func(t *testing.T) {
+		recorder := newStatsRecorder(start)
+		recorder.open("kept", start)
+
+		// Finished jobs are only remembered for the throughput window,
+		// whether or not anyone asks
+		for i := 0; i < 20; i++ {
+			recorder.record(JobEvent{
+				JobID:   fmt.Sprintf("job-%d", i),
+				Channel: "kept",
+				Event:   EventFinished,
+				Status:  JobStatusComplete,
+				Time:    start.Add(time.Duration(i) * time.Minute),
+			})
+		}
+		assert.Len(t, recorder.channels["kept"].finished, 5)
+		assert.Equal(t, int64(20), recorder.channels["kept"].counts.CompletedJobs)
+
+		// A deleted channel's last events do not bring it back
+		recorder.forget("kept")
+		recorder.record(JobEvent{JobID: "late", Channel: "kept", Event: EventFinished, Status: JobStatusCancelled, Time: start})
+		assert.NotContains(t, recorder.channels, "kept")
+	})
+}
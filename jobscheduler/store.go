@@ -0,0 +1,486 @@
+package jobscheduler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JobRecord is the persisted form of a tracked job
+type JobRecord struct {
+	Job            JobPayload   `json:"job"`
+	Result         *JobResult   `json:"result,omitempty"`
+	Attempts       []JobAttempt `json:"attempts,omitempty"`
+	Events         []JobEvent   `json:"events,omitempty"`
+	DeadLetteredAt time.Time    `json:"dead_lettered_at"`
+}
+
+// JobStore persists job state so that queued and running jobs survive a
+// restart of the process that owns the scheduler
+type JobStore interface {
+	// Save creates or replaces the record for a job
+	Save(record JobRecord) error
+
+	// Delete removes the record for a job
+	Delete(jobID string) error
+
+	// Load returns every stored record in the order jobs were first saved
+	Load() ([]JobRecord, error)
+
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// ScheduleStore is implemented by job stores that can also persist
+// recurring schedules. Schedules are kept in memory only when the configured
+// store does not implement it.
+type ScheduleStore interface {
+	// SaveSchedule creates or replaces a schedule
+	SaveSchedule(schedule Schedule) error
+
+	// DeleteSchedule removes a schedule
+	DeleteSchedule(id string) error
+
+	// LoadSchedules returns every stored schedule in creation order
+	LoadSchedules() ([]Schedule, error)
+}
+
+// EventStore is implemented by job stores that can add an event to a job's
+// record without rewriting the rest of it. Other stores have the whole
+// record saved again for each event.
+type EventStore interface {
+	// AppendEvent adds an event to the history of a stored job
+	AppendEvent(event JobEvent) error
+}
+
+// MemoryStore is a JobStore that keeps records in memory only. It is the
+// default store and loses all state when the process exits.
+type MemoryStore struct {
+	mu            sync.RWMutex
+	records       map[string]JobRecord
+	order         []string
+	schedules     map[string]Schedule
+	scheduleOrder []string
+}
+
+// NewMemoryStore creates an empty in-memory job store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records:   make(map[string]JobRecord),
+		schedules: make(map[string]Schedule),
+	}
+}
+
+// Save creates or replaces the record for a job
+func (m *MemoryStore) Save(record JobRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.records[record.Job.ID]; !exists {
+		m.order = append(m.order, record.Job.ID)
+	}
+	m.records[record.Job.ID] = record
+	return nil
+}
+
+// Delete removes the record for a job
+func (m *MemoryStore) Delete(jobID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.records[jobID]; !exists {
+		return nil
+	}
+	delete(m.records, jobID)
+	m.order = removeID(m.order, jobID)
+	return nil
+}
+
+// Load returns every stored record
+func (m *MemoryStore) Load() ([]JobRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := make([]JobRecord, 0, len(m.order))
+	for _, id := range m.order {
+		records = append(records, m.records[id])
+	}
+	return records, nil
+}
+
+// SaveSchedule creates or replaces a schedule
+func (m *MemoryStore) SaveSchedule(schedule Schedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.schedules[schedule.ID]; !exists {
+		m.scheduleOrder = append(m.scheduleOrder, schedule.ID)
+	}
+	m.schedules[schedule.ID] = schedule
+	return nil
+}
+
+// DeleteSchedule removes a schedule
+func (m *MemoryStore) DeleteSchedule(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.schedules[id]; !exists {
+		return nil
+	}
+	delete(m.schedules, id)
+	m.scheduleOrder = removeID(m.scheduleOrder, id)
+	return nil
+}
+
+// LoadSchedules returns every stored schedule
+func (m *MemoryStore) LoadSchedules() ([]Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schedules := make([]Schedule, 0, len(m.scheduleOrder))
+	for _, id := range m.scheduleOrder {
+		schedules = append(schedules, m.schedules[id])
+	}
+	return schedules, nil
+}
+
+// Close is a no-op for the in-memory store
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// walEntry is a single line of the file store's write-ahead log
+type walEntry struct {
+	Op       string     `json:"op"`
+	ID       string     `json:"id,omitempty"`
+	Record   *JobRecord `json:"record,omitempty"`
+	Event    *JobEvent  `json:"event,omitempty"`
+	Schedule *Schedule  `json:"schedule,omitempty"`
+}
+
+const (
+	walOpSave           = "save"
+	walOpDelete         = "delete"
+	walOpEvent          = "event"
+	walOpSaveSchedule   = "save_schedule"
+	walOpDeleteSchedule = "delete_schedule"
+
+	// Compact once the log holds this many more lines than live records
+	walCompactThreshold = 1000
+)
+
+// FileStore is a JobStore backed by a single append-only log file. Every
+// change is written as one JSON line and synced to disk before returning,
+// except events, which are only synced along with the next change.
+// The log is replayed and compacted to a snapshot of the live records when
+// the store is opened, and again whenever it grows well beyond that size.
+type FileStore struct {
+	path          string
+	mu            sync.Mutex
+	file          *os.File
+	records       map[string]JobRecord
+	order         []string
+	schedules     map[string]Schedule
+	scheduleOrder []string
+	lines         int // Lines in the log file
+}
+
+// NewFileStore opens or creates a file-backed job store at path
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %v", err)
+	}
+
+	f := &FileStore{
+		path:      path,
+		records:   make(map[string]JobRecord),
+		schedules: make(map[string]Schedule),
+	}
+	if err := f.replay(); err != nil {
+		return nil, err
+	}
+	if err := f.compact(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// replay rebuilds the in-memory view from the log file
+func (f *FileStore) replay() error {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open job store: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A trailing line without a newline is a write torn by a crash
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read job store: %v", err)
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry walEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("corrupt job store %s at line %d: %v", f.path, lineNum, err)
+		}
+		f.apply(entry)
+	}
+}
+
+// apply updates the in-memory view with a single log entry
+func (f *FileStore) apply(entry walEntry) {
+	switch entry.Op {
+	case walOpSave:
+		if entry.Record == nil {
+			return
+		}
+		id := entry.Record.Job.ID
+		if _, exists := f.records[id]; !exists {
+			f.order = append(f.order, id)
+		}
+		f.records[id] = *entry.Record
+	case walOpEvent:
+		if entry.Event == nil {
+			return
+		}
+		record, exists := f.records[entry.Event.JobID]
+		if !exists {
+			return
+		}
+		// The record's events may share their array with the caller's
+		events := record.Events[:len(record.Events):len(record.Events)]
+		record.Events = append(events, *entry.Event)
+		f.records[entry.Event.JobID] = record
+	case walOpDelete:
+		if _, exists := f.records[entry.ID]; exists {
+			delete(f.records, entry.ID)
+			f.order = removeID(f.order, entry.ID)
+		}
+	case walOpSaveSchedule:
+		if entry.Schedule == nil {
+			return
+		}
+		id := entry.Schedule.ID
+		if _, exists := f.schedules[id]; !exists {
+			f.scheduleOrder = append(f.scheduleOrder, id)
+		}
+		f.schedules[id] = *entry.Schedule
+	case walOpDeleteSchedule:
+		if _, exists := f.schedules[entry.ID]; exists {
+			delete(f.schedules, entry.ID)
+			f.scheduleOrder = removeID(f.scheduleOrder, entry.ID)
+		}
+	}
+}
+
+// live returns the number of records and schedules in the in-memory view
+func (f *FileStore) live() int {
+	return len(f.order) + len(f.scheduleOrder)
+}
+
+// compact rewrites the log as a snapshot of the live records and reopens it
+// for appending. The snapshot replaces the old log atomically.
+func (f *FileStore) compact() error {
+	tmpPath := f.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create job store snapshot: %v", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, id := range f.order {
+		record := f.records[id]
+		if err := encoder.Encode(walEntry{Op: walOpSave, Record: &record}); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write job store snapshot: %v", err)
+		}
+	}
+	for _, id := range f.scheduleOrder {
+		schedule := f.schedules[id]
+		if err := encoder.Encode(walEntry{Op: walOpSaveSchedule, Schedule: &schedule}); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write job store snapshot: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write job store snapshot: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync job store snapshot: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close job store snapshot: %v", err)
+	}
+
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		return fmt.Errorf("failed to replace job store: %v", err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job store: %v", err)
+	}
+	f.file = file
+	f.lines = f.live()
+	return nil
+}
+
+// append writes a log entry, syncs it and applies it to the in-memory view
+func (f *FileStore) append(entry walEntry) error {
+	return f.write(entry, true)
+}
+
+// write writes a log entry, syncing it if asked to, and applies it to the
+// in-memory view
+func (f *FileStore) write(entry walEntry, sync bool) error {
+	if f.file == nil {
+		return fmt.Errorf("job store is closed")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode job record: %v", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := f.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write job store: %v", err)
+	}
+	if sync {
+		if err := f.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync job store: %v", err)
+		}
+	}
+
+	f.apply(entry)
+	f.lines++
+	if f.lines-f.live() > walCompactThreshold {
+		return f.compact()
+	}
+	return nil
+}
+
+// Save creates or replaces the record for a job
+func (f *FileStore) Save(record JobRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.append(walEntry{Op: walOpSave, Record: &record})
+}
+
+// Delete removes the record for a job
+func (f *FileStore) Delete(jobID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.records[jobID]; !exists {
+		return nil
+	}
+	return f.append(walEntry{Op: walOpDelete, ID: jobID})
+}
+
+// AppendEvent adds an event to the history of a stored job. It is written
+// without being synced, so a crash may lose the events recorded since the
+// job last changed.
+func (f *FileStore) AppendEvent(event JobEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.records[event.JobID]; !exists {
+		return nil
+	}
+	return f.write(walEntry{Op: walOpEvent, Event: &event}, false)
+}
+
+// Load returns every stored record
+func (f *FileStore) Load() ([]JobRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := make([]JobRecord, 0, len(f.order))
+	for _, id := range f.order {
+		records = append(records, f.records[id])
+	}
+	return records, nil
+}
+
+// SaveSchedule creates or replaces a schedule
+func (f *FileStore) SaveSchedule(schedule Schedule) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.append(walEntry{Op: walOpSaveSchedule, Schedule: &schedule})
+}
+
+// DeleteSchedule removes a schedule
+func (f *FileStore) DeleteSchedule(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.schedules[id]; !exists {
+		return nil
+	}
+	return f.append(walEntry{Op: walOpDeleteSchedule, ID: id})
+}
+
+// LoadSchedules returns every stored schedule
+func (f *FileStore) LoadSchedules() ([]Schedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	schedules := make([]Schedule, 0, len(f.scheduleOrder))
+	for _, id := range f.scheduleOrder {
+		schedules = append(schedules, f.schedules[id])
+	}
+	return schedules, nil
+}
+
+// Close closes the underlying log file
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// removeID removes the first occurrence of id from ids
+func removeID(ids []string, id string) []string {
+	for i, existing := range ids {
+		if existing == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,153 @@
+package jobscheduler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+
+	t.Run("ReplaysRecords", func(t *testing.T) {
+		store, err := NewFileStore(path)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Save(JobRecord{Job: JobPayload{ID: "a", Status: JobStatusPending}}))
+		require.NoError(t, store.Save(JobRecord{Job: JobPayload{ID: "b", Status: JobStatusPending}}))
+		require.NoError(t, store.Save(JobRecord{Job: JobPayload{ID: "a", Status: JobStatusRunning}}))
+		require.NoError(t, store.Delete("b"))
+		require.NoError(t, store.Close())
+
+		store, err = NewFileStore(path)
+		require.NoError(t, err)
+		defer store.Close()
+
+		records, err := store.Load()
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "a", records[0].Job.ID)
+		assert.Equal(t, JobStatusRunning, records[0].Job.Status)
+	})
+
+	t.Run("IgnoresTornWrite", func(t *testing.T) {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		require.NoError(t, err)
+		_, err = f.WriteString(`{"op":"save","record":{"job":{"id":"c"`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		store, err := NewFileStore(path)
+		require.NoError(t, err)
+		defer store.Close()
+
+		records, err := store.Load()
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "a", records[0].Job.ID)
+	})
+
+	t.Run("AppendsEvents", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.wal")
+		store, err := NewFileStore(path)
+		require.NoError(t, err)
+
+		record := JobRecord{
+			Job:    JobPayload{ID: "d", Status: JobStatusPending},
+			Events: []JobEvent{{JobID: "d", Event: EventSubmitted}},
+		}
+		require.NoError(t, store.Save(record))
+		require.NoError(t, store.AppendEvent(JobEvent{JobID: "d", Event: EventQueued}))
+		require.NoError(t, store.AppendEvent(JobEvent{JobID: "unknown", Event: EventQueued}))
+		require.NoError(t, store.Close())
+
+		// Events are written on their own, leaving the saved record be
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), `"op":"save"`))
+		assert.Equal(t, 1, strings.Count(string(data), `"op":"event"`))
+		assert.Len(t, record.Events, 1)
+
+		store, err = NewFileStore(path)
+		require.NoError(t, err)
+		defer store.Close()
+
+		records, err := store.Load()
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, []EventType{EventSubmitted, EventQueued}, []EventType{records[0].Events[0].Event, records[0].Events[1].Event})
+	})
+
+	t.Run("RejectsCorruptLog", func(t *testing.T) {
+		corrupt := filepath.Join(t.TempDir(), "corrupt.wal")
+		require.NoError(t, os.WriteFile(corrupt, []byte("not json\n"), 0644))
+
+		_, err := NewFileStore(corrupt)
+		assert.Error(t, err)
+	})
+}
+
+func TestJobRecovery(t *testing.T) {
+	tmpDir := t.TempDir()
+	storePath := filepath.Join(tmpDir, "jobs.wal")
+
+	// Simulate a crash that left jobs queued and running
+	store, err := NewFileStore(storePath)
+	require.NoError(t, err)
+	echo := &ApplicationConfig{Name: "echo", Path: "echo", Args: []string{"recovered"}}
+	records := []JobPayload{
+		{ID: "queued", Channel: "recovery", Application: echo, Status: JobStatusPending},
+		{ID: "running-requeue", Channel: "recovery", Application: echo, Status: JobStatusRunning},
+		{ID: "running-fail", Channel: "recovery", Application: echo, Status: JobStatusRunning, Recovery: RecoveryFail},
+		{ID: "queued-discard", Channel: "recovery", Application: echo, Status: JobStatusPending, Recovery: RecoveryDiscard},
+		{ID: "finished", Channel: "recovery", Application: echo, Status: JobStatusComplete},
+		{ID: "scheduled", Channel: "recovery", Application: echo, Status: JobStatusScheduled, RunAt: time.Now().Add(time.Second)},
+		{ID: "blocked", Channel: "recovery", Application: echo, Status: JobStatusBlocked, DependsOn: []string{"finished"}},
+	}
+	for _, job := range records {
+		require.NoError(t, store.Save(JobRecord{Job: job}))
+	}
+	require.NoError(t, store.Close())
+
+	cfg := DefaultConfig()
+	cfg.ProcessingLogPath = filepath.Join(tmpDir, "processing.log")
+	cfg.WorkDir = tmpDir
+	cfg.StorePath = storePath
+	scheduler, err := NewScheduler(cfg)
+	require.NoError(t, err)
+	defer scheduler.Shutdown()
+
+	waitForStatus(t, scheduler, "queued", JobStatusComplete)
+	waitForStatus(t, scheduler, "running-requeue", JobStatusComplete)
+
+	job, err := scheduler.GetJobStatus("running-fail")
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusInterrupted, job.Status)
+
+	job, err = scheduler.GetJobStatus("queued-discard")
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusCancelled, job.Status)
+
+	result, err := scheduler.GetJobResult("running-requeue")
+	require.NoError(t, err)
+	assert.Equal(t, "recovered\n", result.Output)
+
+	// Blocked jobs are released once their dependencies are found complete
+	waitForStatus(t, scheduler, "blocked", JobStatusComplete)
+
+	// Scheduled jobs keep waiting for their run time
+	job, err = scheduler.GetJobStatus("scheduled")
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusScheduled, job.Status)
+	waitForStatus(t, scheduler, "scheduled", JobStatusComplete)
+
+	// Finished jobs are restored as-is
+	job, err = scheduler.GetJobStatus("finished")
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusComplete, job.Status)
+}
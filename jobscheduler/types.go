@@ -3,6 +3,8 @@ package jobscheduler
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"slices"
 	"time"
 )
 
@@ -16,30 +18,193 @@ const (
 	JobStatusFailed    JobStatus = "failed"
 	JobStatusTimedOut  JobStatus = "timed_out"
 	JobStatusCancelled JobStatus = "cancelled"
+
+	// JobStatusScheduled marks a job that is waiting for its RunAt time
+	JobStatusScheduled JobStatus = "scheduled"
+
+	// JobStatusBlocked marks a job that is waiting for the jobs it depends
+	// on to complete
+	JobStatusBlocked JobStatus = "blocked"
+
+	// JobStatusRetrying marks a failed job that is waiting to be retried
+	JobStatusRetrying JobStatus = "retrying"
+
+	// JobStatusInterrupted marks a job that was running when the scheduler
+	// stopped, and which was not re-run when it started again
+	JobStatusInterrupted JobStatus = "interrupted"
+)
+
+// RecoveryPolicy controls what happens to an unfinished job when the
+// scheduler restarts with a persistent JobStore
+type RecoveryPolicy string
+
+const (
+	// RecoveryRequeue re-runs both queued jobs and jobs that were interrupted
+	// mid-run. It is the default.
+	RecoveryRequeue RecoveryPolicy = "requeue"
+
+	// RecoveryFail re-runs queued jobs but leaves interrupted jobs in the
+	// interrupted status. Use it for jobs that are not safe to run twice.
+	RecoveryFail RecoveryPolicy = "fail"
+
+	// RecoveryDiscard cancels queued jobs and leaves interrupted jobs in the
+	// interrupted status
+	RecoveryDiscard RecoveryPolicy = "discard"
+)
+
+// IsTerminal reports whether a job in this status will not run again
+func (s JobStatus) IsTerminal() bool {
+	switch s {
+	case JobStatusComplete, JobStatusFailed, JobStatusTimedOut, JobStatusCancelled, JobStatusInterrupted:
+		return true
+	}
+	return false
+}
+
+// Valid reports whether the status is one the scheduler knows about
+func (s JobStatus) Valid() bool {
+	switch s {
+	case JobStatusPending, JobStatusScheduled, JobStatusBlocked, JobStatusRunning, JobStatusRetrying:
+		return true
+	}
+	return s.IsTerminal()
+}
+
+// Job priority bounds; higher priority jobs are dequeued first
+const (
+	MinPriority = 0
+	MaxPriority = 10
 )
 
 // JobPayload represents the structure of a job submission
 type JobPayload struct {
 	ID          string             `json:"id"`
 	Channel     string             `json:"channel"`
-	Workers     int                `json:"workers,omitempty"`     // Only used for first job in channel
-	Timeout     time.Duration      `json:"timeout,omitempty"`     // Only used for first job in channel
-	Body        json.RawMessage    `json:"body"`                  // Arbitrary JSON data
-	Application *ApplicationConfig `json:"application,omitempty"` // Optional application configuration
+	Type        string             `json:"type,omitempty"`         // Selects the registered handler for jobs without an Application
+	Workers     int                `json:"workers,omitempty"`      // Only used for first job in channel
+	Timeout     time.Duration      `json:"timeout,omitempty"`      // Only used for first job in channel
+	Priority    int                `json:"priority,omitempty"`     // 0 (lowest) to 10 (most urgent)
+	RunAt       time.Time          `json:"run_at,omitempty"`       // Earliest time the job may start
+	Delay       time.Duration      `json:"delay,omitempty"`        // Start no sooner than this after submission
+	Body        json.RawMessage    `json:"body"`                   // Arbitrary JSON data
+	Application *ApplicationConfig `json:"application,omitempty"`  // Optional application configuration
+	Recovery    RecoveryPolicy     `json:"recovery,omitempty"`     // What to do with the job after a restart
+	RetryPolicy *RetryPolicy       `json:"retry_policy,omitempty"` // Overrides the channel and default retry policy
+	RetryCount  int                `json:"retry_count,omitempty"`  // Retries made so far
+	ScheduleID  string             `json:"schedule_id,omitempty"`  // Recurring schedule that created the job
+	DependsOn   []string           `json:"depends_on,omitempty"`   // Jobs that must complete before this one starts
+	WorkflowID  string             `json:"workflow_id,omitempty"`  // Workflow the job was submitted in
+	Untrusted   bool               `json:"untrusted,omitempty"`    // Submitted by someone not trusted to run arbitrary applications
+	SubmittedBy string             `json:"submitted_by,omitempty"` // Who submitted the job, recorded in its history
 	Status      JobStatus          `json:"status"`
 	Error       string             `json:"error,omitempty"`
 	StartTime   time.Time          `json:"start_time,omitempty"`
 	EndTime     time.Time          `json:"end_time,omitempty"`
+	Progress    *Progress          `json:"progress,omitempty"` // Latest report from the application of its current or last run
+}
+
+// Progress is how far a running application has said it has got. An
+// application reports it by writing lines to the file descriptor named by
+// $JOB_PROGRESS_FD, each holding a percentage, optionally followed by a
+// message, or a message alone.
+type Progress struct {
+	Percent   float64   `json:"percent"` // 0 to 100
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// ApplicationConfig defines the external application to run
+// ApplicationConfig defines the external application to run. Naming a
+// registered application runs it, with Path left empty or set to its path.
+// Each run starts in a scratch directory of its own, of which a relative
+// WorkingDir is a subdirectory.
 type ApplicationConfig struct {
 	Name        string            `json:"name"`
-	Path        string            `json:"path"`
+	Path        string            `json:"path,omitempty"`
 	Args        []string          `json:"args,omitempty"`
 	Env         map[string]string `json:"env,omitempty"`
 	WorkingDir  string            `json:"working_dir,omitempty"`
 	PassPayload bool              `json:"pass_payload,omitempty"`
+	Inputs      []JobInput        `json:"inputs,omitempty"`       // Output of earlier jobs to consume
+	Timeout     time.Duration     `json:"timeout,omitempty"`      // Overrides the channel timeout
+	KillTimeout time.Duration     `json:"kill_timeout,omitempty"` // Overrides the channel's grace period between SIGTERM and SIGKILL
+	Limits      ResourceLimits    `json:"limits,omitempty"`
+	Sandbox     *SandboxConfig    `json:"sandbox,omitempty"` // Run isolated from the host
+
+	// Glob patterns, relative to the working directory, of files to keep
+	// as artifacts once the application exits. The working directory must
+	// then be within the scratch directory.
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+// SandboxConfig isolates an application in Linux namespaces. It sees a
+// read-only filesystem apart from a scratch directory of its own, which it
+// starts in and which is removed when it exits; WorkingDir must then be a
+// subdirectory of it. It sees only its own processes, and has no network
+// unless Network is set.
+type SandboxConfig struct {
+	Network bool `json:"network,omitempty"` // Share the host's network
+}
+
+// ResourceLimits bounds the resources an application may use. Zero fields
+// are unlimited. Memory, CPU and process limits are enforced by a cgroup
+// when Config.CgroupRoot is set; otherwise memory bounds the address space,
+// and a CPU quota or process limit cannot be used.
+type ResourceLimits struct {
+	MemoryMax    int64   `json:"memory_max,omitempty"`     // Bytes
+	CPUQuota     float64 `json:"cpu_quota,omitempty"`      // CPUs' worth of time, e.g. 0.5 for half of one
+	MaxProcesses int     `json:"max_processes,omitempty"`  // Processes and threads at once
+	MaxOpenFiles int     `json:"max_open_files,omitempty"` // Per process
+	MaxFileSize  int64   `json:"max_file_size,omitempty"`  // Bytes in any one file the application writes
+}
+
+// Validate checks that no limit is negative
+func (l ResourceLimits) Validate() error {
+	if l.MemoryMax < 0 || l.CPUQuota < 0 || l.MaxProcesses < 0 || l.MaxOpenFiles < 0 || l.MaxFileSize < 0 {
+		return fmt.Errorf("resource limits cannot be negative")
+	}
+	return nil
+}
+
+// InputSource selects which captured output of an upstream job to consume
+type InputSource string
+
+const (
+	InputStdout   InputSource = "stdout" // Default
+	InputStderr   InputSource = "stderr"
+	InputArtifact InputSource = "artifact" // The artifact named by JobInput.Artifact
+)
+
+// JobInput feeds the captured output or an artifact of a completed job to
+// an application, either on its stdin or as a file in its working
+// directory. The upstream job becomes an implicit dependency.
+type JobInput struct {
+	JobID    string      `json:"job_id"`
+	Source   InputSource `json:"source,omitempty"`
+	Artifact string      `json:"artifact,omitempty"` // Name of the artifact, for the artifact source
+	File     string      `json:"file,omitempty"`     // Relative path to write to; stdin when empty
+}
+
+// Validate checks if the input is valid
+func (in JobInput) Validate() error {
+	if in.JobID == "" {
+		return fmt.Errorf("input job ID cannot be empty")
+	}
+	switch in.Source {
+	case "", InputStdout, InputStderr:
+		if in.Artifact != "" {
+			return fmt.Errorf("input artifact needs the artifact source")
+		}
+	case InputArtifact:
+		if in.Artifact == "" {
+			return fmt.Errorf("input artifact name cannot be empty")
+		}
+	default:
+		return fmt.Errorf("unknown input source: %s", in.Source)
+	}
+	if in.File != "" && !filepath.IsLocal(in.File) {
+		return fmt.Errorf("input file %q must be a relative path within the working directory", in.File)
+	}
+	return nil
 }
 
 // Validate checks if the job payload is valid
@@ -53,30 +218,145 @@ func (j *JobPayload) Validate() error {
 	if j.Workers < 0 {
 		return fmt.Errorf("workers cannot be negative")
 	}
+	if j.Priority < MinPriority || j.Priority > MaxPriority {
+		return fmt.Errorf("priority must be between %d and %d", MinPriority, MaxPriority)
+	}
+	if j.Delay < 0 {
+		return fmt.Errorf("delay cannot be negative")
+	}
+	if j.Delay > 0 && !j.RunAt.IsZero() {
+		return fmt.Errorf("only one of delay and run_at may be set")
+	}
+	for _, parent := range j.DependsOn {
+		if parent == j.ID {
+			return fmt.Errorf("job cannot depend on itself")
+		}
+	}
+	switch j.Recovery {
+	case "", RecoveryRequeue, RecoveryFail, RecoveryDiscard:
+	default:
+		return fmt.Errorf("unknown recovery policy: %s", j.Recovery)
+	}
+	if j.RetryPolicy != nil {
+		if err := j.RetryPolicy.Validate(); err != nil {
+			return fmt.Errorf("invalid retry policy: %v", err)
+		}
+	}
 	if j.Application != nil {
-		if j.Application.Path == "" {
-			return fmt.Errorf("application path cannot be empty")
+		if j.Application.Path == "" && j.Application.Name == "" {
+			return fmt.Errorf("application needs a path or the name of a registered application")
+		}
+		if j.Application.Timeout < 0 {
+			return fmt.Errorf("application timeout cannot be negative")
+		}
+		if j.Application.KillTimeout < 0 {
+			return fmt.Errorf("application kill timeout cannot be negative")
+		}
+		if err := j.Application.Limits.Validate(); err != nil {
+			return fmt.Errorf("application %v", err)
+		}
+		if dir := j.Application.WorkingDir; dir != "" && !filepath.IsLocal(dir) {
+			if j.Application.Sandbox != nil {
+				return fmt.Errorf("sandboxed application working directory must be a relative path")
+			}
+			if !filepath.IsAbs(dir) {
+				return fmt.Errorf("application working directory must be absolute or within its scratch directory")
+			}
+			if len(j.Application.Artifacts) > 0 {
+				return fmt.Errorf("artifacts can only be collected from a working directory within the scratch directory")
+			}
+		}
+		for _, pattern := range j.Application.Artifacts {
+			if err := validateArtifactPattern(pattern); err != nil {
+				return err
+			}
+		}
+		stdin := j.Application.PassPayload
+		files := make(map[string]bool)
+		for _, in := range j.Application.Inputs {
+			if err := in.Validate(); err != nil {
+				return fmt.Errorf("invalid input: %v", err)
+			}
+			if in.JobID == j.ID {
+				return fmt.Errorf("job cannot consume its own output")
+			}
+			if in.File == "" {
+				if stdin {
+					return fmt.Errorf("only one of pass_payload and a stdin input may be used")
+				}
+				stdin = true
+				continue
+			}
+			if files[filepath.Clean(in.File)] {
+				return fmt.Errorf("input file %s is written more than once", in.File)
+			}
+			files[filepath.Clean(in.File)] = true
 		}
 	}
 	return nil
 }
 
-// ChannelStats represents statistics for a channel
-type ChannelStats struct {
-	Workers     int       `json:"workers"`
-	ActiveJobs  []string  `json:"active_jobs"`
-	TotalJobs   int64     `json:"total_jobs"`
-	FailedJobs  int64     `json:"failed_jobs"`
-	LastJobTime time.Time `json:"last_job_time"`
+// withInputDependencies returns the job with the jobs its application takes
+// input from added to DependsOn, so it only runs once they have completed
+func (j JobPayload) withInputDependencies() JobPayload {
+	if j.Application == nil || len(j.Application.Inputs) == 0 {
+		return j
+	}
+	deps := append([]string(nil), j.DependsOn...)
+	for _, in := range j.Application.Inputs {
+		if !slices.Contains(deps, in.JobID) {
+			deps = append(deps, in.JobID)
+		}
+	}
+	j.DependsOn = deps
+	return j
 }
 
 // JobResult represents the result of a job execution
 type JobResult struct {
-	JobID     string
-	Status    JobStatus
-	ExitCode  int
-	Output    string
-	Error     string
-	StartTime time.Time
-	EndTime   time.Time
+	JobID         string        `json:"job_id"`
+	Status        JobStatus     `json:"status"`
+	ExitCode      int           `json:"exit_code"`
+	Output        string        `json:"output,omitempty"`         // Captured stdout, its head and tail if longer than Config.MaxOutputSize
+	Stderr        string        `json:"stderr,omitempty"`         // Captured stderr, likewise
+	OutputOmitted int64         `json:"output_omitted,omitempty"` // Bytes omitted from the middle of Output, which OpenOutput still returns
+	StderrOmitted int64         `json:"stderr_omitted,omitempty"` // Bytes omitted from the middle of Stderr
+	Error         string        `json:"error,omitempty"`
+	Termination   Termination   `json:"termination,omitempty"`    // How an application's process stopped
+	Survivors     []int         `json:"survivors,omitempty"`      // Processes of the application that could not be killed
+	FailureReason FailureReason `json:"failure_reason,omitempty"` // Resource limit that made the application fail
+	Artifacts     []Artifact    `json:"artifacts,omitempty"`      // Files kept from the application's working directory
+	WorkDir       string        `json:"work_dir,omitempty"`       // Scratch directory, if the cleanup policy kept it
+	StartTime     time.Time     `json:"start_time"`
+	EndTime       time.Time     `json:"end_time"`
+}
+
+// Termination describes how an application's process came to stop
+type Termination string
+
+const (
+	TerminationExited     Termination = "exited"     // Exited on its own
+	TerminationTerminated Termination = "terminated" // Exited after SIGTERM, within the grace period
+	TerminationKilled     Termination = "killed"     // Killed with SIGKILL once the grace period ran out
+)
+
+// FailureReason names the resource limit that made an application fail
+type FailureReason string
+
+const (
+	FailureOOMKilled     FailureReason = "oom_killed"      // Killed for exceeding its memory limit
+	FailureFileSizeLimit FailureReason = "file_size_limit" // Killed for writing a file over MaxFileSize
+	FailureProcessLimit  FailureReason = "process_limit"   // Failed after being refused more than MaxProcesses
+)
+
+// JobAttempt records the outcome of a single run of a job
+type JobAttempt struct {
+	Attempt       int           `json:"attempt"`
+	Status        JobStatus     `json:"status"`
+	ExitCode      int           `json:"exit_code"`
+	Error         string        `json:"error,omitempty"`
+	Termination   Termination   `json:"termination,omitempty"`
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+	StartTime     time.Time     `json:"start_time"`
+	EndTime       time.Time     `json:"end_time"`
 }
@@ -0,0 +1,366 @@
+package jobscheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrDependencyCycle is returned when a workflow's dependencies form a cycle
+	ErrDependencyCycle = errors.New("dependency cycle")
+
+	// ErrWorkflowNotFound is returned when no jobs belong to a workflow ID
+	ErrWorkflowNotFound = errors.New("workflow not found")
+
+	// ErrWorkflowExists is returned when a workflow is submitted under an ID already in use
+	ErrWorkflowExists = errors.New("workflow already exists")
+)
+
+// Workflow is a set of jobs submitted together, whose DependsOn links form
+// a directed acyclic graph. A job may also depend on jobs submitted earlier
+// outside the workflow.
+type Workflow struct {
+	ID   string       `json:"id"`
+	Jobs []JobPayload `json:"jobs"`
+}
+
+// WorkflowStatus is the aggregate state of the jobs in a workflow
+type WorkflowStatus struct {
+	ID        string            `json:"id"`
+	Status    JobStatus         `json:"status"` // pending, running, complete, failed or cancelled
+	Counts    map[JobStatus]int `json:"counts"` // Jobs in each status
+	Jobs      []JobPayload      `json:"jobs"`
+	StartTime time.Time         `json:"start_time,omitempty"`
+	EndTime   time.Time         `json:"end_time,omitempty"` // Set once every job has finished
+}
+
+// dependencyStateLocked reports whether every job a job depends on has
+// completed, or the reason it never can. The caller must hold r.mu.
+func (r *jobRegistry) dependencyStateLocked(job JobPayload) (ready bool, reason string) {
+	ready = true
+	for _, parentID := range job.DependsOn {
+		parent, exists := r.jobs[parentID]
+		switch {
+		case !exists:
+			return false, fmt.Sprintf("dependency %s no longer exists", parentID)
+		case parent.job.Status == JobStatusComplete:
+		case parent.job.Status.IsTerminal():
+			return false, fmt.Sprintf("dependency %s did not complete (%s)", parentID, parent.job.Status)
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
+
+// indexDependentsLocked records a job against each job it depends on. The
+// caller must hold r.mu.
+func (r *jobRegistry) indexDependentsLocked(job JobPayload) {
+	for _, parentID := range job.DependsOn {
+		r.dependents[parentID] = append(r.dependents[parentID], job.ID)
+	}
+}
+
+// resolveLocked re-evaluates a blocked job, releasing it if its dependencies
+// have all completed or cancelling it if one never will. It reports which of
+// the two happened. The caller must hold r.mu.
+func (r *jobRegistry) resolveLocked(entry *jobEntry) (released, cancelled bool) {
+	if entry.job.Status != JobStatusBlocked {
+		return false, false
+	}
+
+	ready, reason := r.dependencyStateLocked(entry.job)
+	switch {
+	case reason != "":
+		entry.cancelLocked(reason)
+		r.persist(entry)
+		return false, true
+	case ready:
+		entry.job.Status = JobStatusPending
+		if entry.job.RunAt.After(time.Now()) {
+			entry.job.Status = JobStatusScheduled
+		}
+		r.persist(entry)
+		return true, false
+	}
+	return false, false
+}
+
+// resolveDependents re-evaluates the blocked jobs that depend on a job which
+// has just finished
+func (r *jobRegistry) resolveDependents(parentID string) (released, cancelled []JobPayload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.dependents[parentID] {
+		entry, exists := r.jobs[id]
+		if !exists {
+			continue
+		}
+		switch rel, can := r.resolveLocked(entry); {
+		case rel:
+			released = append(released, entry.job)
+		case can:
+			cancelled = append(cancelled, entry.job)
+		}
+	}
+	return released, cancelled
+}
+
+// resolveBlocked re-evaluates every blocked job, such as after a restart
+func (r *jobRegistry) resolveBlocked() (released, cancelled []JobPayload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.order {
+		entry := r.jobs[id]
+		switch rel, can := r.resolveLocked(entry); {
+		case rel:
+			released = append(released, entry.job)
+		case can:
+			cancelled = append(cancelled, entry.job)
+		}
+	}
+	return released, cancelled
+}
+
+// workflowJobs returns the jobs of a workflow in submission order
+func (r *jobRegistry) workflowJobs(workflowID string) []JobPayload {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var jobs []JobPayload
+	for _, id := range r.order {
+		if job := r.jobs[id].job; job.WorkflowID == workflowID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// releaseDependents starts or cancels the jobs waiting on a finished job,
+// cascading cancellation further downstream
+func (s *Scheduler) releaseDependents(parentID string) {
+	released, cancelled := s.registry.resolveDependents(parentID)
+	s.startReleased(released)
+	s.finishCancelled(cancelled)
+}
+
+// startReleased hands jobs whose dependencies have completed to their
+// channels, or to the delay queue if they are not yet due
+func (s *Scheduler) startReleased(jobs []JobPayload) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range jobs {
+		if job.Status == JobStatusScheduled {
+			s.delays.add(job.ID, job.RunAt)
+			continue
+		}
+		s.enqueueWaiting(job)
+	}
+}
+
+// workflowOrder checks a workflow's jobs and returns them ordered so that
+// every job comes after the jobs it depends on
+func (s *Scheduler) workflowOrder(workflow Workflow) ([]JobPayload, error) {
+	jobs := make(map[string]JobPayload, len(workflow.Jobs))
+	for _, job := range workflow.Jobs {
+		job.WorkflowID = workflow.ID
+		if err := job.Validate(); err != nil {
+			return nil, fmt.Errorf("job %s: %w: %v", job.ID, ErrInvalidJob, err)
+		}
+		if err := s.applications.check(job); err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.ID, err)
+		}
+		job = job.withInputDependencies()
+		if _, exists := jobs[job.ID]; exists {
+			return nil, fmt.Errorf("job %s: %w: duplicate job ID", job.ID, ErrInvalidJob)
+		}
+		if _, exists := s.registry.get(job.ID); exists {
+			return nil, fmt.Errorf("job %s: %w", job.ID, ErrJobExists)
+		}
+		jobs[job.ID] = job
+	}
+
+	// Kahn's algorithm over the dependencies within the workflow
+	waiting := make(map[string]int, len(jobs))
+	children := make(map[string][]string)
+	for _, job := range workflow.Jobs {
+		for _, parentID := range jobs[job.ID].DependsOn {
+			if _, inWorkflow := jobs[parentID]; inWorkflow {
+				waiting[job.ID]++
+				children[parentID] = append(children[parentID], job.ID)
+			} else if _, exists := s.registry.get(parentID); !exists {
+				return nil, fmt.Errorf("job %s depends on %s: %w", job.ID, parentID, ErrDependencyNotFound)
+			}
+		}
+	}
+
+	var ready []string
+	for _, job := range workflow.Jobs {
+		if waiting[job.ID] == 0 {
+			ready = append(ready, job.ID)
+		}
+	}
+	order := make([]JobPayload, 0, len(jobs))
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, jobs[id])
+		for _, child := range children[id] {
+			waiting[child]--
+			if waiting[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+
+	if len(order) != len(jobs) {
+		var stuck []string
+		for _, job := range workflow.Jobs {
+			if waiting[job.ID] > 0 {
+				stuck = append(stuck, job.ID)
+			}
+		}
+		return nil, fmt.Errorf("jobs %v: %w", stuck, ErrDependencyCycle)
+	}
+	return order, nil
+}
+
+// SubmitWorkflow submits a set of jobs linked by their DependsOn fields.
+// Each job is held until the jobs it depends on complete, and is cancelled
+// if any of them fails. The workflow is rejected as a whole if its
+// dependencies contain a cycle or name an unknown job, and withdrawn if any
+// of its jobs cannot be submitted.
+func (s *Scheduler) SubmitWorkflow(workflow Workflow) error {
+	if workflow.ID == "" {
+		return fmt.Errorf("workflow ID cannot be empty")
+	}
+	if len(workflow.Jobs) == 0 {
+		return fmt.Errorf("workflow %s has no jobs", workflow.ID)
+	}
+
+	s.workflowMu.Lock()
+	defer s.workflowMu.Unlock()
+	if len(s.registry.workflowJobs(workflow.ID)) > 0 {
+		return fmt.Errorf("workflow %s: %w", workflow.ID, ErrWorkflowExists)
+	}
+
+	order, err := s.workflowOrder(workflow)
+	if err != nil {
+		return fmt.Errorf("invalid workflow %s: %w", workflow.ID, err)
+	}
+
+	submitted := make([]string, 0, len(order))
+	for _, job := range order {
+		if err := s.SubmitJob(job); err != nil {
+			// Withdraw what was already submitted, dependents first, so
+			// that the workflow ID can be used again
+			for i := len(submitted) - 1; i >= 0; i-- {
+				s.cancelJob(submitted[i], "")
+				s.registry.remove(submitted[i])
+			}
+			return fmt.Errorf("failed to submit job %s of workflow %s: %w", job.ID, workflow.ID, err)
+		}
+		submitted = append(submitted, job.ID)
+	}
+	return nil
+}
+
+// GetWorkflowStatus returns the aggregate state of a workflow's jobs
+func (s *Scheduler) GetWorkflowStatus(workflowID string) (*WorkflowStatus, error) {
+	jobs := s.registry.workflowJobs(workflowID)
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("workflow %s: %w", workflowID, ErrWorkflowNotFound)
+	}
+	status := workflowStatus(workflowID, jobs)
+	return &status, nil
+}
+
+// ListWorkflows returns the aggregate state of every workflow, in the order
+// they were submitted
+func (s *Scheduler) ListWorkflows() []WorkflowStatus {
+	byWorkflow := make(map[string][]JobPayload)
+	var ids []string
+	for _, job := range s.registry.list("", "") {
+		if job.WorkflowID == "" {
+			continue
+		}
+		if _, seen := byWorkflow[job.WorkflowID]; !seen {
+			ids = append(ids, job.WorkflowID)
+		}
+		byWorkflow[job.WorkflowID] = append(byWorkflow[job.WorkflowID], job)
+	}
+
+	workflows := make([]WorkflowStatus, 0, len(ids))
+	for _, id := range ids {
+		workflows = append(workflows, workflowStatus(id, byWorkflow[id]))
+	}
+	return workflows
+}
+
+// CancelWorkflow cancels every unfinished job in a workflow
+func (s *Scheduler) CancelWorkflow(workflowID string) error {
+	return s.CancelWorkflowAs(workflowID, "")
+}
+
+// CancelWorkflowAs cancels a workflow like CancelWorkflow, recording who
+// cancelled it in the history of each job
+func (s *Scheduler) CancelWorkflowAs(workflowID, actor string) error {
+	jobs := s.registry.workflowJobs(workflowID)
+	if len(jobs) == 0 {
+		return fmt.Errorf("workflow %s: %w", workflowID, ErrWorkflowNotFound)
+	}
+
+	for _, job := range jobs {
+		if err := s.CancelJobAs(job.ID, actor); err != nil && !errors.Is(err, ErrJobFinished) {
+			return err
+		}
+	}
+	return nil
+}
+
+// workflowStatus aggregates the state of a workflow's jobs
+func workflowStatus(id string, jobs []JobPayload) WorkflowStatus {
+	status := WorkflowStatus{
+		ID:     id,
+		Counts: make(map[JobStatus]int),
+		Jobs:   jobs,
+	}
+
+	finished := 0
+	for _, job := range jobs {
+		status.Counts[job.Status]++
+		if !job.StartTime.IsZero() && (status.StartTime.IsZero() || job.StartTime.Before(status.StartTime)) {
+			status.StartTime = job.StartTime
+		}
+		if job.Status.IsTerminal() {
+			finished++
+			if job.EndTime.After(status.EndTime) {
+				status.EndTime = job.EndTime
+			}
+		}
+	}
+
+	counts := status.Counts
+	switch {
+	case finished < len(jobs):
+		status.EndTime = time.Time{}
+		status.Status = JobStatusRunning
+		if finished == 0 && counts[JobStatusRunning]+counts[JobStatusRetrying] == 0 {
+			status.Status = JobStatusPending
+		}
+	case counts[JobStatusComplete] == len(jobs):
+		status.Status = JobStatusComplete
+	case counts[JobStatusFailed]+counts[JobStatusTimedOut]+counts[JobStatusInterrupted] > 0:
+		status.Status = JobStatusFailed
+	default:
+		status.Status = JobStatusCancelled
+	}
+	return status
+}
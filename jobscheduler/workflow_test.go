@@ -0,0 +1,211 @@
+package jobscheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflows(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	workflowJob := func(id, script string, dependsOn ...string) JobPayload {
+		job := shellJob(id, "workflow-channel", script)
+		job.Workers = 2
+		job.DependsOn = dependsOn
+		return job
+	}
+
+	t.Run("Chain", func(t *testing.T) {
+		err := scheduler.SubmitWorkflow(Workflow{
+			ID: "etl",
+			Jobs: []JobPayload{
+				workflowJob("load", "echo load", "transform"),
+				workflowJob("transform", "echo transform", "extract"),
+				workflowJob("extract", "sleep 0.2"),
+			},
+		})
+		require.NoError(t, err)
+
+		job, err := scheduler.GetJobStatus("load")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusBlocked, job.Status)
+		assert.Equal(t, "etl", job.WorkflowID)
+
+		waitForStatus(t, scheduler, "load", JobStatusComplete)
+		extract, err := scheduler.GetJobResult("extract")
+		require.NoError(t, err)
+		transform, err := scheduler.GetJobResult("transform")
+		require.NoError(t, err)
+		assert.False(t, transform.StartTime.Before(extract.EndTime))
+
+		status, err := scheduler.GetWorkflowStatus("etl")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusComplete, status.Status)
+		assert.Equal(t, 3, status.Counts[JobStatusComplete])
+		assert.False(t, status.EndTime.IsZero())
+	})
+
+	t.Run("FailureCascades", func(t *testing.T) {
+		err := scheduler.SubmitWorkflow(Workflow{
+			ID: "broken",
+			Jobs: []JobPayload{
+				workflowJob("fails", "exit 1"),
+				workflowJob("child", "echo child", "fails"),
+				workflowJob("grandchild", "echo grandchild", "child"),
+				workflowJob("independent", "echo independent"),
+			},
+		})
+		require.NoError(t, err)
+
+		waitForStatus(t, scheduler, "grandchild", JobStatusCancelled)
+		waitForStatus(t, scheduler, "independent", JobStatusComplete)
+		job, err := scheduler.GetJobStatus("child")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCancelled, job.Status)
+		assert.Contains(t, job.Error, "fails")
+
+		status, err := scheduler.GetWorkflowStatus("broken")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusFailed, status.Status)
+
+		// Depending on a failed job cancels the new job straight away
+		require.NoError(t, scheduler.SubmitJob(workflowJob("late", "echo late", "fails")))
+		job, err = scheduler.GetJobStatus("late")
+		require.NoError(t, err)
+		assert.Equal(t, JobStatusCancelled, job.Status)
+	})
+
+	t.Run("RejectsCycles", func(t *testing.T) {
+		err := scheduler.SubmitWorkflow(Workflow{
+			ID: "cyclic",
+			Jobs: []JobPayload{
+				workflowJob("a", "true", "c"),
+				workflowJob("b", "true", "a"),
+				workflowJob("c", "true", "b"),
+				workflowJob("d", "true"),
+			},
+		})
+		assert.ErrorIs(t, err, ErrDependencyCycle)
+
+		_, err = scheduler.GetJobStatus("d")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+		_, err = scheduler.GetWorkflowStatus("cyclic")
+		assert.ErrorIs(t, err, ErrWorkflowNotFound)
+	})
+
+	t.Run("RejectsUnknownDependency", func(t *testing.T) {
+		err := scheduler.SubmitWorkflow(Workflow{
+			ID:   "dangling",
+			Jobs: []JobPayload{workflowJob("orphan", "true", "missing")},
+		})
+		assert.ErrorIs(t, err, ErrDependencyNotFound)
+
+		err = scheduler.SubmitJob(workflowJob("orphan", "true", "missing"))
+		assert.ErrorIs(t, err, ErrDependencyNotFound)
+
+		err = scheduler.SubmitJob(workflowJob("self", "true", "self"))
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectsExistingIDs", func(t *testing.T) {
+		err := scheduler.SubmitWorkflow(Workflow{ID: "etl", Jobs: []JobPayload{workflowJob("etl-again", "true")}})
+		assert.ErrorIs(t, err, ErrWorkflowExists)
+
+		err = scheduler.SubmitWorkflow(Workflow{ID: "reused", Jobs: []JobPayload{workflowJob("extract", "true")}})
+		assert.ErrorIs(t, err, ErrJobExists)
+
+		err = scheduler.SubmitWorkflow(Workflow{ID: "twice", Jobs: []JobPayload{workflowJob("twin", "true"), workflowJob("twin", "true")}})
+		assert.ErrorIs(t, err, ErrInvalidJob)
+	})
+
+	t.Run("CancelCascades", func(t *testing.T) {
+		// The shell's sleep child holds the output pipes open, so the parent
+		// is only seen to stop if its whole process group is killed
+		require.NoError(t, scheduler.SubmitJob(workflowJob("slow-parent", "sleep 30; true")))
+		require.NoError(t, scheduler.SubmitJob(workflowJob("waiting-child", "true", "slow-parent")))
+		require.NoError(t, scheduler.SubmitJob(workflowJob("waiting-grandchild", "true", "waiting-child")))
+
+		require.NoError(t, scheduler.CancelJob("waiting-child"))
+		waitForStatus(t, scheduler, "waiting-grandchild", JobStatusCancelled)
+
+		require.NoError(t, scheduler.CancelJob("slow-parent"))
+		waitForStatus(t, scheduler, "slow-parent", JobStatusCancelled)
+	})
+
+	t.Run("ListWorkflows", func(t *testing.T) {
+		var ids []string
+		for _, workflow := range scheduler.ListWorkflows() {
+			ids = append(ids, workflow.ID)
+		}
+		assert.Equal(t, []string{"etl", "broken"}, ids)
+	})
+
+	t.Run("Inputs", func(t *testing.T) {
+		produce := workflowJob("produce", "sleep 0.2; echo hello; echo oops >&2")
+		consume := workflowJob("consume", "tr a-z A-Z; cat upstream/err.txt")
+		consume.Application.Inputs = []JobInput{
+			{JobID: "produce"},
+			{JobID: "produce", Source: InputStderr, File: "upstream/err.txt"},
+		}
+		require.NoError(t, scheduler.SubmitWorkflow(Workflow{
+			ID:   "piped",
+			Jobs: []JobPayload{consume, produce},
+		}))
+
+		job, err := scheduler.GetJobStatus("consume")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"produce"}, job.DependsOn)
+
+		waitForStatus(t, scheduler, "consume", JobStatusComplete)
+		result, err := scheduler.GetJobResult("consume")
+		require.NoError(t, err)
+		assert.Equal(t, "HELLO\noops\n", result.Output)
+	})
+
+	t.Run("InvalidInputs", func(t *testing.T) {
+		tests := map[string][]JobInput{
+			"own output": {{JobID: "invalid-input"}},
+			"no job":     {{File: "in.txt"}},
+			"bad source": {{JobID: "produce", Source: "exit_code"}},
+			"escapes":    {{JobID: "produce", File: "../in.txt"}},
+			"absolute":   {{JobID: "produce", File: "/tmp/in.txt"}},
+			"two stdins": {{JobID: "produce"}, {JobID: "produce", Source: InputStderr}},
+			"same file":  {{JobID: "produce", File: "in.txt"}, {JobID: "produce", File: "./in.txt"}},
+		}
+		for name, inputs := range tests {
+			job := workflowJob("invalid-input", "cat")
+			job.Application.Inputs = inputs
+			assert.Error(t, scheduler.SubmitJob(job), name)
+		}
+
+		job := workflowJob("invalid-input", "cat")
+		job.Application.PassPayload = true
+		job.Application.Inputs = []JobInput{{JobID: "produce"}}
+		assert.Error(t, scheduler.SubmitJob(job))
+
+		job.Application.Inputs = []JobInput{{JobID: "missing"}}
+		job.Application.PassPayload = false
+		assert.ErrorIs(t, scheduler.SubmitJob(job), ErrDependencyNotFound)
+	})
+
+	t.Run("WithdrawnOnFailure", func(t *testing.T) {
+		_, err := scheduler.CreateChannel(ChannelConfig{Name: "workflow-bounded", MaxQueueSize: 1})
+		require.NoError(t, err)
+		bounded := func(id string, dependsOn ...string) JobPayload {
+			job := workflowJob(id, "true", dependsOn...)
+			job.Channel = "workflow-bounded"
+			return job
+		}
+
+		err = scheduler.SubmitWorkflow(Workflow{ID: "overflow", Jobs: []JobPayload{bounded("first"), bounded("second", "first")}})
+		assert.ErrorIs(t, err, ErrChannelFull)
+		_, err = scheduler.GetJobStatus("first")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+
+		// The same workflow fits once it is smaller
+		require.NoError(t, scheduler.SubmitWorkflow(Workflow{ID: "overflow", Jobs: []JobPayload{bounded("first")}}))
+		waitForStatus(t, scheduler, "first", JobStatusComplete)
+	})
+}
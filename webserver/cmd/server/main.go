@@ -14,7 +14,11 @@ import (
 	"github.com/jonathanleahy/project/jobscheduler"
 	"github.com/jonathanleahy/project/webserver/config"
 	"github.com/jonathanleahy/project/webserver/internal/handlers"
+	"github.com/jonathanleahy/project/webserver/internal/metrics"
 	"github.com/jonathanleahy/project/webserver/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var (
@@ -23,6 +27,8 @@ var (
 )
 
 func main() {
+	// Sandboxed and limited jobs re-execute this binary
+	jobscheduler.MaybeRunHelper()
 	flag.Parse()
 
 	// Load configuration
@@ -32,20 +38,90 @@ func main() {
 	}
 
 	// Initialize job scheduler
+	channelRetryPolicies := make(map[string]jobscheduler.RetryPolicy)
+	for channel, policy := range cfg.Scheduler.ChannelRetryPolicies {
+		channelRetryPolicies[channel] = toRetryPolicy(policy)
+	}
+	channels := make([]jobscheduler.ChannelConfig, len(cfg.Scheduler.Channels))
+	for i, channel := range cfg.Scheduler.Channels {
+		channels[i] = jobscheduler.ChannelConfig{
+			Name:         channel.Name,
+			Workers:      channel.Workers,
+			Timeout:      channel.Timeout,
+			KillTimeout:  channel.KillTimeout,
+			BufferSize:   channel.BufferSize,
+			MaxQueueSize: channel.MaxQueueSize,
+			Sandbox:      channel.Sandbox,
+		}
+	}
+	applications := make([]jobscheduler.Application, len(cfg.Scheduler.Applications))
+	for i, app := range cfg.Scheduler.Applications {
+		applications[i] = jobscheduler.Application{
+			Name:        app.Name,
+			Path:        app.Path,
+			Args:        app.Args,
+			AllowedArgs: app.AllowedArgs,
+			AllowedEnv:  app.AllowedEnv,
+			Env:         app.Env,
+			Limits:      jobscheduler.ResourceLimits(app.Limits),
+			Checksum:    app.Checksum,
+		}
+	}
+	processingLog := jobscheduler.ProcessingLogConfig{
+		Format:     jobscheduler.LogFormat(cfg.Logging.Format),
+		Level:      jobscheduler.LogLevel(cfg.Logging.Level),
+		MaxSize:    int64(cfg.Logging.MaxSize) << 20, // megabytes
+		MaxAge:     time.Duration(cfg.Logging.MaxAge) * 24 * time.Hour,
+		MaxBackups: cfg.Logging.MaxBackups,
+		Compress:   cfg.Logging.Compress,
+	}
 	scheduler, err := jobscheduler.NewScheduler(jobscheduler.Config{
-		ProcessingLogPath: cfg.Scheduler.LogPath,
-		DefaultWorkers:    cfg.Scheduler.DefaultWorkers,
-		DefaultTimeout:    cfg.Scheduler.DefaultTimeout,
-		MaxQueueSize:      cfg.Scheduler.MaxQueueSize,
-		WorkDir:           cfg.Scheduler.WorkDir,
-		MaxOutputSize:     cfg.Scheduler.MaxOutputSize,
-		ShutdownTimeout:   cfg.Scheduler.ShutdownTimeout,
+		ProcessingLogPath:     cfg.Scheduler.LogPath,
+		ProcessingLog:         processingLog,
+		DefaultWorkers:        cfg.Scheduler.DefaultWorkers,
+		DefaultTimeout:        cfg.Scheduler.DefaultTimeout,
+		KillTimeout:           cfg.Scheduler.KillTimeout,
+		MaxQueueSize:          cfg.Scheduler.MaxQueueSize,
+		ChannelBufferSize:     cfg.Scheduler.MaxQueueSize,
+		WorkDir:               cfg.Scheduler.WorkDir,
+		WorkDirCleanup:        jobscheduler.WorkDirCleanup(cfg.Scheduler.WorkDirCleanup),
+		ArtifactDir:           cfg.Scheduler.ArtifactDir,
+		ArtifactRetention:     cfg.Scheduler.ArtifactRetention,
+		Subreaper:             cfg.Scheduler.Subreaper,
+		CgroupRoot:            cfg.Scheduler.CgroupRoot,
+		SandboxUntrusted:      cfg.Scheduler.SandboxJobs,
+		SandboxNetwork:        cfg.Scheduler.SandboxNetwork,
+		MaxOutputSize:         cfg.Scheduler.MaxOutputSize,
+		OutputDir:             cfg.Scheduler.OutputDir,
+		OutputRetention:       cfg.Scheduler.OutputRetention,
+		LogLines:              cfg.Scheduler.LogLines,
+		LogRetention:          cfg.Scheduler.LogRetention,
+		JobRetention:          cfg.Scheduler.JobRetention,
+		MaxFinishedJobs:       cfg.Scheduler.MaxFinishedJobs,
+		ShutdownTimeout:       cfg.Scheduler.ShutdownTimeout,
+		PriorityAgingInterval: cfg.Scheduler.PriorityAgingInterval,
+		StorePath:             cfg.Scheduler.StorePath,
+		RetryPolicy:           toRetryPolicy(cfg.Scheduler.RetryPolicy),
+		ChannelRetryPolicies:  channelRetryPolicies,
+		Channels:              channels,
+		Applications:          applications,
+		RestrictApplications:  *cfg.Scheduler.RestrictApplications,
 	})
 	if err != nil {
 		log.Fatalf("Failed to create scheduler: %v", err)
 	}
 	defer scheduler.Shutdown()
 
+	// Metrics are fed by the scheduler's events and the request middleware
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	schedulerMetrics := metrics.NewSchedulerMetrics(scheduler, registry)
+	defer schedulerMetrics.Close()
+	httpMetrics := middleware.NewHTTPMetrics(registry)
+
 	// Create router and handlers
 	router := http.NewServeMux()
 
@@ -53,19 +129,78 @@ func main() {
 	apiHandler := handlers.NewAPIHandler(scheduler)
 
 	// Register routes with middleware
-	router.Handle("/api/v1/jobs", middleware.Chain(
+	jobsHandler := middleware.Chain(
 		apiHandler.JobsHandler(),
+		httpMetrics.Instrument("jobs"),
+		middleware.Logger,
+		middleware.CORS(cfg.Server.AllowedOrigins),
+		middleware.Auth(cfg.Security.APIKey, cfg.Security.APIKeys),
+	)
+	router.Handle("/api/v1/jobs", jobsHandler)
+	router.Handle("/api/v1/jobs/", jobsHandler)
+
+	deadLetterHandler := middleware.Chain(
+		apiHandler.DeadLetterHandler(),
+		httpMetrics.Instrument("deadletter"),
+		middleware.Logger,
+		middleware.CORS(cfg.Server.AllowedOrigins),
+		middleware.Auth(cfg.Security.APIKey, cfg.Security.APIKeys),
+	)
+	router.Handle("/api/v1/deadletter", deadLetterHandler)
+	router.Handle("/api/v1/deadletter/", deadLetterHandler)
+
+	workflowsHandler := middleware.Chain(
+		apiHandler.WorkflowsHandler(),
+		httpMetrics.Instrument("workflows"),
+		middleware.Logger,
+		middleware.CORS(cfg.Server.AllowedOrigins),
+		middleware.Auth(cfg.Security.APIKey, cfg.Security.APIKeys),
+	)
+	router.Handle("/api/v1/workflows", workflowsHandler)
+	router.Handle("/api/v1/workflows/", workflowsHandler)
+
+	schedulesHandler := middleware.Chain(
+		apiHandler.SchedulesHandler(),
+		httpMetrics.Instrument("schedules"),
+		middleware.Logger,
+		middleware.CORS(cfg.Server.AllowedOrigins),
+		middleware.Auth(cfg.Security.APIKey, cfg.Security.APIKeys),
+	)
+	router.Handle("/api/v1/schedules", schedulesHandler)
+	router.Handle("/api/v1/schedules/", schedulesHandler)
+
+	channelsHandler := middleware.Chain(
+		apiHandler.ChannelsHandler(),
+		httpMetrics.Instrument("channels"),
+		middleware.Logger,
+		middleware.CORS(cfg.Server.AllowedOrigins),
+		middleware.Auth(cfg.Security.APIKey, cfg.Security.APIKeys),
+	)
+	router.Handle("/api/v1/channels", channelsHandler)
+	router.Handle("/api/v1/channels/", channelsHandler)
+
+	applicationsHandler := middleware.Chain(
+		apiHandler.ApplicationsHandler(),
+		httpMetrics.Instrument("applications"),
 		middleware.Logger,
 		middleware.CORS(cfg.Server.AllowedOrigins),
-		middleware.Auth(cfg.Server.APIKey),
-	))
+		middleware.AdminAuth(cfg.Security.AdminKey, cfg.Security.APIKey, cfg.Security.APIKeys),
+	)
+	router.Handle("/api/v1/applications", applicationsHandler)
+	router.Handle("/api/v1/applications/", applicationsHandler)
 
-	router.Handle("/api/v1/stats", middleware.Chain(
+	statsHandler := middleware.Chain(
 		apiHandler.StatsHandler(),
+		httpMetrics.Instrument("stats"),
 		middleware.Logger,
 		middleware.CORS(cfg.Server.AllowedOrigins),
-		middleware.Auth(cfg.Server.APIKey),
-	))
+		middleware.Auth(cfg.Security.APIKey, cfg.Security.APIKeys),
+	)
+	router.Handle("/api/v1/stats", statsHandler)
+	router.Handle("/api/v1/stats/", statsHandler)
+
+	// Metrics are scraped without an API key, like the health check
+	router.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 
 	// Serve static files
 	fs := http.FileServer(http.Dir("static"))
@@ -113,6 +248,18 @@ func main() {
 	log.Println("Server stopped")
 }
 
+// toRetryPolicy converts a configured retry policy to the scheduler's form
+func toRetryPolicy(policy config.RetryPolicy) jobscheduler.RetryPolicy {
+	return jobscheduler.RetryPolicy{
+		MaxRetries:            policy.MaxRetries,
+		InitialDelay:          policy.InitialDelay,
+		MaxDelay:              policy.MaxDelay,
+		BackoffFactor:         policy.BackoffFactor,
+		Jitter:                policy.Jitter,
+		NonRetryableExitCodes: policy.NonRetryableExitCodes,
+	}
+}
+
 // healthCheck handles health check requests
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -120,15 +267,6 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"status": "ok", "timestamp": "%s"}`, time.Now().Format(time.RFC3339))
 }
 
-// metricsHandler handles metrics requests
-func metricsHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain")
-	// TODO: Implement metrics collection and reporting
-	fmt.Fprintf(w, "# HELP job_scheduler_active_jobs Number of currently active jobs\n")
-	fmt.Fprintf(w, "# TYPE job_scheduler_active_jobs gauge\n")
-	// Add actual metrics implementation
-}
-
 // debugHandler provides debug information (only in non-production environments)
 func debugHandler(w http.ResponseWriter, r *http.Request) {
 	if os.Getenv("ENVIRONMENT") == "production" {
@@ -32,33 +32,111 @@ type SchedulerConfig struct {
 	LogPath         string        `yaml:"log_path"`
 	DefaultWorkers  int           `yaml:"default_workers"`
 	DefaultTimeout  time.Duration `yaml:"default_timeout"`
+	KillTimeout     time.Duration `yaml:"kill_timeout"` // Grace period between SIGTERM and SIGKILL
 	MaxQueueSize    int           `yaml:"max_queue_size"`
 	WorkDir         string        `yaml:"work_dir"`
+	Subreaper       bool          `yaml:"subreaper"`       // Adopt and reap processes orphaned by jobs (Linux only)
+	CgroupRoot      string        `yaml:"cgroup_root"`     // Delegated cgroup v2 directory for jobs with resource limits
+	SandboxJobs     bool          `yaml:"sandbox_jobs"`    // Sandbox every application submitted over HTTP
+	SandboxNetwork  bool          `yaml:"sandbox_network"` // Let jobs sandboxed regardless share the network if they ask
 	MaxOutputSize   int64         `yaml:"max_output_size"`
+	LogLines        int           `yaml:"log_lines"`     // Lines of each job's output kept for following it (1000 when zero)
+	LogRetention    time.Duration `yaml:"log_retention"` // How long a finished job's lines are kept (an hour when zero)
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
-	RetryPolicy     RetryPolicy   `yaml:"retry_policy"`
+	StorePath       string        `yaml:"store_path"` // Persist jobs across restarts when set
+
+	// Finished jobs are forgotten once older than job_retention or beyond
+	// the newest max_finished_jobs, except dead letters and jobs that
+	// others still wait on
+	JobRetention    time.Duration `yaml:"job_retention"`     // keep finished jobs forever when zero
+	MaxFinishedJobs int           `yaml:"max_finished_jobs"` // no limit when zero
+
+	// Each job runs in a scratch directory of its own under work_dir,
+	// removed when it exits unless the cleanup policy says otherwise
+	WorkDirCleanup    string        `yaml:"work_dir_cleanup"`   // always, on_success or never
+	ArtifactDir       string        `yaml:"artifact_dir"`       // work_dir/artifacts when empty
+	ArtifactRetention time.Duration `yaml:"artifact_retention"` // keep artifacts forever when zero
+
+	// Output beyond max_output_size keeps its head and tail in memory and
+	// is written whole to a gzipped file under output_dir
+	OutputDir       string        `yaml:"output_dir"`       // work_dir/output when empty
+	OutputRetention time.Duration `yaml:"output_retention"` // keep output forever when zero
+
+	// Waiting time worth one priority level, so low priority jobs are not
+	// starved by a steady stream of urgent ones
+	PriorityAgingInterval time.Duration `yaml:"priority_aging_interval"`
+
+	RetryPolicy RetryPolicy `yaml:"retry_policy"`
+
+	// Retry policies for specific channels, overriding RetryPolicy
+	ChannelRetryPolicies map[string]RetryPolicy `yaml:"channel_retry_policies"`
+
+	// Channels created at startup; others are created by their first job
+	Channels []ChannelConfig `yaml:"channels"`
+
+	// Applications jobs can run by name, and whether they can run anything
+	// else (they cannot unless this is set to false)
+	Applications         []ApplicationConfig `yaml:"applications"`
+	RestrictApplications *bool               `yaml:"restrict_applications"`
+}
+
+// ApplicationConfig registers an executable that jobs can run by name
+type ApplicationConfig struct {
+	Name        string            `yaml:"name"`
+	Path        string            `yaml:"path"`
+	Args        []string          `yaml:"args"`         // passed before the job's arguments
+	AllowedArgs []string          `yaml:"allowed_args"` // regular expressions each job argument must match
+	AllowedEnv  []string          `yaml:"allowed_env"`  // environment variables jobs may set
+	Env         map[string]string `yaml:"env"`          // set over the job's environment
+	Limits      ResourceLimits    `yaml:"limits"`       // caps on the limits jobs ask for
+	Checksum    string            `yaml:"checksum"`     // "sha256:" followed by the hex digest of path
+}
+
+// ResourceLimits bounds the resources an application may use. Zero fields
+// are unlimited.
+type ResourceLimits struct {
+	MemoryMax    int64   `yaml:"memory_max"`     // bytes
+	CPUQuota     float64 `yaml:"cpu_quota"`      // CPUs' worth of time
+	MaxProcesses int     `yaml:"max_processes"`  // processes and threads at once
+	MaxOpenFiles int     `yaml:"max_open_files"` // per process
+	MaxFileSize  int64   `yaml:"max_file_size"`  // bytes in any one file
+}
+
+// ChannelConfig declares a channel's settings. Zero values take the
+// scheduler defaults.
+type ChannelConfig struct {
+	Name         string        `yaml:"name"`
+	Workers      int           `yaml:"workers"`
+	Timeout      time.Duration `yaml:"timeout"`
+	KillTimeout  time.Duration `yaml:"kill_timeout"`
+	BufferSize   int           `yaml:"buffer_size"`    // jobs waiting for a worker
+	MaxQueueSize int           `yaml:"max_queue_size"` // unfinished jobs of any status
+	Sandbox      bool          `yaml:"sandbox"`        // sandbox every application in the channel
 }
 
 // SecurityConfig contains security related configuration
 type SecurityConfig struct {
-	APIKey          string          `yaml:"api_key"`
-	TokenExpiry     time.Duration   `yaml:"token_expiry"`
-	EnableTLS       bool            `yaml:"enable_tls"`
-	TLSCert         string          `yaml:"tls_cert"`
-	TLSKey          string          `yaml:"tls_key"`
-	RateLimit       RateLimitConfig `yaml:"rate_limit"`
-	AllowedIPRanges []string        `yaml:"allowed_ip_ranges"`
+	APIKey          string            `yaml:"api_key"`
+	APIKeys         map[string]string `yaml:"api_keys"`  // further keys, by the name of who holds each
+	AdminKey        string            `yaml:"admin_key"` // changes applications, which other keys can only read
+	TokenExpiry     time.Duration     `yaml:"token_expiry"`
+	EnableTLS       bool              `yaml:"enable_tls"`
+	TLSCert         string            `yaml:"tls_cert"`
+	TLSKey          string            `yaml:"tls_key"`
+	RateLimit       RateLimitConfig   `yaml:"rate_limit"`
+	AllowedIPRanges []string          `yaml:"allowed_ip_ranges"`
 }
 
-// LoggingConfig contains logging related configuration
+// LoggingConfig contains logging related configuration. Level, format and
+// rotation apply to the scheduler's processing log at scheduler.log_path.
 type LoggingConfig struct {
-	Level        string `yaml:"level"`
-	Format       string `yaml:"format"`
+	Level        string `yaml:"level"`  // debug, info, warn or error
+	Format       string `yaml:"format"` // json or text
 	FilePath     string `yaml:"file_path"`
-	MaxSize      int    `yaml:"max_size"`    // megabytes
-	MaxAge       int    `yaml:"max_age"`     // days
-	MaxBackups   int    `yaml:"max_backups"` // files
-	Compress     bool   `yaml:"compress"`
+	MaxSize      int    `yaml:"max_size"`    // megabytes before the log is rotated
+	MaxAge       int    `yaml:"max_age"`     // days before the log is rotated, and rotated logs removed
+	MaxBackups   int    `yaml:"max_backups"` // rotated logs kept
+	Compress     bool   `yaml:"compress"`    // gzip rotated logs
 	EnableStdout bool   `yaml:"enable_stdout"`
 }
 
@@ -71,10 +149,12 @@ type RateLimitConfig struct {
 
 // RetryPolicy contains job retry configuration
 type RetryPolicy struct {
-	MaxRetries    int           `yaml:"max_retries"`
-	InitialDelay  time.Duration `yaml:"initial_delay"`
-	MaxDelay      time.Duration `yaml:"max_delay"`
-	BackoffFactor float64       `yaml:"backoff_factor"`
+	MaxRetries            int           `yaml:"max_retries"`
+	InitialDelay          time.Duration `yaml:"initial_delay"`
+	MaxDelay              time.Duration `yaml:"max_delay"`
+	BackoffFactor         float64       `yaml:"backoff_factor"`
+	Jitter                float64       `yaml:"jitter"`                   // fraction of each delay to randomise
+	NonRetryableExitCodes []int         `yaml:"non_retryable_exit_codes"` // exit codes never retried
 }
 
 // Load reads and parses the configuration file
@@ -122,12 +202,18 @@ func (c *Config) setDefaults() {
 	}
 
 	// Scheduler defaults
+	if c.Scheduler.LogPath == "" {
+		c.Scheduler.LogPath = "processing.log"
+	}
 	if c.Scheduler.DefaultWorkers == 0 {
 		c.Scheduler.DefaultWorkers = 1
 	}
 	if c.Scheduler.DefaultTimeout == 0 {
 		c.Scheduler.DefaultTimeout = 5 * time.Minute
 	}
+	if c.Scheduler.KillTimeout == 0 {
+		c.Scheduler.KillTimeout = 10 * time.Second
+	}
 	if c.Scheduler.MaxQueueSize == 0 {
 		c.Scheduler.MaxQueueSize = 10000
 	}
@@ -137,6 +223,18 @@ func (c *Config) setDefaults() {
 	if c.Scheduler.ShutdownTimeout == 0 {
 		c.Scheduler.ShutdownTimeout = 30 * time.Second
 	}
+	if c.Scheduler.PriorityAgingInterval == 0 {
+		c.Scheduler.PriorityAgingInterval = 30 * time.Second
+	}
+	if c.Scheduler.RestrictApplications == nil {
+		restrict := true
+		c.Scheduler.RestrictApplications = &restrict
+	}
+	c.Scheduler.RetryPolicy.setDefaults()
+	for channel, policy := range c.Scheduler.ChannelRetryPolicies {
+		policy.setDefaults()
+		c.Scheduler.ChannelRetryPolicies[channel] = policy
+	}
 
 	// Security defaults
 	if c.Security.TokenExpiry == 0 {
@@ -164,6 +262,25 @@ func (c *Config) setDefaults() {
 	}
 }
 
+// setDefaults fills in backoff settings for a policy that enables retries
+func (r *RetryPolicy) setDefaults() {
+	if r.MaxRetries == 0 {
+		return
+	}
+	if r.InitialDelay == 0 {
+		r.InitialDelay = time.Second
+	}
+	if r.MaxDelay == 0 {
+		r.MaxDelay = 5 * time.Minute
+	}
+	if r.BackoffFactor == 0 {
+		r.BackoffFactor = 2
+	}
+	if r.Jitter == 0 {
+		r.Jitter = 0.2
+	}
+}
+
 // validate checks if the configuration is valid
 func (c *Config) validate() error {
 	// Validate Server configuration
@@ -175,9 +292,67 @@ func (c *Config) validate() error {
 	if c.Scheduler.DefaultWorkers < 1 {
 		return fmt.Errorf("default workers must be at least 1")
 	}
+	if c.Scheduler.KillTimeout < 0 {
+		return fmt.Errorf("kill timeout cannot be negative")
+	}
 	if c.Scheduler.MaxQueueSize < 1 {
 		return fmt.Errorf("max queue size must be at least 1")
 	}
+	switch c.Scheduler.WorkDirCleanup {
+	case "", "always", "on_success", "never":
+	default:
+		return fmt.Errorf("unknown work directory cleanup policy: %s", c.Scheduler.WorkDirCleanup)
+	}
+	if c.Scheduler.ArtifactRetention < 0 {
+		return fmt.Errorf("artifact retention cannot be negative")
+	}
+	if c.Scheduler.OutputRetention < 0 {
+		return fmt.Errorf("output retention cannot be negative")
+	}
+	if c.Scheduler.JobRetention < 0 {
+		return fmt.Errorf("job retention cannot be negative")
+	}
+	if c.Scheduler.MaxFinishedJobs < 0 {
+		return fmt.Errorf("max finished jobs cannot be negative")
+	}
+	if c.Scheduler.LogLines < 0 {
+		return fmt.Errorf("log lines cannot be negative")
+	}
+	if c.Scheduler.LogRetention < 0 {
+		return fmt.Errorf("log retention cannot be negative")
+	}
+	if c.Scheduler.PriorityAgingInterval < 0 {
+		return fmt.Errorf("priority aging interval cannot be negative")
+	}
+	if c.Scheduler.RetryPolicy.MaxRetries < 0 {
+		return fmt.Errorf("max retries cannot be negative")
+	}
+	if j := c.Scheduler.RetryPolicy.Jitter; j < 0 || j > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	channels := make(map[string]bool)
+	for _, channel := range c.Scheduler.Channels {
+		if channel.Name == "" {
+			return fmt.Errorf("channel name cannot be empty")
+		}
+		if channels[channel.Name] {
+			return fmt.Errorf("channel %s is declared more than once", channel.Name)
+		}
+		channels[channel.Name] = true
+		if channel.Workers < 0 || channel.Timeout < 0 || channel.KillTimeout < 0 || channel.BufferSize < 0 || channel.MaxQueueSize < 0 {
+			return fmt.Errorf("channel %s settings cannot be negative", channel.Name)
+		}
+	}
+	applications := make(map[string]bool)
+	for _, app := range c.Scheduler.Applications {
+		if app.Name == "" || app.Path == "" {
+			return fmt.Errorf("application name and path cannot be empty")
+		}
+		if applications[app.Name] {
+			return fmt.Errorf("application %s is declared more than once", app.Name)
+		}
+		applications[app.Name] = true
+	}
 
 	// Validate Security configuration
 	if c.Security.EnableTLS {
@@ -185,10 +360,36 @@ func (c *Config) validate() error {
 			return fmt.Errorf("TLS certificate and key are required when TLS is enabled")
 		}
 	}
+	for name, key := range c.Security.APIKeys {
+		if name == "" || key == "" {
+			return fmt.Errorf("named API keys need a name and a key")
+		}
+		if key == c.Security.AdminKey {
+			return fmt.Errorf("admin key cannot also be an API key")
+		}
+	}
+	if c.Security.AdminKey != "" && c.Security.AdminKey == c.Security.APIKey {
+		return fmt.Errorf("admin key cannot also be an API key")
+	}
 	if c.Security.RateLimit.Enabled && c.Security.RateLimit.RequestsPerMin < 1 {
 		return fmt.Errorf("requests per minute must be at least 1")
 	}
 
+	// Validate Logging configuration
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("unknown log level: %s", c.Logging.Level)
+	}
+	switch c.Logging.Format {
+	case "json", "text":
+	default:
+		return fmt.Errorf("unknown log format: %s", c.Logging.Format)
+	}
+	if c.Logging.MaxSize < 0 || c.Logging.MaxAge < 0 || c.Logging.MaxBackups < 0 {
+		return fmt.Errorf("log rotation settings cannot be negative")
+	}
+
 	return nil
 }
 
@@ -10,24 +10,125 @@ import (
 type SubmitJobRequest struct {
 	JobID          string             `json:"job_id"`
 	Channel        string             `json:"channel"`
+	Type           string             `json:"type,omitempty"` // Selects an in-process handler when there is no application
 	Workers        int                `json:"workers,omitempty"`
 	TimeoutSeconds int                `json:"timeout_seconds,omitempty"`
 	Priority       int                `json:"priority,omitempty"`
+	RunAt          *time.Time         `json:"run_at,omitempty"`        // Start no earlier than this time
+	DelaySeconds   int                `json:"delay_seconds,omitempty"` // Start no sooner than this after submission
+	DependsOn      []string           `json:"depends_on,omitempty"`    // Jobs that must complete first
 	Application    *ApplicationConfig `json:"application,omitempty"`
+	Retry          *RetryPolicy       `json:"retry,omitempty"`
 	Payload        json.RawMessage    `json:"payload"`
 	Tags           []string           `json:"tags,omitempty"`
 	Notify         *NotifyConfig      `json:"notify,omitempty"`
 }
 
-// ApplicationConfig defines external application configuration
+// ApplicationConfig defines external application configuration. Name may
+// instead refer to a registered application, leaving Path empty.
 type ApplicationConfig struct {
 	Name        string            `json:"name"`
-	Path        string            `json:"path"`
+	Path        string            `json:"path,omitempty"`
 	Args        []string          `json:"args,omitempty"`
 	Env         map[string]string `json:"env,omitempty"`
 	WorkingDir  string            `json:"working_dir,omitempty"`
 	PassPayload bool              `json:"pass_payload,omitempty"`
-	Timeout     int               `json:"timeout,omitempty"`
+	Timeout     int               `json:"timeout,omitempty"`      // Seconds, overriding the channel timeout
+	KillTimeout int               `json:"kill_timeout,omitempty"` // Seconds between SIGTERM and SIGKILL, overriding the channel's
+	Inputs      []JobInput        `json:"inputs,omitempty"`
+	Limits      *ResourceLimits   `json:"limits,omitempty"`
+	Sandbox     *SandboxConfig    `json:"sandbox,omitempty"`   // Run isolated, with a read-only filesystem and a scratch directory
+	Artifacts   []string          `json:"artifacts,omitempty"` // Glob patterns of files to keep once it exits
+}
+
+// SandboxConfig isolates an application from the host
+type SandboxConfig struct {
+	Network bool `json:"network,omitempty"` // Share the host's network
+}
+
+// ResourceLimits bounds the resources an application may use. Zero fields
+// are unlimited.
+type ResourceLimits struct {
+	MemoryMax    int64   `json:"memory_max,omitempty"`     // Bytes
+	CPUQuota     float64 `json:"cpu_quota,omitempty"`      // CPUs' worth of time, e.g. 0.5
+	MaxProcesses int     `json:"max_processes,omitempty"`  // Processes and threads at once
+	MaxOpenFiles int     `json:"max_open_files,omitempty"` // Per process
+	MaxFileSize  int64   `json:"max_file_size,omitempty"`  // Bytes in any one file
+}
+
+// Validate checks that no limit is negative
+func (l *ResourceLimits) Validate() error {
+	if l.MemoryMax < 0 || l.CPUQuota < 0 || l.MaxProcesses < 0 || l.MaxOpenFiles < 0 || l.MaxFileSize < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	return nil
+}
+
+// JobInput feeds the captured output or an artifact of a completed job to
+// the application, on stdin or as a file in its working directory
+type JobInput struct {
+	JobID    string `json:"job_id"`
+	Source   string `json:"source,omitempty"`   // stdout (default), stderr or artifact
+	Artifact string `json:"artifact,omitempty"` // Artifact name, for the artifact source
+	File     string `json:"file,omitempty"`     // Relative path; stdin when empty
+}
+
+// Artifact describes a file a job kept from its working directory
+type Artifact struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// ListArtifactsResponse represents the response structure for listing a
+// job's artifacts
+type ListArtifactsResponse struct {
+	JobID     string     `json:"job_id"`
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// LogLine is a line of a job's output
+type LogLine struct {
+	Seq    int64     `json:"seq"` // Position in the job's log; resume with since or Last-Event-ID
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"` // stdout or stderr
+	Text   string    `json:"text"`
+}
+
+// JobLogsResponse represents the response structure for reading a job's
+// log without following it
+type JobLogsResponse struct {
+	JobID string    `json:"job_id"`
+	Lines []LogLine `json:"lines"`
+	Done  bool      `json:"done"` // The job has finished, so no more lines will follow
+}
+
+// JobEvent is something that happened to a job
+type JobEvent struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"` // submitted, queued, started, skipped, survivors, retrying, cancelled, requeued or finished
+	Attempt  int       `json:"attempt"`
+	Status   string    `json:"status"`             // Status of the job when it happened
+	Actor    string    `json:"actor,omitempty"`    // Who submitted, cancelled or requeued the job
+	Duration float64   `json:"duration,omitempty"` // Seconds the attempt ran for
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// JobEventsResponse represents the response structure for a job's history
+type JobEventsResponse struct {
+	JobID  string     `json:"job_id"`
+	Events []JobEvent `json:"events"` // Oldest first
+}
+
+// RetryPolicy defines how a failed or timed-out job is retried
+type RetryPolicy struct {
+	MaxRetries            int     `json:"max_retries"`
+	InitialDelaySeconds   int     `json:"initial_delay_seconds,omitempty"`
+	MaxDelaySeconds       int     `json:"max_delay_seconds,omitempty"`
+	BackoffFactor         float64 `json:"backoff_factor,omitempty"`
+	Jitter                float64 `json:"jitter,omitempty"` // Fraction of each delay to randomise
+	NonRetryableExitCodes []int   `json:"non_retryable_exit_codes,omitempty"`
 }
 
 // NotifyConfig defines notification settings for job events
@@ -49,17 +150,29 @@ type SubmitJobResponse struct {
 
 // JobStatusResponse represents the response structure for job status
 type JobStatusResponse struct {
-	JobID      string    `json:"job_id"`
-	Channel    string    `json:"channel"`
-	Status     string    `json:"status"`
-	Progress   float64   `json:"progress,omitempty"`
-	StartTime  time.Time `json:"start_time,omitempty"`
-	EndTime    time.Time `json:"end_time,omitempty"`
-	Duration   string    `json:"duration,omitempty"`
-	Error      string    `json:"error,omitempty"`
-	Logs       []string  `json:"logs,omitempty"`
-	ExitCode   int       `json:"exit_code,omitempty"`
-	RetryCount int       `json:"retry_count,omitempty"`
+	JobID         string     `json:"job_id"`
+	Channel       string     `json:"channel"`
+	Type          string     `json:"type,omitempty"`
+	Status        string     `json:"status"`
+	Progress      float64    `json:"progress,omitempty"` // Percentage the application last reported
+	Message       string     `json:"message,omitempty"`  // Message that came with it
+	StartTime     time.Time  `json:"start_time,omitempty"`
+	EndTime       time.Time  `json:"end_time,omitempty"`
+	Duration      string     `json:"duration,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	Logs          []string   `json:"logs,omitempty"`
+	Truncated     bool       `json:"truncated,omitempty"` // Logs omit the middle of the output; GET /api/v1/jobs/{id}/output/{stdout|stderr} for all of it
+	ExitCode      int        `json:"exit_code,omitempty"`
+	Termination   string     `json:"termination,omitempty"`    // exited, terminated or killed
+	Survivors     []int      `json:"survivors,omitempty"`      // Processes that could not be killed
+	FailureReason string     `json:"failure_reason,omitempty"` // oom_killed, file_size_limit or process_limit
+	Artifacts     []Artifact `json:"artifacts,omitempty"`      // Download from /api/v1/jobs/{id}/artifacts/{name}
+	RetryCount    int        `json:"retry_count,omitempty"`
+	Priority      int        `json:"priority"`
+	RunAt         time.Time  `json:"run_at,omitempty"`
+	DependsOn     []string   `json:"depends_on,omitempty"`
+	WorkflowID    string     `json:"workflow_id,omitempty"`
+	SubmittedBy   string     `json:"submitted_by,omitempty"`
 }
 
 // ListJobsResponse represents the response structure for listing jobs
@@ -72,24 +185,41 @@ type ListJobsResponse struct {
 
 // ChannelStats represents statistics for a channel
 type ChannelStats struct {
-	Workers     int       `json:"workers"`
-	ActiveJobs  []string  `json:"active_jobs"`
-	TotalJobs   int64     `json:"total_jobs"`
-	FailedJobs  int64     `json:"failed_jobs"`
-	LastJobTime time.Time `json:"last_job_time"`
-	Uptime      string    `json:"uptime"`
-	QueueSize   int       `json:"queue_size"`
-	Throughput  float64   `json:"throughput"` // jobs per minute
+	Workers         int         `json:"workers"`
+	ActiveJobs      []string    `json:"active_jobs"`
+	TotalJobs       int64       `json:"total_jobs"`
+	CompletedJobs   int64       `json:"completed_jobs"`
+	FailedJobs      int64       `json:"failed_jobs"`
+	TimedOutJobs    int64       `json:"timed_out_jobs"`
+	CancelledJobs   int64       `json:"cancelled_jobs"`
+	InterruptedJobs int64       `json:"interrupted_jobs"`
+	RetriedJobs     int64       `json:"retried_jobs"` // failed attempts that were retried
+	LastJobTime     time.Time   `json:"last_job_time"`
+	Uptime          string      `json:"uptime"`
+	QueueSize       int         `json:"queue_size"`
+	Throughput      float64     `json:"throughput"`   // jobs finished per minute over the last five minutes
+	RunDuration     RunDuration `json:"run_duration"` // of the latest thousand runs
+}
+
+// RunDuration gives percentiles of how long runs took, in seconds
+type RunDuration struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
 }
 
 // StatsSummary represents summarized statistics
 type StatsSummary struct {
-	TotalJobs      int64     `json:"total_jobs"`
-	CompletedJobs  int64     `json:"completed_jobs"`
-	FailedJobs     int64     `json:"failed_jobs"`
-	AverageRuntime float64   `json:"average_runtime"`
-	ActiveChannels int       `json:"active_channels"`
-	TimeRange      TimeRange `json:"time_range"`
+	TotalJobs       int64     `json:"total_jobs"`
+	CompletedJobs   int64     `json:"completed_jobs"`
+	FailedJobs      int64     `json:"failed_jobs"`
+	TimedOutJobs    int64     `json:"timed_out_jobs"`
+	CancelledJobs   int64     `json:"cancelled_jobs"`
+	InterruptedJobs int64     `json:"interrupted_jobs"`
+	RetriedJobs     int64     `json:"retried_jobs"`
+	AverageRuntime  float64   `json:"average_runtime"` // seconds
+	ActiveChannels  int       `json:"active_channels"`
+	TimeRange       TimeRange `json:"time_range"`
 }
 
 // TimeRange represents a time period for statistics
@@ -100,14 +230,20 @@ type TimeRange struct {
 
 // OverallStats represents system-wide statistics
 type OverallStats struct {
-	ActiveJobs     int         `json:"active_jobs"`
-	QueuedJobs     int         `json:"queued_jobs"`
-	CompletedJobs  int64       `json:"completed_jobs"`
-	FailedJobs     int64       `json:"failed_jobs"`
-	ActiveChannels int         `json:"active_channels"`
-	Uptime         string      `json:"uptime"`
-	LastUpdate     time.Time   `json:"last_update"`
-	SystemStats    SystemStats `json:"system_stats"`
+	ActiveJobs      int         `json:"active_jobs"`
+	QueuedJobs      int         `json:"queued_jobs"`
+	TotalJobs       int64       `json:"total_jobs"`
+	CompletedJobs   int64       `json:"completed_jobs"`
+	FailedJobs      int64       `json:"failed_jobs"`
+	TimedOutJobs    int64       `json:"timed_out_jobs"`
+	CancelledJobs   int64       `json:"cancelled_jobs"`
+	InterruptedJobs int64       `json:"interrupted_jobs"`
+	RetriedJobs     int64       `json:"retried_jobs"`
+	ActiveChannels  int         `json:"active_channels"`
+	Throughput      float64     `json:"throughput"` // jobs finished per minute over the last five minutes
+	Uptime          string      `json:"uptime"`
+	LastUpdate      time.Time   `json:"last_update"`
+	SystemStats     SystemStats `json:"system_stats"`
 }
 
 // SystemStats represents system resource statistics
@@ -133,13 +269,44 @@ func (r *SubmitJobRequest) Validate() error {
 		return fmt.Errorf("timeout_seconds cannot be negative")
 	}
 	if r.Application != nil {
-		if r.Application.Path == "" {
-			return fmt.Errorf("application path is required")
+		if r.Application.Path == "" && r.Application.Name == "" {
+			return fmt.Errorf("application path or name is required")
+		}
+		if r.Application.Timeout < 0 {
+			return fmt.Errorf("application timeout cannot be negative")
+		}
+		if r.Application.KillTimeout < 0 {
+			return fmt.Errorf("application kill_timeout cannot be negative")
+		}
+		if l := r.Application.Limits; l != nil {
+			if err := l.Validate(); err != nil {
+				return fmt.Errorf("application %v", err)
+			}
 		}
 	}
 	if r.Priority < 0 || r.Priority > 10 {
 		return fmt.Errorf("priority must be between 0 and 10")
 	}
+	if r.DelaySeconds < 0 {
+		return fmt.Errorf("delay_seconds cannot be negative")
+	}
+	if r.DelaySeconds > 0 && r.RunAt != nil {
+		return fmt.Errorf("only one of delay_seconds and run_at may be set")
+	}
+	if r.Retry != nil {
+		if r.Retry.MaxRetries < 0 {
+			return fmt.Errorf("retry max_retries cannot be negative")
+		}
+		if r.Retry.InitialDelaySeconds < 0 || r.Retry.MaxDelaySeconds < 0 {
+			return fmt.Errorf("retry delays cannot be negative")
+		}
+		if r.Retry.BackoffFactor < 0 {
+			return fmt.Errorf("retry backoff_factor cannot be negative")
+		}
+		if r.Retry.Jitter < 0 || r.Retry.Jitter > 1 {
+			return fmt.Errorf("retry jitter must be between 0 and 1")
+		}
+	}
 	if r.Notify != nil {
 		if r.Notify.Webhook == "" && r.Notify.Email == "" {
 			return fmt.Errorf("either webhook or email must be specified for notifications")
@@ -148,6 +315,264 @@ func (r *SubmitJobRequest) Validate() error {
 	return nil
 }
 
+// JobAttempt represents the outcome of a single run of a job
+type JobAttempt struct {
+	Attempt       int       `json:"attempt"`
+	Status        string    `json:"status"`
+	ExitCode      int       `json:"exit_code"`
+	Error         string    `json:"error,omitempty"`
+	Termination   string    `json:"termination,omitempty"`    // exited, terminated or killed
+	FailureReason string    `json:"failure_reason,omitempty"` // oom_killed, file_size_limit or process_limit
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+}
+
+// DeadLetterResponse represents a job that exhausted its retries
+type DeadLetterResponse struct {
+	JobID          string             `json:"job_id"`
+	Channel        string             `json:"channel"`
+	Status         string             `json:"status"`
+	Error          string             `json:"error,omitempty"`
+	ExitCode       int                `json:"exit_code"`
+	Application    *ApplicationConfig `json:"application,omitempty"`
+	Payload        json.RawMessage    `json:"payload,omitempty"`
+	Attempts       []JobAttempt       `json:"attempts"`
+	FirstAttemptAt time.Time          `json:"first_attempt_at"`
+	DeadLetteredAt time.Time          `json:"dead_lettered_at"`
+}
+
+// ListDeadLettersResponse represents the response structure for listing dead-lettered jobs
+type ListDeadLettersResponse struct {
+	DeadLetters []DeadLetterResponse `json:"dead_letters"`
+	Total       int                  `json:"total"`
+}
+
+// RequeueDeadLettersRequest selects dead-lettered jobs to requeue. When
+// JobIDs is empty, every dead-lettered job in Channel (or in all channels
+// if Channel is empty) is requeued.
+type RequeueDeadLettersRequest struct {
+	JobIDs  []string `json:"job_ids,omitempty"`
+	Channel string   `json:"channel,omitempty"`
+}
+
+// RequeueDeadLettersResponse represents the response for a requeue request
+type RequeueDeadLettersResponse struct {
+	JobIDs   []string `json:"job_ids"`
+	Requeued int      `json:"requeued"`
+}
+
+// PurgeDeadLettersResponse represents the response for a bulk purge request
+type PurgeDeadLettersResponse struct {
+	Purged int `json:"purged"`
+}
+
+// SubmitWorkflowRequest represents the request structure for submitting a
+// set of jobs linked by their depends_on fields
+type SubmitWorkflowRequest struct {
+	WorkflowID string             `json:"workflow_id"`
+	Jobs       []SubmitJobRequest `json:"jobs"`
+}
+
+// Validate validates the workflow submission request
+func (r *SubmitWorkflowRequest) Validate() error {
+	if r.WorkflowID == "" {
+		return fmt.Errorf("workflow_id is required")
+	}
+	if len(r.Jobs) == 0 {
+		return fmt.Errorf("at least one job is required")
+	}
+	for i := range r.Jobs {
+		if err := r.Jobs[i].Validate(); err != nil {
+			return fmt.Errorf("job %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// SubmitWorkflowResponse represents the response structure for workflow submission
+type SubmitWorkflowResponse struct {
+	WorkflowID string    `json:"workflow_id"`
+	JobIDs     []string  `json:"job_ids"`
+	Status     string    `json:"status"`
+	Submitted  time.Time `json:"submitted"`
+}
+
+// WorkflowStatusResponse represents the aggregate status of a workflow
+type WorkflowStatusResponse struct {
+	WorkflowID string              `json:"workflow_id"`
+	Status     string              `json:"status"`
+	Counts     map[string]int      `json:"counts"`
+	StartTime  time.Time           `json:"start_time,omitempty"`
+	EndTime    time.Time           `json:"end_time,omitempty"`
+	Duration   string              `json:"duration,omitempty"`
+	Jobs       []JobStatusResponse `json:"jobs"`
+}
+
+// ListWorkflowsResponse represents the response structure for listing workflows
+type ListWorkflowsResponse struct {
+	Workflows []WorkflowStatusResponse `json:"workflows"`
+	Total     int                      `json:"total"`
+}
+
+// ScheduleRequest represents the request structure for creating or updating
+// a recurring schedule
+type ScheduleRequest struct {
+	ID         string           `json:"id"`
+	Expression string           `json:"expression"`         // 5-field cron expression, or @every/@hourly style shorthand
+	Timezone   string           `json:"timezone,omitempty"` // IANA time zone, UTC if empty
+	Overlap    string           `json:"overlap,omitempty"`  // skip, queue or cancel_previous
+	CatchUp    string           `json:"catch_up,omitempty"` // none, once or all
+	Paused     bool             `json:"paused,omitempty"`
+	Job        SubmitJobRequest `json:"job"` // Template for each run; job_id is ignored
+}
+
+// Validate validates the schedule request
+func (r *ScheduleRequest) Validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if r.Expression == "" {
+		return fmt.Errorf("expression is required")
+	}
+	if r.Job.RunAt != nil || r.Job.DelaySeconds != 0 {
+		return fmt.Errorf("job run_at and delay_seconds cannot be used in a schedule")
+	}
+
+	job := r.Job
+	job.JobID = r.ID
+	if err := job.Validate(); err != nil {
+		return fmt.Errorf("invalid job: %v", err)
+	}
+	return nil
+}
+
+// ScheduleResponse represents a recurring schedule and its run history
+type ScheduleResponse struct {
+	ID         string    `json:"id"`
+	Expression string    `json:"expression"`
+	Timezone   string    `json:"timezone,omitempty"`
+	Channel    string    `json:"channel"`
+	Overlap    string    `json:"overlap"`
+	CatchUp    string    `json:"catch_up"`
+	Paused     bool      `json:"paused"`
+	CreatedAt  time.Time `json:"created_at"`
+	NextRun    time.Time `json:"next_run,omitempty"`
+	LastRun    time.Time `json:"last_run,omitempty"`
+	LastJobID  string    `json:"last_job_id,omitempty"`
+	Runs       int64     `json:"runs"`
+	Skipped    int64     `json:"skipped"`
+	Held       int       `json:"held"`
+}
+
+// ListSchedulesResponse represents the response structure for listing schedules
+type ListSchedulesResponse struct {
+	Schedules []ScheduleResponse `json:"schedules"`
+	Total     int                `json:"total"`
+}
+
+// ChannelRequest represents the request structure for creating or updating
+// a channel. Zero values take the scheduler defaults.
+type ChannelRequest struct {
+	Name               string `json:"name"`
+	Workers            int    `json:"workers,omitempty"`
+	TimeoutSeconds     int    `json:"timeout_seconds,omitempty"`
+	KillTimeoutSeconds int    `json:"kill_timeout_seconds,omitempty"` // Grace period between SIGTERM and SIGKILL
+	BufferSize         int    `json:"buffer_size,omitempty"`          // Jobs waiting for a worker
+	MaxQueueSize       int    `json:"max_queue_size,omitempty"`       // Unfinished jobs of any status
+	Sandbox            bool   `json:"sandbox,omitempty"`              // Sandbox every application in the channel
+}
+
+// Validate performs validation of the channel request
+func (r *ChannelRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Workers < 0 {
+		return fmt.Errorf("workers cannot be negative")
+	}
+	if r.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout_seconds cannot be negative")
+	}
+	if r.KillTimeoutSeconds < 0 {
+		return fmt.Errorf("kill_timeout_seconds cannot be negative")
+	}
+	if r.BufferSize < 0 {
+		return fmt.Errorf("buffer_size cannot be negative")
+	}
+	if r.MaxQueueSize < 0 {
+		return fmt.Errorf("max_queue_size cannot be negative")
+	}
+	return nil
+}
+
+// ChannelResponse represents a channel's settings and current load
+type ChannelResponse struct {
+	Name               string `json:"name"`
+	Workers            int    `json:"workers"`
+	TimeoutSeconds     int    `json:"timeout_seconds"`
+	KillTimeoutSeconds int    `json:"kill_timeout_seconds"`
+	BufferSize         int    `json:"buffer_size"`
+	MaxQueueSize       int    `json:"max_queue_size"`
+	Sandbox            bool   `json:"sandbox"`
+	Declared           bool   `json:"declared"`
+	Queued             int    `json:"queued"`
+	Running            int    `json:"running"`
+}
+
+// ListChannelsResponse represents the response structure for listing channels
+type ListChannelsResponse struct {
+	Channels []ChannelResponse `json:"channels"`
+	Total    int               `json:"total"`
+}
+
+// ApplicationRequest represents the request structure for registering or
+// updating an application that jobs can run by name
+type ApplicationRequest struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	Args        []string          `json:"args,omitempty"`         // Passed before the job's arguments
+	AllowedArgs []string          `json:"allowed_args,omitempty"` // Regular expressions each job argument must match
+	AllowedEnv  []string          `json:"allowed_env,omitempty"`  // Environment variables jobs may set
+	Env         map[string]string `json:"env,omitempty"`          // Set over the job's environment
+	Limits      *ResourceLimits   `json:"limits,omitempty"`       // Caps on the limits jobs ask for
+	Checksum    string            `json:"checksum,omitempty"`     // "sha256:" followed by the hex digest of Path
+}
+
+// Validate performs validation of the application request
+func (r *ApplicationRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if r.Limits != nil {
+		if err := r.Limits.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplicationResponse represents a registered application
+type ApplicationResponse struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	Args        []string          `json:"args,omitempty"`
+	AllowedArgs []string          `json:"allowed_args,omitempty"`
+	AllowedEnv  []string          `json:"allowed_env,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Limits      *ResourceLimits   `json:"limits,omitempty"`
+	Checksum    string            `json:"checksum,omitempty"`
+}
+
+// ListApplicationsResponse represents the response structure for listing
+// registered applications
+type ListApplicationsResponse struct {
+	Applications []ApplicationResponse `json:"applications"`
+	Total        int                   `json:"total"`
+}
+
 // ErrorResponse represents an API error response
 type ErrorResponse struct {
 	Error      string `json:"error"`
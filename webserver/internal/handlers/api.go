@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/jonathanleahy/project/jobscheduler"
+	"github.com/jonathanleahy/project/webserver/internal/middleware"
+)
+
+// APIHandler groups the HTTP handlers backed by a single scheduler
+type APIHandler struct {
+	scheduler *jobscheduler.Scheduler
+}
+
+// NewAPIHandler creates a new API handler
+func NewAPIHandler(scheduler *jobscheduler.Scheduler) *APIHandler {
+	return &APIHandler{
+		scheduler: scheduler,
+	}
+}
+
+// actor returns who made a request, as recorded in the history of the jobs
+// it submits or cancels
+func actor(r *http.Request) string {
+	info, _ := middleware.GetAuthInfo(r.Context())
+	return info.Subject
+}
+
+// JobsHandler returns the handler for job-related requests
+func (h *APIHandler) JobsHandler() http.Handler {
+	return NewJobsHandler(h.scheduler)
+}
+
+// StatsHandler returns the handler for statistics-related requests
+func (h *APIHandler) StatsHandler() http.Handler {
+	return NewStatsHandler(h.scheduler)
+}
+
+// WorkflowsHandler returns the handler for workflow requests
+func (h *APIHandler) WorkflowsHandler() http.Handler {
+	return NewWorkflowsHandler(h.scheduler)
+}
+
+// SchedulesHandler returns the handler for recurring schedule requests
+func (h *APIHandler) SchedulesHandler() http.Handler {
+	return NewSchedulesHandler(h.scheduler)
+}
+
+// ChannelsHandler returns the handler for channel management requests
+func (h *APIHandler) ChannelsHandler() http.Handler {
+	return NewChannelsHandler(h.scheduler)
+}
+
+// ApplicationsHandler returns the handler for application registry requests
+func (h *APIHandler) ApplicationsHandler() http.Handler {
+	return NewApplicationsHandler(h.scheduler)
+}
+
+// DeadLetterHandler returns the handler for dead-lettered job requests
+func (h *APIHandler) DeadLetterHandler() http.Handler {
+	return NewDeadLetterHandler(h.scheduler)
+}
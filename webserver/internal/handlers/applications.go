@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jonathanleahy/project/jobscheduler"
+	"github.com/jonathanleahy/project/webserver/internal/api"
+)
+
+// ApplicationsHandler handles requests for managing the applications that
+// jobs can run by name
+type ApplicationsHandler struct {
+	scheduler *jobscheduler.Scheduler
+}
+
+// NewApplicationsHandler creates a new applications handler
+func NewApplicationsHandler(scheduler *jobscheduler.Scheduler) *ApplicationsHandler {
+	return &ApplicationsHandler{
+		scheduler: scheduler,
+	}
+}
+
+// ServeHTTP handles HTTP requests for applications
+func (h *ApplicationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Path is /api/v1/applications[/{name}]
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/applications"), "/")
+	if strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		h.handleList(w, r)
+	case r.Method == http.MethodPost && name == "":
+		h.handleCreate(w, r)
+	case r.Method == http.MethodGet:
+		h.handleGet(w, r, name)
+	case r.Method == http.MethodPut && name != "":
+		h.handleUpdate(w, r, name)
+	case r.Method == http.MethodDelete && name != "":
+		h.handleDelete(w, r, name)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleList lists every registered application
+func (h *ApplicationsHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	apps := h.scheduler.ListApplications()
+
+	response := api.ListApplicationsResponse{
+		Applications: make([]api.ApplicationResponse, len(apps)),
+		Total:        len(apps),
+	}
+	for i, app := range apps {
+		response.Applications[i] = toApplicationResponse(app)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleCreate registers a new application
+func (h *ApplicationsHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	app, ok := decodeApplication(w, r, "")
+	if !ok {
+		return
+	}
+
+	created, err := h.scheduler.CreateApplication(app)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create application: %v", err), applicationErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toApplicationResponse(*created))
+}
+
+// handleGet returns a single application
+func (h *ApplicationsHandler) handleGet(w http.ResponseWriter, r *http.Request, name string) {
+	app, err := h.scheduler.GetApplication(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get application: %v", err), applicationErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toApplicationResponse(*app))
+}
+
+// handleUpdate replaces a registered application
+func (h *ApplicationsHandler) handleUpdate(w http.ResponseWriter, r *http.Request, name string) {
+	app, ok := decodeApplication(w, r, name)
+	if !ok {
+		return
+	}
+
+	updated, err := h.scheduler.UpdateApplication(app)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update application: %v", err), applicationErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toApplicationResponse(*updated))
+}
+
+// handleDelete unregisters an application
+func (h *ApplicationsHandler) handleDelete(w http.ResponseWriter, r *http.Request, name string) {
+	if err := h.scheduler.DeleteApplication(name); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete application: %v", err), applicationErrorCode(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeApplication parses and validates an application request body,
+// writing an error response if it is invalid. A non-empty name overrides
+// the body's.
+func decodeApplication(w http.ResponseWriter, r *http.Request, name string) (jobscheduler.Application, bool) {
+	var req api.ApplicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return jobscheduler.Application{}, false
+	}
+	if name != "" {
+		req.Name = name
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return jobscheduler.Application{}, false
+	}
+
+	app := jobscheduler.Application{
+		Name:        req.Name,
+		Path:        req.Path,
+		Args:        req.Args,
+		AllowedArgs: req.AllowedArgs,
+		AllowedEnv:  req.AllowedEnv,
+		Env:         req.Env,
+		Checksum:    req.Checksum,
+	}
+	if req.Limits != nil {
+		app.Limits = jobscheduler.ResourceLimits(*req.Limits)
+	}
+	return app, true
+}
+
+// applicationErrorCode maps scheduler errors to HTTP status codes
+func applicationErrorCode(err error) int {
+	switch {
+	case errors.Is(err, jobscheduler.ErrApplicationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobscheduler.ErrApplicationExists):
+		return http.StatusConflict
+	default:
+		// Anything else is a definition the scheduler rejected
+		return http.StatusBadRequest
+	}
+}
+
+// toApplicationResponse converts a registered application to API format
+func toApplicationResponse(app jobscheduler.Application) api.ApplicationResponse {
+	response := api.ApplicationResponse{
+		Name:        app.Name,
+		Path:        app.Path,
+		Args:        app.Args,
+		AllowedArgs: app.AllowedArgs,
+		AllowedEnv:  app.AllowedEnv,
+		Env:         app.Env,
+		Checksum:    app.Checksum,
+	}
+	if app.Limits != (jobscheduler.ResourceLimits{}) {
+		limits := api.ResourceLimits(app.Limits)
+		response.Limits = &limits
+	}
+	return response
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jonathanleahy/project/jobscheduler"
+	"github.com/jonathanleahy/project/webserver/internal/api"
+)
+
+// ChannelsHandler handles requests for managing channels
+type ChannelsHandler struct {
+	scheduler *jobscheduler.Scheduler
+}
+
+// NewChannelsHandler creates a new channels handler
+func NewChannelsHandler(scheduler *jobscheduler.Scheduler) *ChannelsHandler {
+	return &ChannelsHandler{
+		scheduler: scheduler,
+	}
+}
+
+// ServeHTTP handles HTTP requests for channels
+func (h *ChannelsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Path is /api/v1/channels[/{name}]
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/channels"), "/")
+	if strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		h.handleList(w, r)
+	case r.Method == http.MethodPost && name == "":
+		h.handleCreate(w, r)
+	case r.Method == http.MethodGet:
+		h.handleGet(w, r, name)
+	case r.Method == http.MethodPut && name != "":
+		h.handleUpdate(w, r, name)
+	case r.Method == http.MethodDelete && name != "":
+		h.handleDelete(w, r, name)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleList lists every channel
+func (h *ChannelsHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	channels := h.scheduler.ListChannels()
+
+	response := api.ListChannelsResponse{
+		Channels: make([]api.ChannelResponse, len(channels)),
+		Total:    len(channels),
+	}
+	for i, channel := range channels {
+		response.Channels[i] = toChannelResponse(channel)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleCreate declares a new channel
+func (h *ChannelsHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	cfg, ok := decodeChannel(w, r, "")
+	if !ok {
+		return
+	}
+
+	created, err := h.scheduler.CreateChannel(cfg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create channel: %v", err), channelErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toChannelResponse(*created))
+}
+
+// handleGet returns a single channel
+func (h *ChannelsHandler) handleGet(w http.ResponseWriter, r *http.Request, name string) {
+	channel, err := h.scheduler.GetChannel(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get channel: %v", err), channelErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toChannelResponse(*channel))
+}
+
+// handleUpdate replaces the settings of a channel
+func (h *ChannelsHandler) handleUpdate(w http.ResponseWriter, r *http.Request, name string) {
+	cfg, ok := decodeChannel(w, r, name)
+	if !ok {
+		return
+	}
+
+	updated, err := h.scheduler.UpdateChannel(cfg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update channel: %v", err), channelErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toChannelResponse(*updated))
+}
+
+// handleDelete removes an idle channel
+func (h *ChannelsHandler) handleDelete(w http.ResponseWriter, r *http.Request, name string) {
+	if err := h.scheduler.DeleteChannel(name); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete channel: %v", err), channelErrorCode(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeChannel parses and validates a channel request body, writing an
+// error response if it is invalid. A non-empty name overrides the body's.
+func decodeChannel(w http.ResponseWriter, r *http.Request, name string) (jobscheduler.ChannelConfig, bool) {
+	var req api.ChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return jobscheduler.ChannelConfig{}, false
+	}
+	if name != "" {
+		req.Name = name
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return jobscheduler.ChannelConfig{}, false
+	}
+
+	return jobscheduler.ChannelConfig{
+		Name:         req.Name,
+		Workers:      req.Workers,
+		Timeout:      time.Duration(req.TimeoutSeconds) * time.Second,
+		KillTimeout:  time.Duration(req.KillTimeoutSeconds) * time.Second,
+		BufferSize:   req.BufferSize,
+		MaxQueueSize: req.MaxQueueSize,
+		Sandbox:      req.Sandbox,
+	}, true
+}
+
+// channelErrorCode maps scheduler errors to HTTP status codes
+func channelErrorCode(err error) int {
+	switch {
+	case errors.Is(err, jobscheduler.ErrChannelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobscheduler.ErrChannelExists), errors.Is(err, jobscheduler.ErrChannelBusy):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// toChannelResponse converts a channel to API format
+func toChannelResponse(channel jobscheduler.ChannelStatus) api.ChannelResponse {
+	return api.ChannelResponse{
+		Name:               channel.Name,
+		Workers:            channel.Workers,
+		TimeoutSeconds:     int(channel.Timeout / time.Second),
+		KillTimeoutSeconds: int(channel.KillTimeout / time.Second),
+		BufferSize:         channel.BufferSize,
+		MaxQueueSize:       channel.MaxQueueSize,
+		Sandbox:            channel.Sandbox,
+		Declared:           channel.Declared,
+		Queued:             channel.Queued,
+		Running:            channel.Running,
+	}
+}
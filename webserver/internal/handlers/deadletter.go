@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jonathanleahy/project/jobscheduler"
+	"github.com/jonathanleahy/project/webserver/internal/api"
+)
+
+// DeadLetterHandler handles requests for jobs that exhausted their retries
+type DeadLetterHandler struct {
+	scheduler *jobscheduler.Scheduler
+}
+
+// NewDeadLetterHandler creates a new dead-letter handler
+func NewDeadLetterHandler(scheduler *jobscheduler.Scheduler) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		scheduler: scheduler,
+	}
+}
+
+// ServeHTTP handles HTTP requests for dead-lettered jobs
+func (h *DeadLetterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Path is /api/v1/deadletter[/{id}][/requeue]
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/deadletter"), "/")
+	var parts []string
+	if rest != "" {
+		parts = strings.Split(rest, "/")
+	}
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 0:
+		h.handleList(w, r)
+	case r.Method == http.MethodGet && len(parts) == 1:
+		h.handleGet(w, r, parts[0])
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "requeue":
+		h.handleRequeueBulk(w, r)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "requeue":
+		h.handleRequeue(w, r, parts[0])
+	case r.Method == http.MethodDelete && len(parts) == 0:
+		h.handlePurgeAll(w, r)
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		h.handlePurge(w, r, parts[0])
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleList lists dead-lettered jobs, optionally filtered by channel
+func (h *DeadLetterHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	letters := h.scheduler.ListDeadLetters(r.URL.Query().Get("channel"))
+
+	response := api.ListDeadLettersResponse{
+		DeadLetters: make([]api.DeadLetterResponse, len(letters)),
+		Total:       len(letters),
+	}
+	for i, dl := range letters {
+		response.DeadLetters[i] = toDeadLetterResponse(dl)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGet returns a single dead-lettered job with its attempt history
+func (h *DeadLetterHandler) handleGet(w http.ResponseWriter, r *http.Request, jobID string) {
+	dl, err := h.scheduler.GetDeadLetter(jobID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get dead letter: %v", err), deadLetterErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toDeadLetterResponse(*dl))
+}
+
+// handleRequeue requeues a single dead-lettered job
+func (h *DeadLetterHandler) handleRequeue(w http.ResponseWriter, r *http.Request, jobID string) {
+	if err := h.scheduler.RequeueDeadLetterAs(jobID, actor(r)); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to requeue job: %v", err), deadLetterErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(api.RequeueDeadLettersResponse{
+		JobIDs:   []string{jobID},
+		Requeued: 1,
+	})
+}
+
+// handleRequeueBulk requeues the listed jobs, or every dead-lettered job in
+// a channel when no job IDs are given
+func (h *DeadLetterHandler) handleRequeueBulk(w http.ResponseWriter, r *http.Request) {
+	var req api.RequeueDeadLettersRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var requeued []string
+	if len(req.JobIDs) > 0 {
+		for _, jobID := range req.JobIDs {
+			if err := h.scheduler.RequeueDeadLetterAs(jobID, actor(r)); err != nil {
+				http.Error(w, fmt.Sprintf("Failed to requeue job: %v", err), deadLetterErrorCode(err))
+				return
+			}
+			requeued = append(requeued, jobID)
+		}
+	} else {
+		var err error
+		requeued, err = h.scheduler.RequeueDeadLettersAs(req.Channel, actor(r))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to requeue jobs: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(api.RequeueDeadLettersResponse{
+		JobIDs:   requeued,
+		Requeued: len(requeued),
+	})
+}
+
+// handlePurge permanently removes a single dead-lettered job
+func (h *DeadLetterHandler) handlePurge(w http.ResponseWriter, r *http.Request, jobID string) {
+	if err := h.scheduler.PurgeDeadLetter(jobID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to purge job: %v", err), deadLetterErrorCode(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePurgeAll permanently removes every dead-lettered job, optionally
+// limited to one channel
+func (h *DeadLetterHandler) handlePurgeAll(w http.ResponseWriter, r *http.Request) {
+	purged, err := h.scheduler.PurgeDeadLetters(r.URL.Query().Get("channel"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to purge jobs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.PurgeDeadLettersResponse{Purged: purged})
+}
+
+// deadLetterErrorCode maps scheduler errors to HTTP status codes
+func deadLetterErrorCode(err error) int {
+	switch {
+	case errors.Is(err, jobscheduler.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobscheduler.ErrJobNotDeadLettered):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// toDeadLetterResponse converts a dead-lettered job to API format
+func toDeadLetterResponse(dl jobscheduler.DeadLetter) api.DeadLetterResponse {
+	response := api.DeadLetterResponse{
+		JobID:          dl.Job.ID,
+		Channel:        dl.Job.Channel,
+		Status:         string(dl.Status),
+		Error:          dl.Error,
+		ExitCode:       dl.ExitCode,
+		Payload:        dl.Job.Body,
+		FirstAttemptAt: dl.FirstAttemptAt,
+		DeadLetteredAt: dl.DeadLetteredAt,
+		Attempts:       make([]api.JobAttempt, len(dl.Attempts)),
+	}
+	if app := dl.Job.Application; app != nil {
+		response.Application = &api.ApplicationConfig{
+			Name:        app.Name,
+			Path:        app.Path,
+			Args:        app.Args,
+			Env:         app.Env,
+			WorkingDir:  app.WorkingDir,
+			PassPayload: app.PassPayload,
+			Timeout:     int(app.Timeout / time.Second),
+			KillTimeout: int(app.KillTimeout / time.Second),
+			Artifacts:   app.Artifacts,
+		}
+		for _, in := range app.Inputs {
+			response.Application.Inputs = append(response.Application.Inputs, api.JobInput{
+				JobID:    in.JobID,
+				Source:   string(in.Source),
+				Artifact: in.Artifact,
+				File:     in.File,
+			})
+		}
+		if app.Limits != (jobscheduler.ResourceLimits{}) {
+			limits := api.ResourceLimits(app.Limits)
+			response.Application.Limits = &limits
+		}
+		if app.Sandbox != nil {
+			response.Application.Sandbox = &api.SandboxConfig{Network: app.Sandbox.Network}
+		}
+	}
+	for i, attempt := range dl.Attempts {
+		response.Attempts[i] = api.JobAttempt{
+			Attempt:       attempt.Attempt,
+			Status:        string(attempt.Status),
+			ExitCode:      attempt.ExitCode,
+			Error:         attempt.Error,
+			Termination:   string(attempt.Termination),
+			FailureReason: string(attempt.FailureReason),
+			StartTime:     attempt.StartTime,
+			EndTime:       attempt.EndTime,
+		}
+	}
+	return response
+}
@@ -2,8 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,10 +34,28 @@ func (h *JobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		h.handleSubmitJob(w, r)
 	case http.MethodGet:
-		if strings.Contains(r.URL.Path, "/status/") {
-			h.handleJobStatus(w, r)
-		} else {
+		// Artifacts are under /api/v1/jobs/{id}/artifacts[/{name}], the
+		// whole of its output under /api/v1/jobs/{id}/output/{stream}, its
+		// log under /api/v1/jobs/{id}/logs, and its history under
+		// /api/v1/jobs/{id}/events
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/jobs"), "/")
+		jobID, sub, _ := strings.Cut(rest, "/")
+		resource, arg, _ := strings.Cut(sub, "/")
+		switch {
+		case rest == "":
 			h.handleListJobs(w, r)
+		case resource == "artifacts":
+			h.handleArtifacts(w, r, jobID, arg)
+		case resource == "output" && !strings.Contains(arg, "/"):
+			h.handleOutput(w, r, jobID, arg)
+		case sub == "logs":
+			h.handleLogs(w, r, jobID)
+		case sub == "events":
+			h.handleEvents(w, r, jobID)
+		case jobID == "status" && sub != "" && !strings.Contains(sub, "/"):
+			h.handleJobStatus(w, r)
+		default:
+			http.NotFound(w, r)
 		}
 	case http.MethodDelete:
 		h.handleCancelJob(w, r)
@@ -57,12 +80,55 @@ func (h *JobsHandler) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Convert API request to scheduler job
+	job := toJobPayload(req)
+	job.SubmittedBy = actor(r)
+
+	// Submit job
+	if err := h.scheduler.SubmitJob(job); err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, jobscheduler.ErrInvalidJob), errors.Is(err, jobscheduler.ErrDependencyNotFound),
+			errors.Is(err, jobscheduler.ErrApplicationNotFound), errors.Is(err, jobscheduler.ErrApplicationNotAllowed):
+			code = http.StatusBadRequest
+		case errors.Is(err, jobscheduler.ErrJobExists):
+			code = http.StatusConflict
+		case errors.Is(err, jobscheduler.ErrChannelFull):
+			code = http.StatusServiceUnavailable
+		}
+		http.Error(w, fmt.Sprintf("Failed to submit job: %v", err), code)
+		return
+	}
+
+	// Return success response
+	response := api.SubmitJobResponse{
+		JobID:     job.ID,
+		Channel:   job.Channel,
+		Status:    "accepted",
+		Submitted: time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// toJobPayload converts a job submission request to a scheduler job. Jobs
+// submitted over HTTP are untrusted, and sandboxed if the server says so.
+func toJobPayload(req api.SubmitJobRequest) jobscheduler.JobPayload {
 	job := jobscheduler.JobPayload{
-		ID:      req.JobID,
-		Channel: req.Channel,
-		Workers: req.Workers,
-		Timeout: time.Duration(req.TimeoutSeconds) * time.Second,
-		Body:    req.Payload,
+		Untrusted: true,
+		ID:        req.JobID,
+		Channel:   req.Channel,
+		Type:      req.Type,
+		Workers:   req.Workers,
+		Timeout:   time.Duration(req.TimeoutSeconds) * time.Second,
+		Priority:  req.Priority,
+		Delay:     time.Duration(req.DelaySeconds) * time.Second,
+		DependsOn: req.DependsOn,
+		Body:      req.Payload,
+	}
+	if req.RunAt != nil {
+		job.RunAt = *req.RunAt
 	}
 
 	// Add application config if present
@@ -74,26 +140,60 @@ func (h *JobsHandler) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
 			Env:         req.Application.Env,
 			WorkingDir:  req.Application.WorkingDir,
 			PassPayload: req.Application.PassPayload,
+			Timeout:     time.Duration(req.Application.Timeout) * time.Second,
+			KillTimeout: time.Duration(req.Application.KillTimeout) * time.Second,
+			Artifacts:   req.Application.Artifacts,
+		}
+		for _, in := range req.Application.Inputs {
+			job.Application.Inputs = append(job.Application.Inputs, jobscheduler.JobInput{
+				JobID:    in.JobID,
+				Source:   jobscheduler.InputSource(in.Source),
+				Artifact: in.Artifact,
+				File:     in.File,
+			})
+		}
+		if req.Application.Limits != nil {
+			job.Application.Limits = jobscheduler.ResourceLimits(*req.Application.Limits)
+		}
+		if sandbox := req.Application.Sandbox; sandbox != nil {
+			job.Application.Sandbox = &jobscheduler.SandboxConfig{Network: sandbox.Network}
 		}
 	}
 
-	// Submit job
-	if err := h.scheduler.SubmitJob(job); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to submit job: %v", err), http.StatusInternalServerError)
-		return
+	// Add retry policy if present
+	if req.Retry != nil {
+		job.RetryPolicy = toRetryPolicy(*req.Retry)
 	}
+	return job
+}
 
-	// Return success response
-	response := api.SubmitJobResponse{
-		JobID:     job.ID,
-		Channel:   job.Channel,
-		Status:    "accepted",
-		Submitted: time.Now(),
+// toRetryPolicy converts a requested retry policy, filling in the same
+// backoff defaults as a configured policy when it enables retries
+func toRetryPolicy(req api.RetryPolicy) *jobscheduler.RetryPolicy {
+	policy := &jobscheduler.RetryPolicy{
+		MaxRetries:            req.MaxRetries,
+		InitialDelay:          time.Duration(req.InitialDelaySeconds) * time.Second,
+		MaxDelay:              time.Duration(req.MaxDelaySeconds) * time.Second,
+		BackoffFactor:         req.BackoffFactor,
+		Jitter:                req.Jitter,
+		NonRetryableExitCodes: req.NonRetryableExitCodes,
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(response)
+	if policy.MaxRetries == 0 {
+		return policy
+	}
+	if policy.InitialDelay == 0 {
+		policy.InitialDelay = time.Second
+	}
+	if policy.MaxDelay == 0 {
+		policy.MaxDelay = 5 * time.Minute
+	}
+	if policy.BackoffFactor == 0 {
+		policy.BackoffFactor = 2
+	}
+	if policy.Jitter == 0 {
+		policy.Jitter = 0.2
+	}
+	return policy
 }
 
 // handleJobStatus retrieves the status of a specific job
@@ -113,14 +213,13 @@ func (h *JobsHandler) handleJobStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert to API response
-	response := api.JobStatusResponse{
-		JobID:     status.ID,
-		Channel:   status.Channel,
-		Status:    string(status.Status),
-		StartTime: status.StartTime,
-		EndTime:   status.EndTime,
-		Error:     status.Error,
+	// Convert to API response, including the output so far
+	response := toStatusResponse(h.scheduler, *status)
+	if result, err := h.scheduler.GetJobResult(jobID); err == nil {
+		response.Logs = resultLogs(result)
+		response.Truncated = result.OutputOmitted > 0 || result.StderrOmitted > 0
+	} else if lines, _, err := h.scheduler.JobLogs(jobID, 0); err == nil {
+		response.Logs = liveLogs(lines)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -136,24 +235,18 @@ func (h *JobsHandler) handleListJobs(w http.ResponseWriter, r *http.Request) {
 	// Get jobs from scheduler
 	jobs, err := h.scheduler.ListJobs(channel, status)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list jobs: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to list jobs: %v", err), http.StatusBadRequest)
 		return
 	}
 
 	// Convert to API response
 	response := api.ListJobsResponse{
-		Jobs: make([]api.JobStatusResponse, len(jobs)),
+		Jobs:      make([]api.JobStatusResponse, len(jobs)),
+		TotalJobs: len(jobs),
 	}
 
 	for i, job := range jobs {
-		response.Jobs[i] = api.JobStatusResponse{
-			JobID:     job.ID,
-			Channel:   job.Channel,
-			Status:    string(job.Status),
-			StartTime: job.StartTime,
-			EndTime:   job.EndTime,
-			Error:     job.Error,
-		}
+		response.Jobs[i] = toStatusResponse(h.scheduler, job)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -171,10 +264,312 @@ func (h *JobsHandler) handleCancelJob(w http.ResponseWriter, r *http.Request) {
 	jobID := parts[len(parts)-1]
 
 	// Cancel job
-	if err := h.scheduler.CancelJob(jobID); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to cancel job: %v", err), http.StatusInternalServerError)
+	if err := h.scheduler.CancelJobAs(jobID, actor(r)); err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, jobscheduler.ErrJobNotFound):
+			code = http.StatusNotFound
+		case errors.Is(err, jobscheduler.ErrJobFinished):
+			code = http.StatusConflict
+		}
+		http.Error(w, fmt.Sprintf("Failed to cancel job: %v", err), code)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// toStatusResponse converts a scheduler job to its API representation
+func toStatusResponse(scheduler *jobscheduler.Scheduler, job jobscheduler.JobPayload) api.JobStatusResponse {
+	response := api.JobStatusResponse{
+		JobID:       job.ID,
+		Channel:     job.Channel,
+		Type:        job.Type,
+		Status:      string(job.Status),
+		StartTime:   job.StartTime,
+		EndTime:     job.EndTime,
+		Error:       job.Error,
+		RetryCount:  job.RetryCount,
+		Priority:    job.Priority,
+		RunAt:       job.RunAt,
+		DependsOn:   job.DependsOn,
+		WorkflowID:  job.WorkflowID,
+		SubmittedBy: job.SubmittedBy,
+	}
+	if !job.EndTime.IsZero() && !job.StartTime.IsZero() {
+		response.Duration = job.EndTime.Sub(job.StartTime).String()
+	}
+	if job.Progress != nil {
+		response.Progress = job.Progress.Percent
+		response.Message = job.Progress.Message
+	}
+	if result, err := scheduler.GetJobResult(job.ID); err == nil {
+		response.ExitCode = result.ExitCode
+		response.Termination = string(result.Termination)
+		response.Survivors = result.Survivors
+		response.FailureReason = string(result.FailureReason)
+		for _, artifact := range result.Artifacts {
+			response.Artifacts = append(response.Artifacts, api.Artifact(artifact))
+		}
+	}
+	return response
+}
+
+// handleArtifacts lists a finished job's artifacts, or downloads one of
+// them when a name is given
+func (h *JobsHandler) handleArtifacts(w http.ResponseWriter, r *http.Request, jobID, name string) {
+	if name == "" {
+		artifacts, err := h.scheduler.ListArtifacts(jobID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list artifacts: %v", err), artifactErrorCode(err))
+			return
+		}
+		response := api.ListArtifactsResponse{
+			JobID:     jobID,
+			Artifacts: make([]api.Artifact, len(artifacts)),
+		}
+		for i, artifact := range artifacts {
+			response.Artifacts[i] = api.Artifact(artifact)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	f, err := h.scheduler.OpenArtifact(jobID, name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get artifact: %v", err), artifactErrorCode(err))
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get artifact: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Always download, so that a job cannot serve pages from this origin
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(name)}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// artifactErrorCode maps scheduler errors to HTTP status codes
+func artifactErrorCode(err error) int {
+	switch {
+	case errors.Is(err, jobscheduler.ErrJobNotFound), errors.Is(err, jobscheduler.ErrArtifactNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobscheduler.ErrJobNotFinished):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// handleOutput returns the whole of a finished job's stdout or stderr
+func (h *JobsHandler) handleOutput(w http.ResponseWriter, r *http.Request, jobID, stream string) {
+	if stream != string(jobscheduler.LogStdout) && stream != string(jobscheduler.LogStderr) {
+		http.NotFound(w, r)
+		return
+	}
+
+	output, err := h.scheduler.OpenOutput(jobID, jobscheduler.LogStream(stream))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get output: %v", err), outputErrorCode(err))
+		return
+	}
+	defer output.Close()
+
+	// Large output can take longer to send than the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, output)
+}
+
+// outputErrorCode maps scheduler errors to HTTP status codes
+func outputErrorCode(err error) int {
+	switch {
+	case errors.Is(err, jobscheduler.ErrJobNotFound), errors.Is(err, jobscheduler.ErrOutputNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobscheduler.ErrJobNotFinished):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// logHeartbeatInterval is how often an idle log stream sends a comment,
+// so that proxies do not close it
+const logHeartbeatInterval = 15 * time.Second
+
+// handleLogs returns the lines of a job's output after ?since=, or with
+// ?follow=true streams them as server-sent events until the job finishes.
+// Each line is a JSON LogLine message whose ID is its seq, so a
+// reconnecting EventSource resumes where it left off, and the stream ends
+// with an "end" event whose data is the job's status.
+func (h *JobsHandler) handleLogs(w http.ResponseWriter, r *http.Request, jobID string) {
+	query := r.URL.Query()
+	since := query.Get("since")
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		since = lastID
+	}
+	var seq int64
+	if since != "" {
+		var err error
+		if seq, err = strconv.ParseInt(since, 10, 64); err != nil || seq < 0 {
+			http.Error(w, fmt.Sprintf("Invalid since: %s", since), http.StatusBadRequest)
+			return
+		}
+	}
+	follow := false
+	if value := query.Get("follow"); value != "" {
+		var err error
+		if follow, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid follow: %s", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !follow {
+		lines, done, err := h.scheduler.JobLogs(jobID, seq)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get logs: %v", err), logErrorCode(err))
+			return
+		}
+		response := api.JobLogsResponse{
+			JobID: jobID,
+			Lines: make([]api.LogLine, len(lines)),
+			Done:  done,
+		}
+		for i, line := range lines {
+			response.Lines[i] = toLogLine(line)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	lines, err := h.scheduler.FollowJobLogs(r.Context(), jobID, seq)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to follow logs: %v", err), logErrorCode(err))
+		return
+	}
+
+	// The stream lasts as long as the job, beyond the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	heartbeat := time.NewTicker(logHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if r.Context().Err() != nil {
+					return // The client went away
+				}
+				status := ""
+				if job, err := h.scheduler.GetJobStatus(jobID); err == nil {
+					status = string(job.Status)
+				}
+				fmt.Fprintf(w, "event: end\ndata: %s\n\n", status)
+				rc.Flush()
+				return
+			}
+			data, _ := json.Marshal(toLogLine(line))
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", line.Seq, data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// logErrorCode maps scheduler errors to HTTP status codes
+func logErrorCode(err error) int {
+	if errors.Is(err, jobscheduler.ErrJobNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// handleEvents returns everything that has happened to a job, oldest first
+func (h *JobsHandler) handleEvents(w http.ResponseWriter, r *http.Request, jobID string) {
+	events, err := h.scheduler.JobEvents(jobID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, jobscheduler.ErrJobNotFound) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get job events: %v", err), code)
+		return
+	}
+
+	response := api.JobEventsResponse{
+		JobID:  jobID,
+		Events: make([]api.JobEvent, len(events)),
+	}
+	for i, event := range events {
+		response.Events[i] = api.JobEvent{
+			Time:     event.Time,
+			Event:    string(event.Event),
+			Attempt:  event.Attempt,
+			Status:   string(event.Status),
+			Actor:    event.Actor,
+			Duration: event.Duration,
+			ExitCode: event.ExitCode,
+			Error:    event.Error,
+			Message:  event.Message,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// toLogLine converts a line of a job's output to API format
+func toLogLine(line jobscheduler.LogLine) api.LogLine {
+	return api.LogLine{
+		Seq:    line.Seq,
+		Time:   line.Time,
+		Stream: string(line.Stream),
+		Text:   line.Text,
+	}
+}
+
+// liveLogs formats the lines a running job has logged so far like
+// resultLogs, though in the order they were written
+func liveLogs(lines []jobscheduler.LogLine) []string {
+	logs := make([]string, len(lines))
+	for i, line := range lines {
+		logs[i] = line.Text
+		if line.Stream == jobscheduler.LogStderr {
+			logs[i] = "[stderr] " + line.Text
+		}
+	}
+	return logs
+}
+
+// resultLogs splits captured output into log lines, stdout first
+func resultLogs(result *jobscheduler.JobResult) []string {
+	var logs []string
+	for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+		if line != "" {
+			logs = append(logs, line)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimRight(result.Stderr, "\n"), "\n") {
+		if line != "" {
+			logs = append(logs, "[stderr] "+line)
+		}
+	}
+	return logs
+}
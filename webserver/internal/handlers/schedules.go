@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jonathanleahy/project/jobscheduler"
+	"github.com/jonathanleahy/project/webserver/internal/api"
+)
+
+// SchedulesHandler handles requests for recurring job schedules
+type SchedulesHandler struct {
+	scheduler *jobscheduler.Scheduler
+}
+
+// NewSchedulesHandler creates a new schedules handler
+func NewSchedulesHandler(scheduler *jobscheduler.Scheduler) *SchedulesHandler {
+	return &SchedulesHandler{
+		scheduler: scheduler,
+	}
+}
+
+// ServeHTTP handles HTTP requests for schedules
+func (h *SchedulesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Path is /api/v1/schedules[/{id}]
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/schedules"), "/")
+	if strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		h.handleList(w, r)
+	case r.Method == http.MethodPost && id == "":
+		h.handleCreate(w, r)
+	case r.Method == http.MethodGet:
+		h.handleGet(w, r, id)
+	case r.Method == http.MethodPut:
+		h.handleUpdate(w, r, id)
+	case r.Method == http.MethodDelete && id != "":
+		h.handleDelete(w, r, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleList lists every schedule
+func (h *SchedulesHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	schedules := h.scheduler.ListSchedules()
+
+	response := api.ListSchedulesResponse{
+		Schedules: make([]api.ScheduleResponse, len(schedules)),
+		Total:     len(schedules),
+	}
+	for i, schedule := range schedules {
+		response.Schedules[i] = toScheduleResponse(schedule)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleCreate creates a new schedule
+func (h *SchedulesHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	schedule, ok := decodeSchedule(w, r, "")
+	if !ok {
+		return
+	}
+
+	created, err := h.scheduler.CreateSchedule(schedule)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create schedule: %v", err), scheduleErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toScheduleResponse(*created))
+}
+
+// handleGet returns a single schedule
+func (h *SchedulesHandler) handleGet(w http.ResponseWriter, r *http.Request, id string) {
+	schedule, err := h.scheduler.GetSchedule(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get schedule: %v", err), scheduleErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toScheduleResponse(*schedule))
+}
+
+// handleUpdate replaces the definition of a schedule
+func (h *SchedulesHandler) handleUpdate(w http.ResponseWriter, r *http.Request, id string) {
+	if id == "" {
+		http.Error(w, "Schedule ID is required", http.StatusBadRequest)
+		return
+	}
+
+	schedule, ok := decodeSchedule(w, r, id)
+	if !ok {
+		return
+	}
+
+	updated, err := h.scheduler.UpdateSchedule(schedule)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update schedule: %v", err), scheduleErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toScheduleResponse(*updated))
+}
+
+// handleDelete removes a schedule
+func (h *SchedulesHandler) handleDelete(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.scheduler.DeleteSchedule(id); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete schedule: %v", err), scheduleErrorCode(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeSchedule parses and validates a schedule request body, writing an
+// error response if it is invalid. A non-empty id overrides the body's ID.
+func decodeSchedule(w http.ResponseWriter, r *http.Request, id string) (jobscheduler.Schedule, bool) {
+	var req api.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return jobscheduler.Schedule{}, false
+	}
+	if id != "" {
+		req.ID = id
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return jobscheduler.Schedule{}, false
+	}
+
+	schedule := jobscheduler.Schedule{
+		ID:         req.ID,
+		Expression: req.Expression,
+		Timezone:   req.Timezone,
+		Template:   toJobPayload(req.Job),
+		Overlap:    jobscheduler.OverlapPolicy(req.Overlap),
+		CatchUp:    jobscheduler.CatchUpPolicy(req.CatchUp),
+		Paused:     req.Paused,
+	}
+	if err := schedule.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return jobscheduler.Schedule{}, false
+	}
+	return schedule, true
+}
+
+// scheduleErrorCode maps scheduler errors to HTTP status codes
+func scheduleErrorCode(err error) int {
+	switch {
+	case errors.Is(err, jobscheduler.ErrApplicationNotFound), errors.Is(err, jobscheduler.ErrApplicationNotAllowed):
+		// The schedule's job names an application it may not run
+		return http.StatusBadRequest
+	case errors.Is(err, jobscheduler.ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobscheduler.ErrScheduleExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// toScheduleResponse converts a schedule to API format
+func toScheduleResponse(schedule jobscheduler.Schedule) api.ScheduleResponse {
+	return api.ScheduleResponse{
+		ID:         schedule.ID,
+		Expression: schedule.Expression,
+		Timezone:   schedule.Timezone,
+		Channel:    schedule.Template.Channel,
+		Overlap:    string(schedule.Overlap),
+		CatchUp:    string(schedule.CatchUp),
+		Paused:     schedule.Paused,
+		CreatedAt:  schedule.CreatedAt,
+		NextRun:    schedule.NextRun,
+		LastRun:    schedule.LastRun,
+		LastJobID:  schedule.LastJobID,
+		Runs:       schedule.Runs,
+		Skipped:    schedule.Skipped,
+		Held:       schedule.Held,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/jonathanleahy/project/jobscheduler"
 	"github.com/jonathanleahy/project/webserver/internal/api"
@@ -62,24 +63,38 @@ func (h *StatsHandler) handleChannelStats(w http.ResponseWriter, r *http.Request
 
 // handleSummaryStats returns summarized statistics
 func (h *StatsHandler) handleSummaryStats(w http.ResponseWriter, r *http.Request) {
-	// Get time range from query parameters
+	// Get time range from query parameters, as RFC 3339 times
 	from := r.URL.Query().Get("from")
 	to := r.URL.Query().Get("to")
+	fromTime, err := parseTime(from)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid from: %v", err), http.StatusBadRequest)
+		return
+	}
+	toTime, err := parseTime(to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid to: %v", err), http.StatusBadRequest)
+		return
+	}
 
 	// Get summary statistics from scheduler
-	summary, err := h.scheduler.GetStatsSummary(from, to)
+	summary, err := h.scheduler.GetStatsSummary(fromTime, toTime)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get stats summary: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to get stats summary: %v", err), http.StatusBadRequest)
 		return
 	}
 
 	// Convert to API response
 	response := api.StatsSummary{
-		TotalJobs:      summary.TotalJobs,
-		CompletedJobs:  summary.CompletedJobs,
-		FailedJobs:     summary.FailedJobs,
-		AverageRuntime: summary.AverageRuntime,
-		ActiveChannels: summary.ActiveChannels,
+		TotalJobs:       summary.TotalJobs,
+		CompletedJobs:   summary.CompletedJobs,
+		FailedJobs:      summary.FailedJobs,
+		TimedOutJobs:    summary.TimedOutJobs,
+		CancelledJobs:   summary.CancelledJobs,
+		InterruptedJobs: summary.InterruptedJobs,
+		RetriedJobs:     summary.RetriedJobs,
+		AverageRuntime:  summary.AverageRuntime,
+		ActiveChannels:  summary.ActiveChannels,
 		TimeRange: api.TimeRange{
 			From: from,
 			To:   to,
@@ -97,13 +112,19 @@ func (h *StatsHandler) handleOverallStats(w http.ResponseWriter, r *http.Request
 
 	// Convert to API response
 	response := api.OverallStats{
-		ActiveJobs:     stats.ActiveJobs,
-		QueuedJobs:     stats.QueuedJobs,
-		CompletedJobs:  stats.CompletedJobs,
-		FailedJobs:     stats.FailedJobs,
-		ActiveChannels: stats.ActiveChannels,
-		Uptime:         stats.Uptime.String(),
-		LastUpdate:     stats.LastUpdate,
+		ActiveJobs:      stats.ActiveJobs,
+		QueuedJobs:      stats.QueuedJobs,
+		TotalJobs:       stats.TotalJobs,
+		CompletedJobs:   stats.CompletedJobs,
+		FailedJobs:      stats.FailedJobs,
+		TimedOutJobs:    stats.TimedOutJobs,
+		CancelledJobs:   stats.CancelledJobs,
+		InterruptedJobs: stats.InterruptedJobs,
+		RetriedJobs:     stats.RetriedJobs,
+		ActiveChannels:  stats.ActiveChannels,
+		Throughput:      stats.Throughput,
+		Uptime:          stats.Uptime.Round(time.Second).String(),
+		LastUpdate:      stats.LastUpdate,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -113,10 +134,27 @@ func (h *StatsHandler) handleOverallStats(w http.ResponseWriter, r *http.Request
 // convertToAPIStats converts internal stats to API format
 func convertToAPIStats(stats *jobscheduler.ChannelStats) api.ChannelStats {
 	return api.ChannelStats{
-		Workers:     stats.Workers,
-		ActiveJobs:  stats.ActiveJobs,
-		TotalJobs:   stats.TotalJobs,
-		FailedJobs:  stats.FailedJobs,
-		LastJobTime: stats.LastJobTime,
+		Workers:         stats.Workers,
+		ActiveJobs:      stats.ActiveJobs,
+		TotalJobs:       stats.TotalJobs,
+		CompletedJobs:   stats.CompletedJobs,
+		FailedJobs:      stats.FailedJobs,
+		TimedOutJobs:    stats.TimedOutJobs,
+		CancelledJobs:   stats.CancelledJobs,
+		InterruptedJobs: stats.InterruptedJobs,
+		RetriedJobs:     stats.RetriedJobs,
+		LastJobTime:     stats.LastJobTime,
+		Uptime:          time.Since(stats.Since).Round(time.Second).String(),
+		QueueSize:       stats.QueuedJobs,
+		Throughput:      stats.Throughput,
+		RunDuration:     api.RunDuration(stats.RunDuration),
+	}
+}
+
+// parseTime parses an RFC 3339 time, leaving an empty one zero
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
 	}
+	return time.Parse(time.RFC3339, value)
 }
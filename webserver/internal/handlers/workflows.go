@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jonathanleahy/project/jobscheduler"
+	"github.com/jonathanleahy/project/webserver/internal/api"
+)
+
+// WorkflowsHandler handles requests for workflows of dependent jobs
+type WorkflowsHandler struct {
+	scheduler *jobscheduler.Scheduler
+}
+
+// NewWorkflowsHandler creates a new workflows handler
+func NewWorkflowsHandler(scheduler *jobscheduler.Scheduler) *WorkflowsHandler {
+	return &WorkflowsHandler{
+		scheduler: scheduler,
+	}
+}
+
+// ServeHTTP handles HTTP requests for workflows
+func (h *WorkflowsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Path is /api/v1/workflows[/{id}]
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/workflows"), "/")
+	if strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		h.handleList(w, r)
+	case r.Method == http.MethodPost && id == "":
+		h.handleSubmit(w, r)
+	case r.Method == http.MethodGet:
+		h.handleGet(w, r, id)
+	case r.Method == http.MethodDelete && id != "":
+		h.handleCancel(w, r, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSubmit submits every job of a workflow
+func (h *WorkflowsHandler) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req api.SubmitWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	workflow := jobscheduler.Workflow{
+		ID:   req.WorkflowID,
+		Jobs: make([]jobscheduler.JobPayload, len(req.Jobs)),
+	}
+	jobIDs := make([]string, len(req.Jobs))
+	for i, jobReq := range req.Jobs {
+		workflow.Jobs[i] = toJobPayload(jobReq)
+		workflow.Jobs[i].SubmittedBy = actor(r)
+		jobIDs[i] = jobReq.JobID
+	}
+
+	if err := h.scheduler.SubmitWorkflow(workflow); err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, jobscheduler.ErrInvalidJob), errors.Is(err, jobscheduler.ErrDependencyCycle),
+			errors.Is(err, jobscheduler.ErrDependencyNotFound), errors.Is(err, jobscheduler.ErrApplicationNotFound),
+			errors.Is(err, jobscheduler.ErrApplicationNotAllowed):
+			code = http.StatusBadRequest
+		case errors.Is(err, jobscheduler.ErrJobExists), errors.Is(err, jobscheduler.ErrWorkflowExists):
+			code = http.StatusConflict
+		case errors.Is(err, jobscheduler.ErrChannelFull):
+			code = http.StatusServiceUnavailable
+		}
+		http.Error(w, fmt.Sprintf("Failed to submit workflow: %v", err), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(api.SubmitWorkflowResponse{
+		WorkflowID: workflow.ID,
+		JobIDs:     jobIDs,
+		Status:     "accepted",
+		Submitted:  time.Now(),
+	})
+}
+
+// handleList lists the aggregate status of every workflow
+func (h *WorkflowsHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	workflows := h.scheduler.ListWorkflows()
+
+	response := api.ListWorkflowsResponse{
+		Workflows: make([]api.WorkflowStatusResponse, len(workflows)),
+		Total:     len(workflows),
+	}
+	for i, workflow := range workflows {
+		response.Workflows[i] = h.toWorkflowResponse(workflow)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGet returns the aggregate status of a workflow and its jobs
+func (h *WorkflowsHandler) handleGet(w http.ResponseWriter, r *http.Request, id string) {
+	workflow, err := h.scheduler.GetWorkflowStatus(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get workflow: %v", err), workflowErrorCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.toWorkflowResponse(*workflow))
+}
+
+// handleCancel cancels every unfinished job of a workflow
+func (h *WorkflowsHandler) handleCancel(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.scheduler.CancelWorkflowAs(id, actor(r)); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to cancel workflow: %v", err), workflowErrorCode(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// workflowErrorCode maps scheduler errors to HTTP status codes
+func workflowErrorCode(err error) int {
+	if errors.Is(err, jobscheduler.ErrWorkflowNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// toWorkflowResponse converts a workflow status to API format
+func (h *WorkflowsHandler) toWorkflowResponse(workflow jobscheduler.WorkflowStatus) api.WorkflowStatusResponse {
+	response := api.WorkflowStatusResponse{
+		WorkflowID: workflow.ID,
+		Status:     string(workflow.Status),
+		Counts:     make(map[string]int, len(workflow.Counts)),
+		StartTime:  workflow.StartTime,
+		EndTime:    workflow.EndTime,
+		Jobs:       make([]api.JobStatusResponse, len(workflow.Jobs)),
+	}
+	for status, count := range workflow.Counts {
+		response.Counts[string(status)] = count
+	}
+	if !workflow.EndTime.IsZero() && !workflow.StartTime.IsZero() {
+		response.Duration = workflow.EndTime.Sub(workflow.StartTime).String()
+	}
+	for i, job := range workflow.Jobs {
+		response.Jobs[i] = toStatusResponse(h.scheduler, job)
+	}
+	return response
+}
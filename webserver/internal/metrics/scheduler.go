@@ -0,0 +1,171 @@
+// Package metrics exposes the scheduler's activity to Prometheus
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonathanleahy/project/jobscheduler"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "jobscheduler"
+
+// SchedulerMetrics keeps Prometheus metrics for each channel up to date
+// from the events the scheduler records, so they change the moment a job
+// does rather than when someone asks
+type SchedulerMetrics struct {
+	submitted   *prometheus.CounterVec   // channel
+	finished    *prometheus.CounterVec   // channel, status
+	retried     *prometheus.CounterVec   // channel
+	queueDepth  *prometheus.GaugeVec     // channel
+	activeJobs  *prometheus.GaugeVec     // channel
+	queueWait   *prometheus.HistogramVec // channel
+	runDuration *prometheus.HistogramVec // channel
+	unsubscribe func()
+
+	// Jobs waiting for and holding a worker, by channel, and when each
+	// waiting job was queued
+	mu      sync.Mutex
+	queued  map[string]map[string]time.Time
+	running map[string]map[string]bool
+}
+
+// NewSchedulerMetrics registers the scheduler's metrics with reg and
+// starts keeping them
+func NewSchedulerMetrics(scheduler *jobscheduler.Scheduler, reg prometheus.Registerer) *SchedulerMetrics {
+	m := &SchedulerMetrics{
+		submitted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "jobs_submitted_total",
+			Help:      "Jobs submitted to each channel.",
+		}, []string{"channel"}),
+		finished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "jobs_finished_total",
+			Help:      "Jobs that finished in each channel, by final status: complete, failed, timed_out, cancelled or interrupted.",
+		}, []string{"channel", "status"}),
+		retried: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "jobs_retried_total",
+			Help:      "Failed attempts in each channel that were retried.",
+		}, []string{"channel"}),
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_depth",
+			Help:      "Jobs waiting for a worker in each channel.",
+		}, []string{"channel"}),
+		activeJobs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_workers",
+			Help:      "Workers running a job in each channel.",
+		}, []string{"channel"}),
+		queueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "queue_wait_seconds",
+			Help:      "Time jobs spent waiting for a worker in each channel.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 18), // 10ms to about 22m
+		}, []string{"channel"}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "run_duration_seconds",
+			Help:      "Time each attempt at a job ran for in each channel.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 16), // 100ms to about 55m
+		}, []string{"channel"}),
+		queued:  make(map[string]map[string]time.Time),
+		running: make(map[string]map[string]bool),
+	}
+	reg.MustRegister(m.submitted, m.finished, m.retried, m.queueDepth, m.activeJobs, m.queueWait, m.runDuration)
+
+	// Subscribing before taking stock of the jobs already waiting and
+	// running means none are missed. One that moves on in between is put
+	// right once it finishes.
+	m.unsubscribe = scheduler.Subscribe(m.observe)
+	if jobs, err := scheduler.ListJobs("", ""); err == nil {
+		m.mu.Lock()
+		for _, job := range jobs {
+			switch job.Status {
+			case jobscheduler.JobStatusPending:
+				m.setQueued(job.Channel, job.ID, time.Time{})
+			case jobscheduler.JobStatusRunning:
+				m.setRunning(job.Channel, job.ID)
+			}
+		}
+		m.mu.Unlock()
+	}
+	return m
+}
+
+// observe updates the metrics for an event
+func (m *SchedulerMetrics) observe(event jobscheduler.JobEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	channel, jobID := event.Channel, event.JobID
+	switch event.Event {
+	case jobscheduler.EventSubmitted:
+		m.submitted.WithLabelValues(channel).Inc()
+	case jobscheduler.EventQueued:
+		m.setQueued(channel, jobID, event.Time)
+	case jobscheduler.EventStarted:
+		if queuedAt := m.unqueue(channel, jobID); !queuedAt.IsZero() {
+			m.queueWait.WithLabelValues(channel).Observe(event.Time.Sub(queuedAt).Seconds())
+		}
+		m.setRunning(channel, jobID)
+	case jobscheduler.EventSkipped:
+		m.unqueue(channel, jobID)
+	case jobscheduler.EventRetrying:
+		m.retried.WithLabelValues(channel).Inc()
+		m.runDuration.WithLabelValues(channel).Observe(event.Duration)
+		m.stopRunning(channel, jobID)
+	case jobscheduler.EventFinished:
+		m.finished.WithLabelValues(channel, string(event.Status)).Inc()
+		if m.running[channel][jobID] {
+			m.runDuration.WithLabelValues(channel).Observe(event.Duration)
+		}
+		m.unqueue(channel, jobID)
+		m.stopRunning(channel, jobID)
+	}
+}
+
+// setQueued notes that a job is waiting for a worker
+func (m *SchedulerMetrics) setQueued(channel, jobID string, at time.Time) {
+	if m.queued[channel] == nil {
+		m.queued[channel] = make(map[string]time.Time)
+	}
+	m.queued[channel][jobID] = at
+	m.queueDepth.WithLabelValues(channel).Set(float64(len(m.queued[channel])))
+}
+
+// unqueue notes that a job is no longer waiting, returning when it was
+// queued if that is known
+func (m *SchedulerMetrics) unqueue(channel, jobID string) time.Time {
+	queuedAt, ok := m.queued[channel][jobID]
+	if ok {
+		delete(m.queued[channel], jobID)
+		m.queueDepth.WithLabelValues(channel).Set(float64(len(m.queued[channel])))
+	}
+	return queuedAt
+}
+
+// setRunning notes that a job holds a worker
+func (m *SchedulerMetrics) setRunning(channel, jobID string) {
+	if m.running[channel] == nil {
+		m.running[channel] = make(map[string]bool)
+	}
+	m.running[channel][jobID] = true
+	m.activeJobs.WithLabelValues(channel).Set(float64(len(m.running[channel])))
+}
+
+// stopRunning notes that a job has let go of its worker
+func (m *SchedulerMetrics) stopRunning(channel, jobID string) {
+	if m.running[channel][jobID] {
+		delete(m.running[channel], jobID)
+		m.activeJobs.WithLabelValues(channel).Set(float64(len(m.running[channel])))
+	}
+}
+
+// Close stops keeping the metrics
+func (m *SchedulerMetrics) Close() {
+	m.unsubscribe()
+}
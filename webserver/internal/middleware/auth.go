@@ -9,9 +9,16 @@ import (
 	"time"
 )
 
+// DefaultSubject identifies whoever authenticated with the main API key
+const DefaultSubject = "default"
+
+// AdminSubject identifies whoever authenticated with the admin key
+const AdminSubject = "admin"
+
 // AuthConfig contains authentication configuration
 type AuthConfig struct {
 	APIKey      string
+	NamedKeys   map[string]string // Further API keys, by the name of who holds each
 	TokenHeader string
 	SkipPaths   []string
 	TokenExpiry time.Duration
@@ -29,10 +36,13 @@ func DefaultAuthConfig() AuthConfig {
 	}
 }
 
-// Auth creates a new authentication middleware
-func Auth(apiKey string) func(http.Handler) http.Handler {
+// Auth creates a new authentication middleware. A request that carries
+// one of the named keys is identified by that key's name, and one that
+// carries apiKey as DefaultSubject.
+func Auth(apiKey string, namedKeys map[string]string) func(http.Handler) http.Handler {
 	cfg := DefaultAuthConfig()
 	cfg.APIKey = apiKey
+	cfg.NamedKeys = namedKeys
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -50,14 +60,16 @@ func Auth(apiKey string) func(http.Handler) http.Handler {
 			}
 
 			// Validate token
-			if !validateToken(token, cfg.APIKey) {
+			subject, ok := authenticate(token, cfg)
+			if !ok {
 				http.Error(w, "Invalid authentication token", http.StatusUnauthorized)
 				return
 			}
 
 			// Add authentication info to context
-			ctx := context.WithValue(r.Context(), "auth_info", AuthInfo{
+			ctx := context.WithValue(r.Context(), authInfoKey, AuthInfo{
 				Token:    token,
+				Subject:  subject,
 				IssuedAt: time.Now(),
 			})
 
@@ -67,13 +79,62 @@ func Auth(apiKey string) func(http.Handler) http.Handler {
 	}
 }
 
+// AdminAuth creates an authentication middleware for endpoints that change
+// what the server will run. Requests that only read may use any key Auth
+// accepts, but those that change anything need adminKey; without one,
+// nothing can be changed through them.
+func AdminAuth(adminKey, apiKey string, namedKeys map[string]string) func(http.Handler) http.Handler {
+	auth := Auth(apiKey, namedKeys)
+	cfg := DefaultAuthConfig()
+
+	return func(next http.Handler) http.Handler {
+		readOnly := auth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := extractToken(r.Header.Get(cfg.TokenHeader))
+			if adminKey != "" && token != "" && validateToken(token, adminKey) {
+				ctx := context.WithValue(r.Context(), authInfoKey, AuthInfo{
+					Token:    token,
+					Subject:  AdminSubject,
+					IssuedAt: time.Now(),
+				})
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				readOnly.ServeHTTP(w, r)
+			default:
+				if token == "" {
+					http.Error(w, "Missing authentication token", http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, "Changes need the admin key", http.StatusForbidden)
+			}
+		})
+	}
+}
+
 // AuthInfo contains authentication information
 type AuthInfo struct {
 	Token     string
+	Subject   string // Who the token identifies
 	IssuedAt  time.Time
 	ExpiresAt time.Time
 }
 
+// contextKey keys the values this package adds to request contexts
+type contextKey string
+
+const authInfoKey contextKey = "auth_info"
+
+// GetAuthInfo returns the authentication information of a request that
+// passed Auth
+func GetAuthInfo(ctx context.Context) (AuthInfo, bool) {
+	info, ok := ctx.Value(authInfoKey).(AuthInfo)
+	return info, ok
+}
+
 // extractToken extracts the token from the Authorization header
 func extractToken(header string) string {
 	if header == "" {
@@ -90,6 +151,20 @@ func extractToken(header string) string {
 	return header
 }
 
+// authenticate returns who a token identifies, or false if it is not a
+// valid key
+func authenticate(token string, cfg AuthConfig) (string, bool) {
+	if validateToken(token, cfg.APIKey) {
+		return DefaultSubject, true
+	}
+	for name, key := range cfg.NamedKeys {
+		if key != "" && validateToken(token, key) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 // validateToken validates the authentication token
 func validateToken(token, apiKey string) bool {
 	// Use constant time comparison to prevent timing attacks
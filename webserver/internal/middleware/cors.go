@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -76,7 +77,7 @@ func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
 			headers.Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
 			headers.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
 			headers.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
-			headers.Set("Access-Control-Max-Age", string(cfg.MaxAge))
+			headers.Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
 
 			if cfg.AllowCredentials {
 				headers.Set("Access-Control-Allow-Credentials", "true")
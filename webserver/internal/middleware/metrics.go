@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTPMetrics counts and times the requests served by each handler
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec   // handler, method, code
+	duration *prometheus.HistogramVec // handler, method
+	inFlight *prometheus.GaugeVec     // handler
+}
+
+// NewHTTPMetrics registers request metrics with reg
+func NewHTTPMetrics(reg prometheus.Registerer) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by handler, method and status code.",
+		}, []string{"handler", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by handler and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"handler", "method"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being served, by handler.",
+		}, []string{"handler"}),
+	}
+	reg.MustRegister(m.requests, m.duration, m.inFlight)
+	return m
+}
+
+// Instrument records the requests served by a handler under its name.
+// Naming handlers rather than labelling by path keeps job IDs out of the
+// metrics.
+func (m *HTTPMetrics) Instrument(handler string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			inFlight := m.inFlight.WithLabelValues(handler)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			next.ServeHTTP(rec, r)
+
+			m.requests.WithLabelValues(handler, r.Method, strconv.Itoa(rec.status)).Inc()
+			m.duration.WithLabelValues(handler, r.Method).Observe(time.Since(start).Seconds())
+		})
+	}
+}
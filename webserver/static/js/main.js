@@ -71,12 +71,24 @@ class JobSchedulerAPI {
         }
         return response.json();
     }
+
+    // Streams a job's output line by line until the job finishes
+    followLogs(jobID, onLine, onEnd) {
+        const source = new EventSource(`${this.baseURL}/api/v1/jobs/${encodeURIComponent(jobID)}/logs?follow=true`);
+        source.onmessage = (e) => onLine(JSON.parse(e.data));
+        source.addEventListener('end', (e) => {
+            source.close();
+            onEnd(e.data);
+        });
+        return source;
+    }
 }
 
 // UI Controller
 class DashboardUI {
     constructor(api) {
         this.api = api;
+        this.logSource = null;
         this.setupEventListeners();
         this.startPolling();
     }
@@ -87,6 +99,14 @@ class DashboardUI {
         document.getElementById('close-modal').addEventListener('click', () => this.hideModal());
         document.getElementById('submit-modal').addEventListener('click', () => this.handleJobSubmission());
 
+        // Tail the logs of an active job
+        document.getElementById('active-jobs').addEventListener('click', (e) => {
+            const button = e.target.closest('[data-tail-job]');
+            if (button) {
+                this.tailLogs(button.dataset.tailJob);
+            }
+        });
+
         // Close modal on background click
         document.getElementById('job-modal').addEventListener('click', (e) => {
             if (e.target.id === 'job-modal') {
@@ -123,6 +143,22 @@ class DashboardUI {
         }
     }
 
+    tailLogs(jobID) {
+        if (this.logSource) {
+            this.logSource.close();
+        }
+        const output = document.getElementById('job-logs');
+        document.getElementById('job-logs-title').textContent = `Logs: ${jobID}`;
+        output.textContent = '';
+        this.logSource = this.api.followLogs(jobID, (line) => {
+            output.textContent += (line.stream === 'stderr' ? '[stderr] ' : '') + line.text + '\n';
+            output.scrollTop = output.scrollHeight;
+        }, (status) => {
+            output.textContent += `-- job ${status} --\n`;
+            this.logSource = null;
+        });
+    }
+
     showNotification(message, type) {
         const indicator = document.getElementById('status-indicator');
         indicator.textContent = message;
@@ -183,17 +219,30 @@ class DashboardUI {
                         <div>Started: ${new Date(job.start_time).toLocaleString()}</div>
                         <div>Duration: ${job.duration || 'N/A'}</div>
                     </div>
+                    <div class="w-full bg-gray-200 rounded-full h-2 mt-2">
+                        <div class="bg-blue-500 h-2 rounded-full" style="width: ${job.progress || 0}%"></div>
+                    </div>
+                    ${job.message ? `<div class="text-sm text-gray-600 mt-1">${this.escapeHTML(job.message)}</div>` : ''}
+                    <button data-tail-job="${job.job_id}" class="mt-2 text-sm text-blue-600 hover:underline">Tail logs</button>
                 </div>
             `)
             .join('');
     }
 
+    escapeHTML(text) {
+        const div = document.createElement('div');
+        div.textContent = text;
+        return div.innerHTML;
+    }
+
     getStatusColor(status) {
         const colors = {
             running: 'bg-blue-100 text-blue-800',
             completed: 'bg-green-100 text-green-800',
             failed: 'bg-red-100 text-red-800',
-            pending: 'bg-yellow-100 text-yellow-800'
+            pending: 'bg-yellow-100 text-yellow-800',
+            scheduled: 'bg-purple-100 text-purple-800',
+            blocked: 'bg-orange-100 text-orange-800'
         };
         return colors[status] || 'bg-gray-100 text-gray-800';
     }